@@ -0,0 +1,33 @@
+package api
+
+import "time"
+
+type ExportedNote struct {
+	ID        string    `json:"id,omitempty"`
+	Category  string    `json:"category"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ImportReport struct {
+	Mode    string        `json:"mode"`
+	DryRun  bool          `json:"dryRun"`
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Skipped int           `json:"skipped"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors"`
+}
+
+type ImportError struct {
+	Line    int    `json:"line,omitempty"`
+	File    string `json:"file,omitempty"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportResponse struct {
+	Message string       `json:"message,omitempty"`
+	Report  ImportReport `json:"report"`
+}
@@ -0,0 +1,26 @@
+package api
+
+import "time"
+
+type Author struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type Revision struct {
+	Commit  string    `json:"commit"`
+	Author  Author    `json:"author"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Note    *Note     `json:"note,omitempty"`
+}
+
+type HistoryResponse struct {
+	Message   string     `json:"message,omitempty"`
+	Revisions []Revision `json:"revisions"`
+}
+
+type RestoreRequest struct {
+	Commit string `json:"commit,omitempty"`
+}
@@ -16,3 +16,8 @@ type NoteResponse struct {
 	Note    any    `json:"note,omitempty"`
 	Notes   any    `json:"notes,omitempty"`
 }
+
+type CategoriesResponse struct {
+	Message    string   `json:"message,omitempty"`
+	Categories []string `json:"categories"`
+}
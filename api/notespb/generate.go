@@ -0,0 +1,5 @@
+// Package notespb contains the gRPC API of the notes service. The Go code
+// is generated from notes.proto with protoc-gen-go and protoc-gen-go-grpc.
+package notespb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative notes.proto
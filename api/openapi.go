@@ -0,0 +1,8 @@
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 document of the API.
+//
+//go:embed openapi.json
+var OpenAPI []byte
@@ -0,0 +1,43 @@
+package api
+
+import "time"
+
+type WebhookRequest struct {
+	URL      string   `json:"url,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Events   []string `json:"events,omitempty"`
+	Secret   string   `json:"secret,omitempty"`
+}
+
+type Webhook struct {
+	ID       string   `json:"id"`
+	URL      string   `json:"url"`
+	Category string   `json:"category,omitempty"`
+	Events   []string `json:"events,omitempty"`
+	// Secret is only returned when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WebhookResponse struct {
+	Message  string    `json:"message,omitempty"`
+	Webhook  *Webhook  `json:"webhook,omitempty"`
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+}
+
+type Delivery struct {
+	ID             string    `json:"id"`
+	EventID        uint64    `json:"eventId"`
+	Event          string    `json:"event"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	ResponseStatus int       `json:"responseStatus,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+type DeliveriesResponse struct {
+	Message    string     `json:"message,omitempty"`
+	Deliveries []Delivery `json:"deliveries"`
+}
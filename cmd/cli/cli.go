@@ -24,6 +24,28 @@ func CLI(args []string) int {
 				Value:       "http://localhost:3000", // Default value
 				Destination: &host,
 			},
+			&cli.StringFlag{
+				Name:  "ca-cert",
+				Usage: "CA certificate file to verify the server certificate with",
+			},
+			&cli.StringFlag{
+				Name:  "client-cert",
+				Usage: "Client certificate file for mutual TLS",
+			},
+			&cli.StringFlag{
+				Name:  "client-key",
+				Usage: "Client key file for mutual TLS",
+			},
+			&cli.StringFlag{
+				Name:    "author-name",
+				Usage:   "Name of the author of the changes, recorded by the git storage backend",
+				EnvVars: []string{"NOTES_AUTHOR_NAME"},
+			},
+			&cli.StringFlag{
+				Name:    "author-email",
+				Usage:   "Email of the author of the changes, recorded by the git storage backend",
+				EnvVars: []string{"NOTES_AUTHOR_EMAIL"},
+			},
 		},
 		Commands: []*cli.Command{
 			commands.CreateNote(&host),
@@ -31,6 +53,12 @@ func CLI(args []string) int {
 			commands.DeleteNote(&host),
 			commands.GetNoteByID(&host),
 			commands.ListNotes(&host),
+			commands.Watch(&host),
+			commands.Export(&host),
+			commands.Import(&host),
+			commands.ExportMarkdown(&host),
+			commands.NoteHistory(&host),
+			commands.RestoreNote(&host),
 		},
 		CustomAppHelpTemplate: `NAME:
 	{{.HelpName}} - {{.Usage}}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/KatrinSalt/notes-service/cmd/cli/output"
+	"github.com/urfave/cli/v2"
+)
+
+// ImportReport is the report of an import of notes.
+type ImportReport struct {
+	Mode    string `json:"mode"`
+	DryRun  bool   `json:"dryRun"`
+	Total   int    `json:"total"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+	Skipped int    `json:"skipped"`
+	Failed  int    `json:"failed"`
+	Errors  []struct {
+		Line    int    `json:"line"`
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type ImportResponse struct {
+	Message string       `json:"message,omitempty"`
+	Report  ImportReport `json:"report"`
+}
+
+func Export(host *string) *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Export the notes of all categories to an NDJSON file",
+		UsageText: `
+        notes-service-cli export --file notes.ndjson`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "file",
+				Aliases: []string{"f"},
+				Usage:   "File to write the notes to",
+				Value:   "notes.ndjson",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			path := c.String("file")
+
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Get(*host + "/admin/export")
+			if err != nil {
+				return fmt.Errorf("error exporting the notes: %w", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				body, _ := io.ReadAll(resp.Body)
+				return fmt.Errorf("error exporting the notes: status: %s, response: %s", resp.Status, string(body))
+			}
+
+			file, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("error creating the export file: %w", err)
+			}
+			lines := &lineCounter{w: file}
+			if _, err := io.Copy(lines, resp.Body); err != nil {
+				file.Close()
+				os.Remove(path)
+				return fmt.Errorf("error exporting the notes: %w", err)
+			}
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("error writing the export file: %w", err)
+			}
+
+			output.Println(fmt.Sprintf("Exported %d notes to %s.", lines.n, path))
+			return nil
+		},
+	}
+}
+
+func Import(host *string) *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Import notes from an NDJSON file",
+		UsageText: `
+        notes-service-cli import --file notes.ndjson
+        notes-service-cli import -f notes.ndjson --mode upsert --dry-run`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "File to read the notes from, required",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "mode",
+				Usage: "How existing notes are handled: create, upsert or skip-existing",
+				Value: "create",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Report what the import would do without writing notes",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			body, err := os.ReadFile(c.String("file"))
+			if err != nil {
+				return fmt.Errorf("error reading the import file: %w", err)
+			}
+
+			query := url.Values{}
+			query.Set("mode", c.String("mode"))
+			if c.Bool("dry-run") {
+				query.Set("dryRun", "true")
+			}
+
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Post(*host+"/admin/import?"+query.Encode(), "application/x-ndjson", bytes.NewReader(body))
+			if err != nil {
+				return fmt.Errorf("error importing the notes: %w", err)
+			}
+			defer resp.Body.Close()
+
+			respBody, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("error importing the notes: %w", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("error importing the notes: status: %s, response: %s", resp.Status, string(respBody))
+			}
+
+			var response ImportResponse
+			if err := json.Unmarshal(respBody, &response); err != nil {
+				return fmt.Errorf("error importing the notes: %w", err)
+			}
+
+			report := response.Report
+			output.Println(fmt.Sprintf("%s.\n  Mode: %s\n  Total: %d\n  Created: %d\n  Updated: %d\n  Skipped: %d\n  Failed: %d",
+				response.Message, report.Mode, report.Total, report.Created, report.Updated, report.Skipped, report.Failed))
+			for _, e := range report.Errors {
+				output.PrintlnErr(fmt.Errorf("line %d: %s", e.Line, e.Message))
+			}
+			return nil
+		},
+	}
+}
+
+// lineCounter counts the lines written to w.
+type lineCounter struct {
+	w io.Writer
+	n int
+}
+
+func (l *lineCounter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	l.n += bytes.Count(p[:n], []byte("\n"))
+	return n, err
+}
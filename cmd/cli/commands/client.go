@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// newHTTPClient returns an HTTP client configured with the TLS flags
+// --ca-cert, --client-cert and --client-key, that sends the author of the
+// --author-name and --author-email flags with every request. A timeout of
+// zero means no timeout.
+func newHTTPClient(c *cli.Context, timeout time.Duration) (*http.Client, error) {
+	transport, err := newTransport(c)
+	if err != nil {
+		return nil, err
+	}
+
+	name, email := c.String("author-name"), c.String("author-email")
+	if len(name) > 0 || len(email) > 0 {
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		transport = &authorTransport{next: transport, name: name, email: email}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// newTransport returns the transport of the TLS flags, and nil if none
+// is set.
+func newTransport(c *cli.Context) (http.RoundTripper, error) {
+	caCert := c.String("ca-cert")
+	clientCert := c.String("client-cert")
+	clientKey := c.String("client-key")
+
+	if len(caCert) == 0 && len(clientCert) == 0 && len(clientKey) == 0 {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caCert) > 0 {
+		pem, err := os.ReadFile(caCert)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("the CA certificate file contains no certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(clientCert) > 0 || len(clientKey) > 0 {
+		if len(clientCert) == 0 || len(clientKey) == 0 {
+			return nil, errors.New("both --client-cert and --client-key shall be provided")
+		}
+		cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("error loading the client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// authorTransport sets the X-Author-Name and X-Author-Email headers of
+// the requests, so that the changes are recorded with the author by the
+// git storage backend.
+type authorTransport struct {
+	next  http.RoundTripper
+	name  string
+	email string
+}
+
+// RoundTrip sets the author headers on a copy of the request and sends it.
+func (t *authorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if len(t.name) > 0 {
+		req.Header.Set("X-Author-Name", t.name)
+	}
+	if len(t.email) > 0 {
+		req.Header.Set("X-Author-Email", t.email)
+	}
+	return t.next.RoundTrip(req)
+}
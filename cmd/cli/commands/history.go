@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/cmd/cli/output"
+	"github.com/urfave/cli/v2"
+)
+
+// shortCommitLength is the length of the commits printed in the history.
+const shortCommitLength = 12
+
+type Author struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type Revision struct {
+	Commit  string    `json:"commit"`
+	Author  Author    `json:"author"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+	Deleted bool      `json:"deleted,omitempty"`
+	Note    *Note     `json:"note,omitempty"`
+}
+
+type HistoryResponse struct {
+	Revisions []Revision `json:"revisions"`
+}
+
+func NoteHistory(host *string) *cli.Command {
+	return &cli.Command{
+		Name:    "note-history",
+		Aliases: []string{"history"},
+		Usage:   "List the changes of a note, with the git storage backend",
+		UsageText: `
+        notes-service-cli note-history --category personal --id 123
+        notes-service-cli history -c work -i 321 --full`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "category",
+				Aliases:  []string{"c"},
+				Usage:    "Category of the note, required",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "id",
+				Aliases:  []string{"i"},
+				Usage:    "ID of the note, required",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "full",
+				Usage: "Print the full commits and the content of the note after every change",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			category := c.String("category")
+			id := c.String("id")
+
+			target := fmt.Sprintf("%s/v1/categories/%s/notes/%s/history", *host, url.PathEscape(category), url.PathEscape(id))
+
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Get(target)
+			if err != nil {
+				return fmt.Errorf("error fetching the history of the note: %w", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("error fetching the history of the note: %w", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("error fetching the history of the note: status: %s, response: %s", resp.Status, string(body))
+			}
+			var history HistoryResponse
+			if err := json.Unmarshal(body, &history); err != nil {
+				return fmt.Errorf("error fetching the history of the note: %w", err)
+			}
+
+			for _, revision := range history.Revisions {
+				commit := revision.Commit
+				if !c.Bool("full") && len(commit) > shortCommitLength {
+					commit = commit[:shortCommitLength]
+				}
+				author := revision.Author.Name
+				if len(revision.Author.Email) > 0 {
+					author += " <" + revision.Author.Email + ">"
+				}
+				output.Println(fmt.Sprintf("%s %s %s | %s", commit, revision.Time.Local().Format(time.DateTime), author, revision.Message))
+				if c.Bool("full") && revision.Note != nil {
+					output.Println("  Note: " + revision.Note.Note)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func RestoreNote(host *string) *cli.Command {
+	return &cli.Command{
+		Name:    "restore-note",
+		Aliases: []string{"restore"},
+		Usage:   "Restore a note as it was after a change, with the git storage backend",
+		UsageText: `
+        notes-service-cli restore-note --category personal --id 123 --commit 3f1c2a9e0b7d
+        notes-service-cli restore -c work -i 321 --commit 3f1c2a9e0b7d`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "category",
+				Aliases:  []string{"c"},
+				Usage:    "Category of the note, required",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "id",
+				Aliases:  []string{"i"},
+				Usage:    "ID of the note, required",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "commit",
+				Usage:    "Commit of the change to restore the note from, as listed by note-history, required",
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			category := c.String("category")
+			id := c.String("id")
+
+			body, err := json.Marshal(map[string]string{"commit": c.String("commit")})
+			if err != nil {
+				return err
+			}
+			target := fmt.Sprintf("%s/v1/categories/%s/notes/%s/restore", *host, url.PathEscape(category), url.PathEscape(id))
+
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Post(target, "application/json", bytes.NewReader(body))
+			if err != nil {
+				return fmt.Errorf("error restoring the note: %w", err)
+			}
+			defer resp.Body.Close()
+
+			response, err := processResponse(resp)
+			if err != nil {
+				return fmt.Errorf("error restoring the note: %w", err)
+			}
+
+			message := fmt.Sprintf("Note is restored.\nNote Details:\n  ID: %s\n  Category: %s\n  Note: %s", response.Note.ID, response.Note.Category, response.Note.Note)
+			output.Println(message)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/KatrinSalt/notes-service/cmd/cli/output"
+	"github.com/urfave/cli/v2"
+)
+
+func ExportMarkdown(host *string) *cli.Command {
+	return &cli.Command{
+		Name:  "export-md",
+		Usage: "Export the notes of a category as Markdown files to a directory",
+		UsageText: `
+        notes-service-cli export-md --category work
+        notes-service-cli export-md -c work --dir notes/work`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "category",
+				Aliases:  []string{"c"},
+				Usage:    "Category of the notes, required",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "dir",
+				Aliases: []string{"d"},
+				Usage:   "Directory to write the notes to, the category if not provided",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			category := c.String("category")
+			dir := c.String("dir")
+			if len(dir) == 0 {
+				dir = category
+			}
+
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Get(*host + "/notes/categories/" + url.PathEscape(category) + "/export.zip")
+			if err != nil {
+				return fmt.Errorf("error exporting the notes: %w", err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("error exporting the notes: %w", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("error exporting the notes: status: %s, response: %s", resp.Status, string(body))
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			if err != nil {
+				return fmt.Errorf("error exporting the notes: %w", err)
+			}
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("error creating the export directory: %w", err)
+			}
+
+			count := 0
+			for _, f := range zr.File {
+				// Only the name of the file is used, so that the archive
+				// cannot write outside of the directory.
+				name := path.Base(f.Name)
+				if f.FileInfo().IsDir() || path.Ext(name) != ".md" {
+					continue
+				}
+				if err := extractFile(f, filepath.Join(dir, name)); err != nil {
+					return fmt.Errorf("error writing the note %s: %w", name, err)
+				}
+				count++
+			}
+
+			output.Println(fmt.Sprintf("Exported %d notes to %s.", count, dir))
+			return nil
+		},
+	}
+}
+
+// extractFile writes the content of a file of a zip archive to dst.
+func extractFile(f *zip.File, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	file, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, rc); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
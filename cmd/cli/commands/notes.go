@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/KatrinSalt/notes-service/cmd/cli/output"
+	"github.com/google/uuid"
 	"github.com/urfave/cli/v2"
 )
 
@@ -30,7 +31,7 @@ func CreateNote(host *string) *cli.Command {
 		Usage:   "Create a new note on the server",
 		UsageText: ` 
 		    notes-service-cli create-note --category personal --note "Buy groceries"
-		    notes-service-cli create -c work -n "Do time reporting"`,
+		    notes-service-cli create -c work -n "Do time reporting" --retries 5`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:     "category",
@@ -44,14 +45,48 @@ func CreateNote(host *string) *cli.Command {
 				Usage:    "Content of the note to create",
 				Required: true,
 			},
+			&cli.StringFlag{
+				Name:  "idempotency-key",
+				Usage: "Idempotency key of the request, generated if not provided",
+			},
+			&cli.IntFlag{
+				Name:  "retries",
+				Usage: "Number of times to retry the request on timeouts and server errors",
+				Value: defaultRetries,
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Timeout of a single request attempt",
+				Value: defaultTimeout,
+			},
 		},
 		Action: func(c *cli.Context) error {
 			category := c.String("category")
 			noteContent := c.String("note")
 
+			// The same idempotency key is sent with every attempt, so that
+			// the server does not create a duplicate note on retries.
+			idempotencyKey := c.String("idempotency-key")
+			if len(idempotencyKey) == 0 {
+				idempotencyKey = uuid.NewString()
+			}
+
 			jsonStr := []byte(fmt.Sprintf(`{"note":"%s"}`, noteContent))
-			url := fmt.Sprintf("%s/notes/create/%s", *host, category)
-			reqResp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonStr))
+			url := fmt.Sprintf("%s/v1/categories/%s/notes", *host, category)
+
+			client, err := newHTTPClient(c, c.Duration("timeout"))
+			if err != nil {
+				return err
+			}
+			reqResp, err := doWithRetry(client, c.Int("retries"), func() (*http.Request, error) {
+				req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonStr))
+				if err != nil {
+					return nil, err
+				}
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Idempotency-Key", idempotencyKey)
+				return req, nil
+			})
 			if err != nil {
 				return fmt.Errorf("error creating note: %w", err)
 			}
@@ -109,14 +144,17 @@ func UpdateNote(host *string) *cli.Command {
 
 			jsonStr := []byte(fmt.Sprintf(`{"note":"%s"}`, noteContent))
 
-			url := fmt.Sprintf("%s/notes/update/%s/%s", *host, category, id)
+			url := fmt.Sprintf("%s/v1/categories/%s/notes/%s", *host, category, id)
 			req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(jsonStr))
 			if err != nil {
 				return fmt.Errorf("error creating update request: %w", err)
 			}
 			req.Header.Set("Content-Type", "application/json")
 
-			client := &http.Client{}
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
 			reqResp, err := client.Do(req)
 			if err != nil {
 				// fmt.Println("Error updating note:", err)
@@ -167,13 +205,16 @@ func DeleteNote(host *string) *cli.Command {
 			category := c.String("category")
 			id := c.String("id")
 
-			url := fmt.Sprintf("%s/notes/delete/%s/%s", *host, category, id)
+			url := fmt.Sprintf("%s/v1/categories/%s/notes/%s", *host, category, id)
 			req, err := http.NewRequest(http.MethodDelete, url, nil)
 			if err != nil {
 				return fmt.Errorf("error creating delete request: %w", err)
 			}
 
-			client := &http.Client{}
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
 			reqResp, err := client.Do(req)
 			if err != nil {
 				return fmt.Errorf("error deleting the note: %w", err)
@@ -233,9 +274,13 @@ func GetNoteByID(host *string) *cli.Command {
 				return fmt.Errorf("note ID shall be provided")
 			}
 
-			url := fmt.Sprintf("%s/notes/categories/%s/ids/%s", *host, category, id)
+			url := fmt.Sprintf("%s/v1/categories/%s/notes/%s", *host, category, id)
 
-			reqResp, err := http.Get(url)
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			reqResp, err := client.Get(url)
 			if err != nil {
 				return fmt.Errorf("error fetching the note: %w", err)
 			}
@@ -282,9 +327,13 @@ func ListNotes(host *string) *cli.Command {
 				return fmt.Errorf("note category shall be provided")
 			}
 
-			url := fmt.Sprintf("%s/notes/categories/%s", *host, category)
+			url := fmt.Sprintf("%s/v1/categories/%s/notes", *host, category)
 
-			reqResp, err := http.Get(url)
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+			reqResp, err := client.Get(url)
 			if err != nil {
 				return fmt.Errorf("error listing the notes: %w", err)
 			}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultRetries is the default number of retries for requests that are safe to retry.
+	defaultRetries = 3
+	// defaultTimeout is the default timeout for a single request attempt.
+	defaultTimeout = 10 * time.Second
+	// retryBaseDelay is the delay before the first retry, it is doubled for every retry.
+	retryBaseDelay = 500 * time.Millisecond
+)
+
+// doWithRetry sends the request created by newRequest and retries it on
+// network errors, timeouts, rate limiting and server errors. A new request
+// is created for every attempt, so that the body can be sent again.
+// It must only be used for requests that are safe to retry, i.e. requests
+// with an Idempotency-Key header.
+func doWithRetry(client *http.Client, retries int, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		delay := retryBaseDelay << attempt
+		resp, err := client.Do(req)
+		if err == nil {
+			if !retryableStatus(resp.StatusCode) || attempt >= retries {
+				return resp, nil
+			}
+			if retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && retryAfter > 0 {
+				delay = time.Duration(retryAfter) * time.Second
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			lastErr = fmt.Errorf("status: %s", resp.Status)
+		} else {
+			lastErr = err
+		}
+
+		if attempt >= retries {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt+1, lastErr)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// retryableStatus reports whether a request with the response status code can be retried.
+func retryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
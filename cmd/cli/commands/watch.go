@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/cmd/cli/output"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	// watchReconnectDelay is the delay before the stream is opened again
+	// after it was closed.
+	watchReconnectDelay = time.Second
+	// watchMaxReconnectDelay is the maximum delay between reconnects.
+	watchMaxReconnectDelay = 30 * time.Second
+)
+
+// event is an event of the stream of note events.
+type event struct {
+	id   string
+	name string
+	data string
+}
+
+func Watch(host *string) *cli.Command {
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "Watch the create, update and delete events of notes",
+		UsageText: `
+        notes-service-cli watch
+        notes-service-cli watch --category work`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "category",
+				Aliases: []string{"c"},
+				Usage:   "Category of the notes to watch, all categories if not provided",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			target := *host + "/notes/events"
+			if category := c.String("category"); len(category) > 0 {
+				target += "?category=" + url.QueryEscape(category)
+			}
+
+			client, err := newHTTPClient(c, 0)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+			defer stop()
+
+			// The stream is opened again when it is closed, and resumed
+			// after the last received event.
+			var lastEventID string
+			delay := watchReconnectDelay
+			for {
+				received, err := watch(ctx, client, target, &lastEventID)
+				if ctx.Err() != nil {
+					return nil
+				}
+				if received {
+					delay = watchReconnectDelay
+				}
+				if err != nil {
+					output.PrintlnErr(fmt.Errorf("error watching the notes: %w, reconnecting in %s", err, delay))
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(delay):
+				}
+				delay = min(2*delay, watchMaxReconnectDelay)
+			}
+		},
+	}
+}
+
+// watch opens the stream of note events and prints the events until the
+// stream is closed. It reports whether an event was received.
+func watch(ctx context.Context, client *http.Client, target string, lastEventID *string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if len(*lastEventID) > 0 {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("status: %s, response: %s", resp.Status, string(body))
+	}
+
+	received := false
+	err = readEvents(resp.Body, func(e event) {
+		received = true
+		if len(e.id) > 0 {
+			*lastEventID = e.id
+		}
+		printEvent(e)
+	})
+	if err == nil {
+		err = io.ErrUnexpectedEOF
+	}
+	return received, err
+}
+
+// readEvents reads the Server-Sent Events of the stream and calls fn with
+// every event.
+func readEvents(r io.Reader, fn func(e event)) error {
+	scanner := bufio.NewScanner(r)
+	var e event
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 {
+			if len(e.name) > 0 || len(e.data) > 0 {
+				fn(e)
+			}
+			e = event{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			e.id = value
+		case "event":
+			e.name = value
+		case "data":
+			e.data = value
+		}
+	}
+	return scanner.Err()
+}
+
+// printEvent prints the event of a note.
+func printEvent(e event) {
+	if e.name == "reset" {
+		output.Println("Some events were missed, the notes may have changed.")
+		return
+	}
+
+	var note Note
+	if err := json.Unmarshal([]byte(e.data), &note); err != nil {
+		output.PrintlnErr(fmt.Errorf("error reading the event %s: %w", e.id, err))
+		return
+	}
+	message := fmt.Sprintf("%s | %s | Category: %s | ID: %s", time.Now().Format(time.TimeOnly), e.name, note.Category, note.ID)
+	if len(note.Note) > 0 {
+		message += " | Note: " + note.Note
+	}
+	output.Println(message)
+}
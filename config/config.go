@@ -11,17 +11,80 @@ import (
 type Configuration struct {
 	Server   Server
 	Services Services
+	Tracing  Tracing
+}
+
+// Tracing contains the configuration for OpenTelemetry tracing.
+type Tracing struct {
+	// Exporter is the span exporter: none, otlp or stdout. The OTLP
+	// exporter is configured with the OTEL_EXPORTER_OTLP_* environment
+	// variables.
+	Exporter    string  `env:"TRACING_EXPORTER"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO"`
 }
 
 // Server contains the configuration for the server.
 type Server struct {
 	Host string
 	Port string
+	// GRPCPort is the port of the gRPC API, which is served alongside
+	// the HTTP API. The gRPC API is disabled when it is not set.
+	GRPCPort  string `env:"SERVER_GRPC_PORT"`
+	RateLimit RateLimit
+	// IdempotencyTTL is how long responses to create requests with an
+	// Idempotency-Key header are stored and replayed.
+	IdempotencyTTL time.Duration `env:"SERVER_IDEMPOTENCY_TTL"`
+	// MetricsEnabled enables the Prometheus /metrics endpoint.
+	MetricsEnabled bool `env:"SERVER_METRICS_ENABLED"`
+	// ShutdownDelay is how long the server keeps serving requests after
+	// the readiness probe starts failing on shutdown.
+	ShutdownDelay time.Duration `env:"SERVER_SHUTDOWN_DELAY"`
+	TLS           TLS
+	CORS          CORS
+	// UIEnabled enables the web front-end under /ui.
+	UIEnabled bool `env:"SERVER_UI_ENABLED"`
+	// AdminEnabled enables the export and import of all notes under
+	// /admin.
+	AdminEnabled bool `env:"SERVER_ADMIN_ENABLED"`
+}
+
+// CORS contains the CORS configuration for the server. CORS is enabled
+// when AllowedOrigins is set. Lists are comma separated.
+type CORS struct {
+	AllowedOrigins   []string      `env:"SERVER_CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `env:"SERVER_CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `env:"SERVER_CORS_ALLOWED_HEADERS"`
+	AllowCredentials bool          `env:"SERVER_CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `env:"SERVER_CORS_MAX_AGE"`
+}
+
+// TLS contains the TLS configuration for the server. TLS is enabled
+// when CertFile is set, and mutual TLS when ClientCAFile is set.
+type TLS struct {
+	CertFile     string `env:"SERVER_TLS_CERT_FILE"`
+	KeyFile      string `env:"SERVER_TLS_KEY_FILE"`
+	MinVersion   string `env:"SERVER_TLS_MIN_VERSION"`
+	ClientCAFile string `env:"SERVER_TLS_CLIENT_CA_FILE"`
+}
+
+// RateLimit contains the per-client rate limit configuration for the server.
+// Rates are given in requests per second.
+type RateLimit struct {
+	Enabled    bool    `env:"SERVER_RATE_LIMIT_ENABLED"`
+	ReadRate   float64 `env:"SERVER_RATE_LIMIT_READ_RATE"`
+	ReadBurst  int     `env:"SERVER_RATE_LIMIT_READ_BURST"`
+	WriteRate  float64 `env:"SERVER_RATE_LIMIT_WRITE_RATE"`
+	WriteBurst int     `env:"SERVER_RATE_LIMIT_WRITE_BURST"`
+	// APIKeys are the API keys that identify a client, other clients are
+	// identified by their IP address.
+	APIKeys []string `env:"SERVER_RATE_LIMIT_API_KEYS"`
 }
 
 type Services struct {
 	Note     Note
 	Database Database
+	Webhooks Webhooks
+	Outbox   Outbox
 	Log      Logger
 }
 
@@ -29,13 +92,91 @@ type Note struct {
 	Timeout time.Duration
 }
 
+// Webhooks contains the configuration of the webhooks and their
+// deliveries. The webhooks are stored in File, and kept in memory only if
+// it is empty.
+type Webhooks struct {
+	Timeout             time.Duration `env:"WEBHOOKS_TIMEOUT"`
+	MaxAttempts         int           `env:"WEBHOOKS_MAX_ATTEMPTS"`
+	BaseDelay           time.Duration `env:"WEBHOOKS_RETRY_BASE_DELAY"`
+	MaxDelay            time.Duration `env:"WEBHOOKS_RETRY_MAX_DELAY"`
+	File                string        `env:"WEBHOOKS_FILE"`
+	AllowPrivateTargets bool          `env:"WEBHOOKS_ALLOW_PRIVATE_TARGETS"`
+}
+
+// Outbox contains the configuration of the transactional outbox of the
+// note events. Sinks is a comma separated list of sse, webhooks and file,
+// and File is the path of the file sink.
+type Outbox struct {
+	Enabled      bool          `env:"OUTBOX_ENABLED"`
+	PollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL"`
+	Sinks        []string      `env:"OUTBOX_SINKS"`
+	File         string        `env:"OUTBOX_FILE"`
+}
+
+// Database contains the configuration of the notes database. Backend is
+// cosmos (the default), filesystem or git.
 type Database struct {
+	Backend               string `env:"DB_BACKEND"`
 	CosmosContainerClient Client
+	Filesystem            Filesystem
+	Git                   Git
+	Retry                 Retry
+	CircuitBreaker        CircuitBreaker
+	Cache                 Cache
+	ChangeFeed            ChangeFeed
 	Log                   Logger
 }
 
+// Cache contains the configuration of the read-through cache for notes.
+type Cache struct {
+	Enabled bool          `env:"DB_CACHE_ENABLED"`
+	Size    int           `env:"DB_CACHE_SIZE"`
+	TTL     time.Duration `env:"DB_CACHE_TTL"`
+}
+
+// ChangeFeed contains the configuration of the processor of the change
+// feed of the notes container. The processor invalidates the cache of the
+// instance with the changes made after it started.
+type ChangeFeed struct {
+	Enabled      bool          `env:"DB_CHANGE_FEED_ENABLED"`
+	PollInterval time.Duration `env:"DB_CHANGE_FEED_POLL_INTERVAL"`
+}
+
+// CircuitBreaker contains the circuit breaker configuration for the database.
+type CircuitBreaker struct {
+	FailureThreshold int           `env:"DB_CIRCUIT_BREAKER_FAILURE_THRESHOLD"`
+	OpenTimeout      time.Duration `env:"DB_CIRCUIT_BREAKER_OPEN_TIMEOUT"`
+}
+
+// Retry contains the retry policy for transient database errors.
+type Retry struct {
+	MaxRetries int           `env:"DB_RETRY_MAX_RETRIES"`
+	BaseDelay  time.Duration `env:"DB_RETRY_BASE_DELAY"`
+	MaxDelay   time.Duration `env:"DB_RETRY_MAX_DELAY"`
+}
+
+// Filesystem contains the configuration of the filesystem backend, which
+// stores the notes as Markdown files in Dir.
+type Filesystem struct {
+	Dir string `env:"DB_FILESYSTEM_DIR"`
+}
+
+// Git contains the configuration of the git backend, which stores the
+// notes as Markdown files in the bare repository Dir, with a commit per
+// change on Branch. Changes without an author are committed by the
+// author of AuthorName and AuthorEmail.
+type Git struct {
+	Dir         string `env:"DB_GIT_DIR"`
+	Branch      string `env:"DB_GIT_BRANCH"`
+	AuthorName  string `env:"DB_GIT_AUTHOR_NAME"`
+	AuthorEmail string `env:"DB_GIT_AUTHOR_EMAIL"`
+}
+
+// Client contains the configuration of the Cosmos DB backend. The
+// connection string is required by the cosmos backend.
 type Client struct {
-	ConnectionString string `env:"COSMOSDB_CONNECTION_STRING,required"`
+	ConnectionString string `env:"COSMOSDB_CONNECTION_STRING"`
 	DatabaseID       string `env:"COSMOSDB_DATABASE_ID"`
 	ContainerID      string `env:"COSMOSDB_CONTAINER_ID"`
 }
@@ -62,27 +203,88 @@ func New(options ...Option) (Configuration, error) {
 		Server: Server{
 			Host: defaultServerHost,
 			Port: defaultServerPort,
+			RateLimit: RateLimit{
+				Enabled:    defaultRateLimitEnabled,
+				ReadRate:   defaultRateLimitReadRate,
+				ReadBurst:  defaultRateLimitReadBurst,
+				WriteRate:  defaultRateLimitWriteRate,
+				WriteBurst: defaultRateLimitWriteBurst,
+			},
+			IdempotencyTTL: defaultIdempotencyTTL,
+			MetricsEnabled: defaultMetricsEnabled,
+			ShutdownDelay:  defaultShutdownDelay,
+			TLS: TLS{
+				MinVersion: defaultTLSMinVersion,
+			},
+			CORS: CORS{
+				MaxAge: defaultCORSMaxAge,
+			},
+			UIEnabled:    defaultUIEnabled,
+			AdminEnabled: defaultAdminEnabled,
 		},
 		Services: Services{
 			Note: Note{
 				Timeout: defaultNoteTimeout,
 			},
 			Database: Database{
+				Backend: defaultDBBackend,
 				CosmosContainerClient: Client{
 					DatabaseID:  defaultCosmosDatabaseID,
 					ContainerID: defaultCosmosContainerID,
 				},
+				Filesystem: Filesystem{
+					Dir: defaultDBFilesystemDir,
+				},
+				Git: Git{
+					Dir:    defaultDBGitDir,
+					Branch: defaultDBGitBranch,
+				},
+				Retry: Retry{
+					MaxRetries: defaultDBRetryMaxRetries,
+					BaseDelay:  defaultDBRetryBaseDelay,
+					MaxDelay:   defaultDBRetryMaxDelay,
+				},
+				CircuitBreaker: CircuitBreaker{
+					FailureThreshold: defaultDBCircuitBreakerFailureThreshold,
+					OpenTimeout:      defaultDBCircuitBreakerOpenTimeout,
+				},
+				Cache: Cache{
+					Enabled: defaultDBCacheEnabled,
+					Size:    defaultDBCacheSize,
+					TTL:     defaultDBCacheTTL,
+				},
+				ChangeFeed: ChangeFeed{
+					PollInterval: defaultDBChangeFeedPollInterval,
+				},
 				Log: Logger{
 					DBLevel: defaultDBLogLevel,
 				},
 			},
+			Webhooks: Webhooks{
+				Timeout:     defaultWebhooksTimeout,
+				MaxAttempts: defaultWebhooksMaxAttempts,
+				BaseDelay:   defaultWebhooksBaseDelay,
+				MaxDelay:    defaultWebhooksMaxDelay,
+			},
+			Outbox: Outbox{
+				PollInterval: defaultOutboxPollInterval,
+				File:         defaultOutboxFile,
+			},
 			Log: Logger{
 				ServiceLevel: defaultServiceLogLevel,
 			},
 		},
+		Tracing: Tracing{
+			Exporter:    defaultTracingExporter,
+			SampleRatio: defaultTracingSampleRatio,
+		},
 	}
 
-	if err := envconfig.Process(context.Background(), &cfg); err != nil {
+	// The environment variables overwrite the defaults set above.
+	if err := envconfig.ProcessWith(context.Background(), &envconfig.Config{
+		Target:           &cfg,
+		DefaultOverwrite: true,
+	}); err != nil {
 		return cfg, err
 	}
 
@@ -4,8 +4,24 @@ import "time"
 
 // Default server configuration.
 const (
-	defaultServerHost = "localhost"
-	defaultServerPort = "3000"
+	defaultServerHost     = "localhost"
+	defaultServerPort     = "3000"
+	defaultIdempotencyTTL = 24 * time.Hour
+	defaultMetricsEnabled = true
+	defaultShutdownDelay  = 5 * time.Second
+	defaultTLSMinVersion  = "1.2"
+	defaultCORSMaxAge     = 10 * time.Minute
+	defaultUIEnabled      = true
+	defaultAdminEnabled   = false
+)
+
+// Default rate limit configuration.
+const (
+	defaultRateLimitEnabled    = true
+	defaultRateLimitReadRate   = 20
+	defaultRateLimitReadBurst  = 40
+	defaultRateLimitWriteRate  = 5
+	defaultRateLimitWriteBurst = 10
 )
 
 // Default Note configuration.
@@ -13,12 +29,61 @@ const (
 	defaultNoteTimeout = 10 * time.Second
 )
 
+// Default webhooks configuration.
+const (
+	defaultWebhooksTimeout     = 10 * time.Second
+	defaultWebhooksMaxAttempts = 5
+	defaultWebhooksBaseDelay   = time.Second
+	defaultWebhooksMaxDelay    = time.Minute
+)
+
+// Default outbox configuration. The sinks are used when OUTBOX_SINKS is
+// not set.
+const (
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxSinks        = "sse,webhooks"
+	defaultOutboxFile         = "events.log"
+)
+
+// Default database backend configuration.
+const (
+	defaultDBBackend       = "cosmos"
+	defaultDBFilesystemDir = "notes"
+	defaultDBGitDir        = "notes.git"
+	defaultDBGitBranch     = "main"
+)
+
 // Default CosmosDB configuration.
 const (
 	defaultCosmosDatabaseID  = "NotesDB"
 	defaultCosmosContainerID = "notes"
 )
 
+// Default retry configuration for DB.
+const (
+	defaultDBRetryMaxRetries = 3
+	defaultDBRetryBaseDelay  = 100 * time.Millisecond
+	defaultDBRetryMaxDelay   = 2 * time.Second
+)
+
+// Default circuit breaker configuration for DB.
+const (
+	defaultDBCircuitBreakerFailureThreshold = 5
+	defaultDBCircuitBreakerOpenTimeout      = 30 * time.Second
+)
+
+// Default cache configuration for DB.
+const (
+	defaultDBCacheEnabled = true
+	defaultDBCacheSize    = 1000
+	defaultDBCacheTTL     = 30 * time.Second
+)
+
+// Default change feed configuration for DB.
+const (
+	defaultDBChangeFeedPollInterval = 5 * time.Second
+)
+
 // Default Logger configuration for Service.
 const (
 	defaultServiceLogLevel = "INFO"
@@ -28,3 +93,9 @@ const (
 const (
 	defaultDBLogLevel = "INFO"
 )
+
+// Default tracing configuration.
+const (
+	defaultTracingExporter    = "none"
+	defaultTracingSampleRatio = 1.0
+)
@@ -1,30 +1,123 @@
 package config
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/KatrinSalt/notes-service/db"
 	"github.com/KatrinSalt/notes-service/log"
+	"github.com/KatrinSalt/notes-service/metrics"
 	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/KatrinSalt/notes-service/webhooks"
 )
 
 type services struct {
 	Note notes.Service
+	// NotesArchive exports and imports all notes.
+	NotesArchive *notes.Archive
+	// NotesHistory reads the history of the notes and restores them, it
+	// is nil if the backend is not git.
+	NotesHistory *notes.History
+	// NoteEvents is the broker of the create, update and delete events
+	// of the notes service.
+	NoteEvents *notes.Broker
+	// Webhooks delivers the events of the notes service to the
+	// registered webhooks.
+	Webhooks *webhooks.Dispatcher
+	// NotesCache is the cache in front of the notes database, it is nil
+	// if the cache is disabled.
+	NotesCache *db.Cache
+	// NotesDBPing checks that the notes database can be reached.
+	NotesDBPing func(ctx context.Context) error
+	// NotesDBBreaker is the circuit breaker in front of the notes
+	// database, it is nil if the backend is not cosmos.
+	NotesDBBreaker *db.CircuitBreaker
+	// NotesChangeFeed processes the change feed of the notes container,
+	// it is nil if the change feed is disabled.
+	NotesChangeFeed *db.ChangeFeedProcessor
+	// OutboxRelay delivers the note events of the outbox to the event
+	// sinks, it is nil if the outbox is disabled.
+	OutboxRelay *notes.OutboxRelay
 }
 
-func SetupServices(config Services) (*services, error) {
+// notesDBStack holds the notes database of the backend and the layers
+// below it. notesDB and breaker are only set by the cosmos backend, and
+// gitDB by the git backend.
+type notesDBStack struct {
+	database notesDatabase
+	ping     func(ctx context.Context) error
+	notesDB  *db.NotesDB
+	breaker  *db.CircuitBreaker
+	gitDB    *db.GitDB
+}
+
+// notesDatabase is the interface implemented by the notes database
+// and the layers wrapping it.
+type notesDatabase interface {
+	CreateNote(ctx context.Context, note db.Note) (db.Note, error)
+	UpdateNote(ctx context.Context, note db.Note) (db.Note, error)
+	DeleteNote(ctx context.Context, id, category string) error
+	GetNotesByCategory(ctx context.Context, category string) ([]db.Note, error)
+	GetNotesByCategories(ctx context.Context, categories []string) ([]db.Note, error)
+	ListCategories(ctx context.Context) ([]string, error)
+	GetNoteByID(ctx context.Context, category, id string) (db.Note, error)
+}
+
+// SetupServices sets up the services. If metrics is not nil, the
+// database layers report their metrics to it.
+func SetupServices(config Services, metrics *metrics.Metrics) (*services, error) {
 	logger, err := setupLogger(config.Log.ServiceLevel)
 	if err != nil {
 		return nil, err
 	}
 
-	notesDB, err := setupNotesDB(config.Database)
+	stack, err := setupDatabase(config.Database, config.Outbox.Enabled, metrics)
 	if err != nil {
 		return nil, err
 	}
 
-	notesvc, err := notes.NewService(notesDB, logger, func(o *notes.ServiceOptions) {
+	database := stack.database
+	if metrics != nil {
+		database, err = db.NewInstrumentedDB(stack.database, metrics)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cache *db.Cache
+	if config.Database.Cache.Enabled {
+		cache, err = db.NewCache(database, func(o *db.CacheOptions) {
+			o.Size = config.Database.Cache.Size
+			o.TTL = config.Database.Cache.TTL
+		})
+		if err != nil {
+			return nil, err
+		}
+		database = cache
+		if metrics != nil {
+			metrics.RegisterCache(cache)
+		}
+	}
+
+	var changeFeed *db.ChangeFeedProcessor
+	if config.Database.ChangeFeed.Enabled {
+		changeFeed, err = setupChangeFeed(config.Database, cache)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// With the outbox, the events are written with the notes and relayed
+	// to the broker and the webhooks, instead of being published by the
+	// service.
+	broker := notes.NewBroker()
+	notesvc, err := notes.NewService(database, logger, func(o *notes.ServiceOptions) {
 		o.Timeout = config.Note.Timeout
+		if !config.Outbox.Enabled {
+			o.Broker = broker
+		}
 	},
 	)
 
@@ -32,34 +125,215 @@ func SetupServices(config Services) (*services, error) {
 		return nil, err
 	}
 
+	// Imports are written with the service, so that they invalidate the
+	// cache and publish their events.
+	archive, err := notes.NewArchive(database, notesvc)
+	if err != nil {
+		return nil, err
+	}
+
+	// The history is read from the repository, and restores are written
+	// with the service so that they invalidate the cache and publish
+	// their events.
+	var history *notes.History
+	if stack.gitDB != nil {
+		history, err = notes.NewHistory(stack.gitDB, notesvc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var events *notes.Broker
+	if !config.Outbox.Enabled {
+		events = broker
+	}
+	dispatcher, err := webhooks.NewDispatcher(events, logger, func(o *webhooks.DispatcherOptions) {
+		o.Timeout = config.Webhooks.Timeout
+		o.AllowPrivateTargets = config.Webhooks.AllowPrivateTargets
+		if len(config.Webhooks.File) > 0 {
+			o.Store = webhooks.NewFileStore(config.Webhooks.File)
+		}
+		o.MaxAttempts = config.Webhooks.MaxAttempts
+		o.BaseDelay = config.Webhooks.BaseDelay
+		o.MaxDelay = config.Webhooks.MaxDelay
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var relay *notes.OutboxRelay
+	if config.Outbox.Enabled {
+		relay, err = setupOutboxRelay(config.Outbox, stack.notesDB, broker, dispatcher, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &services{
-		Note: notesvc,
+		Note:            notesvc,
+		NotesArchive:    archive,
+		NotesHistory:    history,
+		NoteEvents:      broker,
+		Webhooks:        dispatcher,
+		NotesCache:      cache,
+		NotesDBPing:     stack.ping,
+		NotesDBBreaker:  stack.breaker,
+		NotesChangeFeed: changeFeed,
+		OutboxRelay:     relay,
 	}, nil
 
 }
 
-func setupNotesDB(config Database) (*db.NotesDB, error) {
+// setupDatabase sets up the notes database of the configured backend.
+// The outbox and the change feed are only supported by the cosmos
+// backend.
+func setupDatabase(config Database, outbox bool, metrics *metrics.Metrics) (notesDBStack, error) {
+	if config.Backend != "" && config.Backend != "cosmos" && (outbox || config.ChangeFeed.Enabled) {
+		return notesDBStack{}, errors.New("the outbox and the change feed require the cosmos database backend")
+	}
+	switch config.Backend {
+	case "", "cosmos":
+		return setupNotesDB(config, outbox, metrics)
+	case "filesystem":
+		filesystemDB, err := db.NewFilesystemDB(config.Filesystem.Dir)
+		if err != nil {
+			return notesDBStack{}, err
+		}
+		return notesDBStack{database: filesystemDB, ping: filesystemDB.Ping}, nil
+	case "git":
+		gitDB, err := db.NewGitDB(config.Git.Dir, func(o *db.GitDBOptions) {
+			o.Branch = config.Git.Branch
+			o.Author = db.Author{Name: config.Git.AuthorName, Email: config.Git.AuthorEmail}
+		})
+		if err != nil {
+			return notesDBStack{}, err
+		}
+		return notesDBStack{database: gitDB, ping: gitDB.Ping, gitDB: gitDB}, nil
+	default:
+		return notesDBStack{}, fmt.Errorf("unknown database backend: %s", config.Backend)
+	}
+}
+
+func setupNotesDB(config Database, outbox bool, metrics *metrics.Metrics) (notesDBStack, error) {
 	if len(config.CosmosContainerClient.ConnectionString) == 0 {
-		return nil, errors.New("cosmosdb connection string is empty")
+		return notesDBStack{}, errors.New("cosmosdb connection string is empty")
 	}
 	if len(config.CosmosContainerClient.DatabaseID) == 0 {
-		return nil, errors.New("cosmosdb database id is empty")
+		return notesDBStack{}, errors.New("cosmosdb database id is empty")
 	}
 	if len(config.CosmosContainerClient.DatabaseID) == 0 {
-		return nil, errors.New("cosmosdb container id is empty")
+		return notesDBStack{}, errors.New("cosmosdb container id is empty")
 	}
 
 	containerClient, err := db.NewCosmosContainerClient(config.CosmosContainerClient.ConnectionString,
+		config.CosmosContainerClient.DatabaseID, config.CosmosContainerClient.ContainerID, func(o *db.CosmosContainerClientOptions) {
+			if metrics != nil {
+				o.OnRequestCharge = metrics.ObserveRequestCharge
+			}
+		})
+	if err != nil {
+		return notesDBStack{}, err
+	}
+
+	retryClient, err := db.NewRetryClient(containerClient, func(o *db.RetryOptions) {
+		o.MaxRetries = config.Retry.MaxRetries
+		o.BaseDelay = config.Retry.BaseDelay
+		o.MaxDelay = config.Retry.MaxDelay
+	})
+	if err != nil {
+		return notesDBStack{}, err
+	}
+
+	logger, err := setupLogger(config.Log.DBLevel)
+	if err != nil {
+		return notesDBStack{}, err
+	}
+
+	breaker, err := db.NewCircuitBreaker(retryClient, func(o *db.CircuitBreakerOptions) {
+		o.Logger = logger
+		o.FailureThreshold = config.CircuitBreaker.FailureThreshold
+		o.OpenTimeout = config.CircuitBreaker.OpenTimeout
+	})
+	if err != nil {
+		return notesDBStack{}, err
+	}
+
+	if metrics != nil {
+		metrics.RegisterCircuitBreaker(breaker)
+	}
+
+	notesDB, err := db.NewNotesDB(breaker, func(o *db.NotesDBOptions) {
+		o.Outbox = outbox
+	})
+	if err != nil {
+		return notesDBStack{}, err
+	}
+	return notesDBStack{database: notesDB, ping: containerClient.Ping, notesDB: notesDB, breaker: breaker}, nil
+}
+
+// setupChangeFeed sets up the processor of the change feed of the notes
+// container, which is only used to invalidate the cache. The leases are
+// kept in memory, so that every instance reads all partitions and
+// invalidates its own cache, starting from the changes made after it
+// started. Handlers that must not miss changes across restarts need a
+// persisted lease store.
+func setupChangeFeed(config Database, cache *db.Cache) (*db.ChangeFeedProcessor, error) {
+	feed, err := db.NewCosmosChangeFeed(config.CosmosContainerClient.ConnectionString,
 		config.CosmosContainerClient.DatabaseID, config.CosmosContainerClient.ContainerID)
 	if err != nil {
 		return nil, err
 	}
 
-	notesDB, err := db.NewNotesDB(containerClient)
+	logger, err := setupLogger(config.Log.DBLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := db.NewChangeFeedProcessor(feed, db.NewMemoryLeaseStore(), func(o *db.ChangeFeedProcessorOptions) {
+		o.Logger = logger
+		o.PollInterval = config.ChangeFeed.PollInterval
+		o.StartFromNow = true
+	})
 	if err != nil {
 		return nil, err
 	}
-	return notesDB, nil
+
+	if cache != nil {
+		processor.Handle("cache", cache)
+	}
+	return processor, nil
+}
+
+// setupOutboxRelay sets up the relay of the outbox of the notes database
+// to the configured sinks.
+func setupOutboxRelay(config Outbox, notesDB *db.NotesDB, broker *notes.Broker, dispatcher *webhooks.Dispatcher, logger *log.Logger) (*notes.OutboxRelay, error) {
+	names := config.Sinks
+	if len(names) == 0 {
+		names = strings.Split(defaultOutboxSinks, ",")
+	}
+
+	var sinks []notes.EventSink
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "sse":
+			sinks = append(sinks, broker)
+		case "webhooks":
+			sinks = append(sinks, dispatcher)
+		case "file":
+			sink, err := notes.NewFileSink(config.File)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink: %s", name)
+		}
+	}
+
+	return notes.NewOutboxRelay(notesDB, logger, func(o *notes.OutboxRelayOptions) {
+		o.Sinks = sinks
+		o.PollInterval = config.PollInterval
+	})
 }
 
 func setupLogger(logLevel string) (*log.Logger, error) {
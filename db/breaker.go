@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+const (
+	// defaultFailureThreshold is the default number of consecutive failures
+	// that opens the circuit.
+	defaultFailureThreshold = 5
+	// defaultOpenTimeout is the default time the circuit stays open before
+	// a trial call is let through.
+	defaultOpenTimeout = 30 * time.Second
+)
+
+// logger is the interface that wraps around methods InfoContext and
+// ErrorContext. The context carries the request ID.
+type logger interface {
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all calls through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all calls without calling the database.
+	CircuitOpen
+	// CircuitHalfOpen lets a single trial call through to probe the database.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreaker is a client that stops calling the wrapped client after
+// consecutive failures, and fails fast with ErrCircuitOpen until the
+// database has recovered.
+type CircuitBreaker struct {
+	cl               client
+	log              logger
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+
+	mu            sync.Mutex
+	state         CircuitState
+	failures      int
+	openedAt      time.Time
+	trialInFlight bool
+	listeners     []func(from, to CircuitState)
+}
+
+// CircuitBreakerOptions contains options for the CircuitBreaker.
+type CircuitBreakerOptions struct {
+	Logger logger
+	// FailureThreshold is the number of consecutive failures that opens the circuit.
+	FailureThreshold int
+	// OpenTimeout is the time the circuit stays open before a trial call
+	// is let through.
+	OpenTimeout time.Duration
+}
+
+// CircuitBreakerOption is a function that sets options on the CircuitBreaker.
+type CircuitBreakerOption func(o *CircuitBreakerOptions)
+
+// NewCircuitBreaker returns a new CircuitBreaker wrapping the client.
+func NewCircuitBreaker(client client, options ...CircuitBreakerOption) (*CircuitBreaker, error) {
+	if client == nil {
+		return nil, ErrClientRequired
+	}
+
+	opts := CircuitBreakerOptions{
+		FailureThreshold: defaultFailureThreshold,
+		OpenTimeout:      defaultOpenTimeout,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.Logger == nil {
+		return nil, ErrLoggerRequired
+	}
+
+	return &CircuitBreaker{
+		cl:               client,
+		log:              opts.Logger,
+		failureThreshold: opts.FailureThreshold,
+		openTimeout:      opts.OpenTimeout,
+		now:              time.Now,
+	}, nil
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Check returns ErrCircuitOpen if the circuit is not closed.
+func (b *CircuitBreaker) Check(ctx context.Context) error {
+	if state := b.State(); state != CircuitClosed {
+		return fmt.Errorf("%w (%s)", ErrCircuitOpen, state)
+	}
+	return nil
+}
+
+// OnStateChange registers a function that is called on every state change.
+// The function must not call back into the CircuitBreaker.
+func (b *CircuitBreaker) OnStateChange(fn func(from, to CircuitState)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+func (b *CircuitBreaker) CreateItem(ctx context.Context, partitionKey string, item []byte) ([]byte, error) {
+	var resp []byte
+	err := b.do(ctx, func() error {
+		var err error
+		resp, err = b.cl.CreateItem(ctx, partitionKey, item)
+		return err
+	})
+	return resp, err
+}
+
+func (b *CircuitBreaker) ReplaceItem(ctx context.Context, partitionKey string, id string, item []byte) ([]byte, error) {
+	var resp []byte
+	err := b.do(ctx, func() error {
+		var err error
+		resp, err = b.cl.ReplaceItem(ctx, partitionKey, id, item)
+		return err
+	})
+	return resp, err
+}
+
+func (b *CircuitBreaker) DeleteItem(ctx context.Context, partitionKey string, id string) error {
+	return b.do(ctx, func() error {
+		return b.cl.DeleteItem(ctx, partitionKey, id)
+	})
+}
+
+func (b *CircuitBreaker) ReadItem(ctx context.Context, partitionKey string, id string) ([]byte, error) {
+	var resp []byte
+	err := b.do(ctx, func() error {
+		var err error
+		resp, err = b.cl.ReadItem(ctx, partitionKey, id)
+		return err
+	})
+	return resp, err
+}
+
+func (b *CircuitBreaker) ListItems(ctx context.Context, partitionKey string) ([][]byte, error) {
+	var resp [][]byte
+	err := b.do(ctx, func() error {
+		var err error
+		resp, err = b.cl.ListItems(ctx, partitionKey)
+		return err
+	})
+	return resp, err
+}
+
+func (b *CircuitBreaker) QueryItems(ctx context.Context, query string, parameters ...azcosmos.QueryParameter) ([][]byte, error) {
+	var resp [][]byte
+	err := b.do(ctx, func() error {
+		var err error
+		resp, err = b.cl.QueryItems(ctx, query, parameters...)
+		return err
+	})
+	return resp, err
+}
+
+func (b *CircuitBreaker) ExecuteBatch(ctx context.Context, partitionKey string, operations []BatchOperation) ([][]byte, error) {
+	var resp [][]byte
+	err := b.do(ctx, func() error {
+		var err error
+		resp, err = b.cl.ExecuteBatch(ctx, partitionKey, operations)
+		return err
+	})
+	return resp, err
+}
+
+// do calls op if the circuit allows it and records the outcome.
+func (b *CircuitBreaker) do(ctx context.Context, op func() error) error {
+	trial, err := b.before(ctx)
+	if err != nil {
+		return err
+	}
+	err = op()
+	b.after(ctx, trial, isFailure(ctx, err))
+	return err
+}
+
+// before returns ErrCircuitOpen if the call is not allowed. A call that
+// is let through in the half-open state is a trial call.
+func (b *CircuitBreaker) before(ctx context.Context) (trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false, ErrCircuitOpen
+		}
+		b.setState(ctx, CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.trialInFlight {
+			return false, ErrCircuitOpen
+		}
+		b.trialInFlight = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// after records the outcome of a call.
+func (b *CircuitBreaker) after(ctx context.Context, trial, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trialInFlight = false
+		if failed {
+			b.open(ctx)
+		} else {
+			b.failures = 0
+			b.setState(ctx, CircuitClosed)
+		}
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitClosed && b.failures >= b.failureThreshold {
+		b.open(ctx)
+	}
+}
+
+// open opens the circuit.
+func (b *CircuitBreaker) open(ctx context.Context) {
+	b.openedAt = b.now()
+	b.setState(ctx, CircuitOpen)
+}
+
+// setState changes the state, logs the change and notifies the listeners.
+func (b *CircuitBreaker) setState(ctx context.Context, state CircuitState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+
+	args := []any{"type", "database", "name", "circuitBreaker", "from", from.String(), "to", state.String()}
+	if state == CircuitOpen {
+		b.log.ErrorContext(ctx, "Circuit breaker state changed.", append(args, "failures", b.failures)...)
+	} else {
+		b.log.InfoContext(ctx, "Circuit breaker state changed.", args...)
+	}
+	for _, fn := range b.listeners {
+		fn(from, state)
+	}
+}
+
+// isFailure reports whether the error indicates that the database is
+// unhealthy. Client errors, such as a missing item, and calls cancelled
+// by the caller are not failures.
+func isFailure(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return false
+	}
+	var responseError *azcore.ResponseError
+	if errors.As(err, &responseError) {
+		return responseError.StatusCode >= http.StatusInternalServerError ||
+			responseError.StatusCode == http.StatusRequestTimeout
+	}
+	return true
+}
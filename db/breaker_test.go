@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CircuitBreaker(t *testing.T) {
+	unavailable := newResponseError(http.StatusServiceUnavailable, "")
+	notFound := newResponseError(http.StatusNotFound, "")
+
+	tests := []struct {
+		name        string
+		errs        []error
+		calls       int
+		elapse      time.Duration
+		wantCalls   int
+		wantState   CircuitState
+		expectError error
+	}{
+		{
+			name:      "CircuitBreaker - stays closed below the threshold",
+			errs:      []error{unavailable, unavailable},
+			calls:     3,
+			wantCalls: 3,
+			wantState: CircuitClosed,
+		},
+		{
+			name:        "CircuitBreaker - opens at the threshold and fails fast",
+			errs:        []error{unavailable, unavailable, unavailable},
+			calls:       5,
+			wantCalls:   3,
+			wantState:   CircuitOpen,
+			expectError: ErrCircuitOpen,
+		},
+		{
+			name:      "CircuitBreaker - client errors are not failures",
+			errs:      []error{notFound, notFound, notFound},
+			calls:     4,
+			wantCalls: 4,
+			wantState: CircuitClosed,
+		},
+		{
+			name:      "CircuitBreaker - closes after successful trial",
+			errs:      []error{unavailable, unavailable, unavailable},
+			calls:     4,
+			elapse:    time.Minute,
+			wantCalls: 4,
+			wantState: CircuitClosed,
+		},
+		{
+			name:        "CircuitBreaker - opens after failed trial",
+			errs:        []error{unavailable, unavailable, unavailable, unavailable},
+			calls:       5,
+			elapse:      time.Minute,
+			wantCalls:   4,
+			wantState:   CircuitOpen,
+			expectError: ErrCircuitOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fake := &failingClient{errs: tt.errs}
+			breaker, err := NewCircuitBreaker(fake, func(o *CircuitBreakerOptions) {
+				o.Logger = &mockLogger{}
+				o.FailureThreshold = 3
+				o.OpenTimeout = 30 * time.Second
+			})
+			require.NoError(t, err)
+
+			now := time.Now()
+			breaker.now = func() time.Time { return now }
+
+			// Act
+			for i := 0; i < tt.calls; i++ {
+				if i == 3 {
+					now = now.Add(tt.elapse)
+				}
+				_, err = breaker.ReadItem(context.Background(), "category", "id")
+			}
+
+			// Assert
+			require.Equal(t, tt.wantCalls, fake.calls)
+			require.Equal(t, tt.wantState, breaker.State())
+			if tt.expectError != nil {
+				require.ErrorIs(t, err, tt.expectError)
+				require.ErrorIs(t, err, ErrUnavailable)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_CircuitBreaker_OnStateChange(t *testing.T) {
+	fake := &failingClient{errs: []error{assert.AnError}}
+	log := &mockLogger{}
+	breaker, err := NewCircuitBreaker(fake, func(o *CircuitBreakerOptions) {
+		o.Logger = log
+		o.FailureThreshold = 1
+		o.OpenTimeout = time.Second
+	})
+	require.NoError(t, err)
+
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	var changes []string
+	breaker.OnStateChange(func(from, to CircuitState) {
+		changes = append(changes, from.String()+"->"+to.String())
+	})
+
+	breaker.DeleteItem(context.Background(), "category", "id")
+	now = now.Add(time.Second)
+	breaker.DeleteItem(context.Background(), "category", "id")
+
+	require.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, changes)
+	require.Equal(t, 1, log.errors)
+	require.Equal(t, 2, log.infos)
+}
+
+func Test_CircuitBreaker_cancelledCall(t *testing.T) {
+	fake := &failingClient{errs: []error{context.Canceled}}
+	breaker, err := NewCircuitBreaker(fake, func(o *CircuitBreakerOptions) {
+		o.Logger = &mockLogger{}
+		o.FailureThreshold = 1
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = breaker.ListItems(ctx, "category")
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, CircuitClosed, breaker.State())
+}
+
+// mockLogger counts the logged messages.
+type mockLogger struct {
+	infos  int
+	errors int
+}
+
+func (l *mockLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.infos++
+}
+
+func (l *mockLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.errors++
+}
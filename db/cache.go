@@ -0,0 +1,340 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultCacheSize is the default maximum number of cached entries.
+	defaultCacheSize = 1000
+	// defaultCacheTTL is the default time an entry is cached for.
+	defaultCacheTTL = 30 * time.Second
+)
+
+// database is the interface that wraps around the note operations of
+// a storage backend.
+type database interface {
+	// CreateNote creates a new note.
+	CreateNote(ctx context.Context, note Note) (Note, error)
+	// UpdateNote updates a note.
+	UpdateNote(ctx context.Context, note Note) (Note, error)
+	// DeleteNote deletes a note.
+	DeleteNote(ctx context.Context, id, category string) error
+	// GetNotesByCategory returns a list of notes stored in DB.
+	GetNotesByCategory(ctx context.Context, category string) ([]Note, error)
+	// GetNotesByCategories returns the notes of the categories.
+	GetNotesByCategories(ctx context.Context, categories []string) ([]Note, error)
+	// ListCategories returns the categories that have notes.
+	ListCategories(ctx context.Context) ([]string, error)
+	// GetNoteByID returns a notes with id <id>.
+	GetNoteByID(ctx context.Context, category, id string) (Note, error)
+}
+
+// CacheStats contains the statistics of a Cache.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+// Cache is a read-through cache for notes that wraps another database.
+// Single notes, the lists of notes per category and the list of categories
+// are kept in an LRU with a TTL. Writes invalidate the cached note and the
+// list of its category, and the list of categories if they can change it.
+type Cache struct {
+	db      database
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	// gen is incremented on every invalidation, so that reads that raced
+	// with a write do not cache stale results.
+	gen uint64
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// cacheKey is the key of a cached note or, if id is empty, of the cached
+// list of notes in a category.
+type cacheKey struct {
+	category string
+	id       string
+	// categories is set on the key of the cached list of categories.
+	categories bool
+}
+
+// categoriesKey is the key of the cached list of categories.
+var categoriesKey = cacheKey{categories: true}
+
+// cacheEntry is an entry in the LRU list.
+type cacheEntry struct {
+	key        cacheKey
+	note       Note
+	notes      []Note
+	categories []string
+	expires    time.Time
+}
+
+// CacheOptions contains options for the Cache.
+type CacheOptions struct {
+	// Size is the maximum number of cached notes and lists.
+	Size int
+	// TTL is the time an entry is cached for.
+	TTL time.Duration
+}
+
+// CacheOption is a function that sets options on the Cache.
+type CacheOption func(o *CacheOptions)
+
+// NewCache returns a new Cache wrapping the database.
+func NewCache(db database, options ...CacheOption) (*Cache, error) {
+	if db == nil {
+		return nil, ErrDatabaseRequired
+	}
+
+	opts := CacheOptions{
+		Size: defaultCacheSize,
+		TTL:  defaultCacheTTL,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Cache{
+		db:      db,
+		size:    opts.Size,
+		ttl:     opts.TTL,
+		now:     time.Now,
+		entries: make(map[cacheKey]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+	}
+}
+
+func (c *Cache) CreateNote(ctx context.Context, note Note) (Note, error) {
+	noteDB, err := c.db.CreateNote(ctx, note)
+	c.invalidate(cacheKey{category: note.Category}, categoriesKey)
+	if err != nil {
+		return Note{}, err
+	}
+	c.set(&cacheEntry{key: cacheKey{category: noteDB.Category, id: noteDB.ID}, note: noteDB})
+	return noteDB, nil
+}
+
+func (c *Cache) UpdateNote(ctx context.Context, note Note) (Note, error) {
+	noteDB, err := c.db.UpdateNote(ctx, note)
+	c.invalidate(cacheKey{category: note.Category}, cacheKey{category: note.Category, id: note.ID})
+	if err != nil {
+		return Note{}, err
+	}
+	if noteDB.Category != note.Category {
+		// The note has moved to another category.
+		c.invalidate(cacheKey{category: noteDB.Category}, categoriesKey)
+	}
+	c.set(&cacheEntry{key: cacheKey{category: noteDB.Category, id: noteDB.ID}, note: noteDB})
+	return noteDB, nil
+}
+
+func (c *Cache) DeleteNote(ctx context.Context, id, category string) error {
+	err := c.db.DeleteNote(ctx, id, category)
+	c.invalidate(cacheKey{category: category}, cacheKey{category: category, id: id}, categoriesKey)
+	return err
+}
+
+func (c *Cache) GetNotesByCategory(ctx context.Context, category string) ([]Note, error) {
+	key := cacheKey{category: category}
+	entry, gen, ok := c.get(key)
+	if ok {
+		return append([]Note(nil), entry.notes...), nil
+	}
+
+	notes, err := c.db.GetNotesByCategory(ctx, category)
+	if err != nil {
+		return notes, err
+	}
+	c.setIfCurrent(&cacheEntry{key: key, notes: append([]Note(nil), notes...)}, gen)
+	return notes, nil
+}
+
+// GetNotesByCategories returns the notes of the categories. The cached
+// lists of notes are served from the cache, and the lists of the other
+// categories are read with a single call and cached.
+func (c *Cache) GetNotesByCategories(ctx context.Context, categories []string) ([]Note, error) {
+	var notes []Note
+	var missing []string
+	gens := make(map[string]uint64)
+	for _, category := range categories {
+		entry, gen, ok := c.get(cacheKey{category: category})
+		if ok {
+			notes = append(notes, entry.notes...)
+			continue
+		}
+		missing = append(missing, category)
+		gens[category] = gen
+	}
+	if len(missing) == 0 {
+		return notes, nil
+	}
+
+	read, err := c.db.GetNotesByCategories(ctx, missing)
+	if err != nil {
+		return read, err
+	}
+	byCategory := make(map[string][]Note, len(missing))
+	for _, note := range read {
+		byCategory[note.Category] = append(byCategory[note.Category], note)
+	}
+	for _, category := range missing {
+		c.setIfCurrent(&cacheEntry{key: cacheKey{category: category}, notes: byCategory[category]}, gens[category])
+	}
+	return append(notes, read...), nil
+}
+
+// ListCategories returns the cached list of categories. It is invalidated
+// by the creates and deletes, and the updates that move a note to another
+// category.
+func (c *Cache) ListCategories(ctx context.Context) ([]string, error) {
+	entry, gen, ok := c.get(categoriesKey)
+	if ok {
+		return append([]string(nil), entry.categories...), nil
+	}
+
+	categories, err := c.db.ListCategories(ctx)
+	if err != nil {
+		return categories, err
+	}
+	c.setIfCurrent(&cacheEntry{key: categoriesKey, categories: append([]string(nil), categories...)}, gen)
+	return categories, nil
+}
+
+func (c *Cache) GetNoteByID(ctx context.Context, category, id string) (Note, error) {
+	key := cacheKey{category: category, id: id}
+	entry, gen, ok := c.get(key)
+	if ok {
+		return entry.note, nil
+	}
+
+	note, err := c.db.GetNoteByID(ctx, category, id)
+	if err != nil {
+		return note, err
+	}
+	c.setIfCurrent(&cacheEntry{key: key, note: note}, gen)
+	return note, nil
+}
+
+// HandleChanges invalidates the cached notes of the changes of the change
+// feed, the lists of their categories and the list of categories, so that
+// writes that bypass the cache are not served stale until the TTL expires.
+func (c *Cache) HandleChanges(ctx context.Context, partition string, notes []Note) error {
+	keys := make([]cacheKey, 0, 2*len(notes)+1)
+	keys = append(keys, categoriesKey)
+	for _, note := range notes {
+		keys = append(keys, cacheKey{category: note.Category}, cacheKey{category: note.Category, id: note.ID})
+	}
+	c.invalidate(keys...)
+	return nil
+}
+
+// get returns the entry for the key if it is cached and not expired,
+// together with the current generation of the cache.
+func (c *Cache) get(key cacheKey) (*cacheEntry, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses.Add(1)
+		return nil, c.gen, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.remove(elem)
+		c.misses.Add(1)
+		return nil, c.gen, false
+	}
+	c.lru.MoveToFront(elem)
+	c.hits.Add(1)
+	return entry, c.gen, true
+}
+
+// setIfCurrent caches the entry if nothing has been invalidated since
+// the generation gen.
+func (c *Cache) setIfCurrent(entry *cacheEntry, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.gen != gen {
+		return
+	}
+	c.store(entry)
+}
+
+// set caches the entry.
+func (c *Cache) set(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(entry)
+}
+
+// store caches the entry and evicts the least recently used entries
+// if the cache is full. The caller must hold the lock.
+func (c *Cache) store(entry *cacheEntry) {
+	if c.size <= 0 {
+		return
+	}
+
+	entry.expires = c.now().Add(c.ttl)
+	if elem, ok := c.entries[entry.key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+// invalidate removes the entries with the keys from the cache.
+func (c *Cache) invalidate(keys ...cacheKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, key := range keys {
+		if elem, ok := c.entries[key]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// remove removes the element from the cache. The caller must hold the lock.
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,368 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Cache_GetNoteByID(t *testing.T) {
+	tests := []struct {
+		name       string
+		act        func(c *Cache, now *time.Time)
+		wantReads  int
+		wantHits   uint64
+		wantMisses uint64
+	}{
+		{
+			name: "GetNoteByID() - second read is a hit",
+			act: func(c *Cache, now *time.Time) {
+				c.GetNoteByID(context.Background(), "category", "1")
+				c.GetNoteByID(context.Background(), "category", "1")
+			},
+			wantReads:  1,
+			wantHits:   1,
+			wantMisses: 1,
+		},
+		{
+			name: "GetNoteByID() - expired entry is read again",
+			act: func(c *Cache, now *time.Time) {
+				c.GetNoteByID(context.Background(), "category", "1")
+				*now = now.Add(time.Minute)
+				c.GetNoteByID(context.Background(), "category", "1")
+			},
+			wantReads:  2,
+			wantMisses: 2,
+		},
+		{
+			name: "GetNoteByID() - least recently used entry is evicted",
+			act: func(c *Cache, now *time.Time) {
+				c.GetNoteByID(context.Background(), "category", "1")
+				c.GetNoteByID(context.Background(), "category", "2")
+				c.GetNoteByID(context.Background(), "category", "1")
+				c.GetNoteByID(context.Background(), "category", "3")
+				c.GetNoteByID(context.Background(), "category", "2")
+			},
+			wantReads:  4,
+			wantHits:   1,
+			wantMisses: 4,
+		},
+		{
+			name: "GetNoteByID() - errors are not cached",
+			act: func(c *Cache, now *time.Time) {
+				c.GetNoteByID(context.Background(), "category", "missing")
+				c.GetNoteByID(context.Background(), "category", "missing")
+			},
+			wantReads:  2,
+			wantMisses: 2,
+		},
+		{
+			name: "GetNoteByID() - update invalidates the note",
+			act: func(c *Cache, now *time.Time) {
+				c.GetNoteByID(context.Background(), "category", "1")
+				c.UpdateNote(context.Background(), Note{ID: "1", Category: "category", Note: "updated"})
+				note, _ := c.GetNoteByID(context.Background(), "category", "1")
+				assert.Equal(t, "updated", note.Note)
+			},
+			wantReads:  1,
+			wantHits:   1,
+			wantMisses: 1,
+		},
+		{
+			name: "GetNoteByID() - delete invalidates the note",
+			act: func(c *Cache, now *time.Time) {
+				c.GetNoteByID(context.Background(), "category", "1")
+				c.DeleteNote(context.Background(), "1", "category")
+				_, err := c.GetNoteByID(context.Background(), "category", "1")
+				assert.ErrorIs(t, err, ErrNotFound)
+			},
+			wantReads:  2,
+			wantMisses: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fake := newFakeDatabase(
+				Note{ID: "1", Category: "category", Note: "note 1"},
+				Note{ID: "2", Category: "category", Note: "note 2"},
+				Note{ID: "3", Category: "category", Note: "note 3"},
+			)
+			cache, err := NewCache(fake, func(o *CacheOptions) {
+				o.Size = 2
+				o.TTL = 30 * time.Second
+			})
+			require.NoError(t, err)
+			now := time.Now()
+			cache.now = func() time.Time { return now }
+
+			// Act
+			tt.act(cache, &now)
+
+			// Assert
+			stats := cache.Stats()
+			require.Equal(t, tt.wantReads, fake.reads)
+			require.Equal(t, tt.wantHits, stats.Hits)
+			require.Equal(t, tt.wantMisses, stats.Misses)
+		})
+	}
+}
+
+func Test_Cache_GetNotesByCategory(t *testing.T) {
+	tests := []struct {
+		name      string
+		write     func(c *Cache)
+		wantNotes int
+		wantLists int
+	}{
+		{
+			name:      "GetNotesByCategory() - list is cached",
+			write:     func(c *Cache) {},
+			wantNotes: 1,
+			wantLists: 1,
+		},
+		{
+			name: "GetNotesByCategory() - create invalidates the list",
+			write: func(c *Cache) {
+				c.CreateNote(context.Background(), Note{ID: "2", Category: "category"})
+			},
+			wantNotes: 2,
+			wantLists: 2,
+		},
+		{
+			name: "GetNotesByCategory() - create in other category keeps the list",
+			write: func(c *Cache) {
+				c.CreateNote(context.Background(), Note{ID: "2", Category: "other"})
+			},
+			wantNotes: 1,
+			wantLists: 1,
+		},
+		{
+			name: "GetNotesByCategory() - update invalidates the list",
+			write: func(c *Cache) {
+				c.UpdateNote(context.Background(), Note{ID: "1", Category: "category", Note: "updated"})
+			},
+			wantNotes: 1,
+			wantLists: 2,
+		},
+		{
+			name: "GetNotesByCategory() - delete invalidates the list",
+			write: func(c *Cache) {
+				c.DeleteNote(context.Background(), "1", "category")
+			},
+			wantNotes: 0,
+			wantLists: 2,
+		},
+		{
+			name: "GetNotesByCategory() - changes of the change feed invalidate the list",
+			write: func(c *Cache) {
+				c.HandleChanges(context.Background(), "0", []Note{{ID: "1", Category: "category"}})
+			},
+			wantNotes: 1,
+			wantLists: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fake := newFakeDatabase(Note{ID: "1", Category: "category", Note: "note 1"})
+			cache, err := NewCache(fake)
+			require.NoError(t, err)
+
+			// Act
+			_, err = cache.GetNotesByCategory(context.Background(), "category")
+			require.NoError(t, err)
+			tt.write(cache)
+			notes, err := cache.GetNotesByCategory(context.Background(), "category")
+
+			// Assert
+			require.NoError(t, err)
+			require.Len(t, notes, tt.wantNotes)
+			require.Equal(t, tt.wantLists, fake.lists)
+		})
+	}
+}
+
+func Test_Cache_ListCategories(t *testing.T) {
+	tests := []struct {
+		name           string
+		write          func(c *Cache)
+		wantCategories []string
+		wantReads      int
+	}{
+		{
+			name:           "ListCategories() - list is cached",
+			write:          func(c *Cache) {},
+			wantCategories: []string{"category"},
+			wantReads:      1,
+		},
+		{
+			name: "ListCategories() - create invalidates the list",
+			write: func(c *Cache) {
+				c.CreateNote(context.Background(), Note{ID: "2", Category: "other"})
+			},
+			wantCategories: []string{"category", "other"},
+			wantReads:      2,
+		},
+		{
+			name: "ListCategories() - update keeps the list",
+			write: func(c *Cache) {
+				c.UpdateNote(context.Background(), Note{ID: "1", Category: "category", Note: "updated"})
+			},
+			wantCategories: []string{"category"},
+			wantReads:      1,
+		},
+		{
+			name: "ListCategories() - delete invalidates the list",
+			write: func(c *Cache) {
+				c.DeleteNote(context.Background(), "1", "category")
+			},
+			wantCategories: nil,
+			wantReads:      2,
+		},
+		{
+			name: "ListCategories() - changes of the change feed invalidate the list",
+			write: func(c *Cache) {
+				c.HandleChanges(context.Background(), "0", []Note{{ID: "1", Category: "category"}})
+			},
+			wantCategories: []string{"category"},
+			wantReads:      2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fake := newFakeDatabase(Note{ID: "1", Category: "category", Note: "note 1"})
+			cache, err := NewCache(fake)
+			require.NoError(t, err)
+
+			// Act
+			_, err = cache.ListCategories(context.Background())
+			require.NoError(t, err)
+			tt.write(cache)
+			categories, err := cache.ListCategories(context.Background())
+
+			// Assert
+			require.NoError(t, err)
+			require.ElementsMatch(t, tt.wantCategories, categories)
+			require.Equal(t, tt.wantReads, fake.categories)
+		})
+	}
+}
+
+func Test_Cache_GetNotesByCategories(t *testing.T) {
+	// Arrange
+	fake := newFakeDatabase(
+		Note{ID: "1", Category: "category", Note: "note 1"},
+		Note{ID: "2", Category: "other", Note: "note 2"},
+	)
+	cache, err := NewCache(fake)
+	require.NoError(t, err)
+
+	// Act
+	_, err = cache.GetNotesByCategory(context.Background(), "category")
+	require.NoError(t, err)
+	notes, err := cache.GetNotesByCategories(context.Background(), []string{"category", "other", "empty"})
+	require.NoError(t, err)
+	cached, err := cache.GetNotesByCategories(context.Background(), []string{"other", "empty"})
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, notes, 2)
+	require.Len(t, cached, 1)
+	require.Equal(t, 1, fake.lists)
+	require.Equal(t, [][]string{{"other", "empty"}}, fake.batches)
+}
+
+// fakeDatabase is an in-memory database that counts reads.
+type fakeDatabase struct {
+	notes      map[cacheKey]Note
+	reads      int
+	lists      int
+	categories int
+	batches    [][]string
+}
+
+func newFakeDatabase(notes ...Note) *fakeDatabase {
+	db := &fakeDatabase{notes: make(map[cacheKey]Note)}
+	for _, note := range notes {
+		db.notes[cacheKey{category: note.Category, id: note.ID}] = note
+	}
+	return db
+}
+
+func (db *fakeDatabase) CreateNote(ctx context.Context, note Note) (Note, error) {
+	key := cacheKey{category: note.Category, id: note.ID}
+	if _, ok := db.notes[key]; ok {
+		return Note{}, ErrAlreadyExists
+	}
+	db.notes[key] = note
+	return note, nil
+}
+
+func (db *fakeDatabase) UpdateNote(ctx context.Context, note Note) (Note, error) {
+	key := cacheKey{category: note.Category, id: note.ID}
+	if _, ok := db.notes[key]; !ok {
+		return Note{}, ErrNotFound
+	}
+	db.notes[key] = note
+	return note, nil
+}
+
+func (db *fakeDatabase) DeleteNote(ctx context.Context, id, category string) error {
+	key := cacheKey{category: category, id: id}
+	if _, ok := db.notes[key]; !ok {
+		return ErrNotFound
+	}
+	delete(db.notes, key)
+	return nil
+}
+
+func (db *fakeDatabase) GetNotesByCategory(ctx context.Context, category string) ([]Note, error) {
+	db.lists++
+	var notes []Note
+	for key, note := range db.notes {
+		if key.category == category {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+func (db *fakeDatabase) GetNotesByCategories(ctx context.Context, categories []string) ([]Note, error) {
+	db.batches = append(db.batches, categories)
+	var notes []Note
+	for key, note := range db.notes {
+		if slices.Contains(categories, key.category) {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+func (db *fakeDatabase) ListCategories(ctx context.Context) ([]string, error) {
+	db.categories++
+	var categories []string
+	for key := range db.notes {
+		if !slices.Contains(categories, key.category) {
+			categories = append(categories, key.category)
+		}
+	}
+	return categories, nil
+}
+
+func (db *fakeDatabase) GetNoteByID(ctx context.Context, category, id string) (Note, error) {
+	db.reads++
+	note, ok := db.notes[cacheKey{category: category, id: id}]
+	if !ok {
+		return Note{}, ErrNotFound
+	}
+	return note, nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import "context"
+
+// Author is the author of a change of a note. It is recorded by the
+// databases that keep a history of the changes.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// authorKey is the context key of the author of a change.
+type authorKey struct{}
+
+// changeMessageKey is the context key of the message of a change.
+type changeMessageKey struct{}
+
+// WithAuthor returns a copy of the context with the author of the
+// changes made with it.
+func WithAuthor(ctx context.Context, author Author) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// AuthorFromContext returns the author of the context, and false if the
+// context has no author.
+func AuthorFromContext(ctx context.Context) (Author, bool) {
+	author, ok := ctx.Value(authorKey{}).(Author)
+	return author, ok
+}
+
+// WithChangeMessage returns a copy of the context with a message that
+// describes the changes made with it, in place of the default message
+// of the database.
+func WithChangeMessage(ctx context.Context, message string) context.Context {
+	return context.WithValue(ctx, changeMessageKey{}, message)
+}
+
+// ChangeMessageFromContext returns the change message of the context,
+// and false if the context has no message.
+func ChangeMessageFromContext(ctx context.Context) (string, bool) {
+	message, ok := ctx.Value(changeMessageKey{}).(string)
+	return message, ok
+}
@@ -0,0 +1,383 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultChangeFeedPollInterval is the default interval between reads
+	// of a partition without new changes.
+	defaultChangeFeedPollInterval = 5 * time.Second
+	// defaultLeaseTTL is the default time a lease is held for without
+	// a checkpoint.
+	defaultLeaseTTL = 30 * time.Second
+)
+
+var (
+	// ErrChangeFeedRequired is returned when the change feed is not provided.
+	ErrChangeFeedRequired = errors.New("change feed is not provided")
+	// ErrLeaseStoreRequired is returned when the lease store is not provided.
+	ErrLeaseStoreRequired = errors.New("lease store is not provided")
+)
+
+var (
+	// ErrPartitionGone is returned when a partition of the change feed no
+	// longer exists, because it was split or merged.
+	ErrPartitionGone = errors.New("partition is gone")
+	// ErrLeaseTaken is returned when the lease of a partition is held by
+	// another owner.
+	ErrLeaseTaken = errors.New("lease is taken")
+	// ErrLeaseLost is returned when a lease expired and was taken by
+	// another owner.
+	ErrLeaseLost = errors.New("lease is lost")
+)
+
+// ContinuationNow is the continuation of a change feed that skips the
+// existing changes and starts with the next change.
+const ContinuationNow = "*"
+
+// ChangeFeed is the interface that wraps around the methods to read the
+// changes of a container per partition.
+type ChangeFeed interface {
+	// Partitions returns the IDs of the partitions of the feed.
+	Partitions(ctx context.Context) ([]string, error)
+	// ReadChanges returns the changes of the partition after the
+	// continuation, and the continuation of the returned changes. The
+	// changes are read from the beginning if continuation is empty, and
+	// from the next change if it is ContinuationNow.
+	ReadChanges(ctx context.Context, partition, continuation string) (ChangeFeedPage, error)
+}
+
+// ChangeFeedPage is a page of changes of a partition.
+type ChangeFeedPage struct {
+	// Notes contains the latest version of the created and updated notes.
+	// Deleted notes are not part of the feed.
+	Notes        []Note
+	Continuation string
+}
+
+// ChangeHandler handles the changes of a partition of the change feed.
+type ChangeHandler interface {
+	HandleChanges(ctx context.Context, partition string, notes []Note) error
+}
+
+// ChangeHandlerFunc is a function that implements ChangeHandler.
+type ChangeHandlerFunc func(ctx context.Context, partition string, notes []Note) error
+
+// HandleChanges calls f.
+func (f ChangeHandlerFunc) HandleChanges(ctx context.Context, partition string, notes []Note) error {
+	return f(ctx, partition, notes)
+}
+
+// Lease is the lease of a partition of the change feed. It holds the
+// continuation of the changes that were handled.
+type Lease struct {
+	Partition    string
+	Owner        string
+	Continuation string
+	ExpiresAt    time.Time
+}
+
+// LeaseStore stores the leases of the partitions of the change feed.
+type LeaseStore interface {
+	// Acquire acquires the lease of the partition for the owner. It
+	// returns ErrLeaseTaken if the lease is held by another owner and has
+	// not expired.
+	Acquire(ctx context.Context, partition, owner string, ttl time.Duration) (Lease, error)
+	// Checkpoint stores the continuation of the lease and extends it by
+	// the TTL. It returns ErrLeaseLost if the lease is held by another
+	// owner.
+	Checkpoint(ctx context.Context, lease Lease, ttl time.Duration) (Lease, error)
+	// Release releases the lease, keeping its continuation.
+	Release(ctx context.Context, lease Lease) error
+}
+
+// ChangeFeedProcessor reads the changes of every partition of a change
+// feed, dispatches them to the registered handlers and checkpoints the
+// continuation in a lease store. A partition is read by the processor
+// that holds its lease, so several processors can share a lease store.
+// Changes are handled at least once: if a handler fails, the changes are
+// read again from the last checkpoint.
+type ChangeFeedProcessor struct {
+	feed         ChangeFeed
+	leases       LeaseStore
+	log          logger
+	owner        string
+	pollInterval time.Duration
+	leaseTTL     time.Duration
+	startFromNow bool
+
+	mu       sync.Mutex
+	handlers []namedChangeHandler
+}
+
+// namedChangeHandler is a registered handler.
+type namedChangeHandler struct {
+	name    string
+	handler ChangeHandler
+}
+
+// ChangeFeedProcessorOptions contains options for the ChangeFeedProcessor.
+type ChangeFeedProcessorOptions struct {
+	Logger logger
+	// Owner identifies the processor in the leases. A random ID is used
+	// if it is empty.
+	Owner string
+	// PollInterval is the interval between reads of a partition without
+	// new changes, and between checks for new partitions.
+	PollInterval time.Duration
+	// LeaseTTL is the time a lease is held for without a checkpoint.
+	LeaseTTL time.Duration
+	// StartFromNow skips the existing changes of partitions that do not
+	// have a checkpoint yet. The changes are read from the beginning
+	// otherwise.
+	StartFromNow bool
+}
+
+// ChangeFeedProcessorOption is a function that sets options on the
+// ChangeFeedProcessor.
+type ChangeFeedProcessorOption func(o *ChangeFeedProcessorOptions)
+
+// NewChangeFeedProcessor returns a new ChangeFeedProcessor of the feed.
+func NewChangeFeedProcessor(feed ChangeFeed, leases LeaseStore, options ...ChangeFeedProcessorOption) (*ChangeFeedProcessor, error) {
+	if feed == nil {
+		return nil, ErrChangeFeedRequired
+	}
+	if leases == nil {
+		return nil, ErrLeaseStoreRequired
+	}
+
+	opts := ChangeFeedProcessorOptions{
+		PollInterval: defaultChangeFeedPollInterval,
+		LeaseTTL:     defaultLeaseTTL,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.Logger == nil {
+		return nil, ErrLoggerRequired
+	}
+	if len(opts.Owner) == 0 {
+		opts.Owner = uuid.NewString()
+	}
+
+	return &ChangeFeedProcessor{
+		feed:         feed,
+		leases:       leases,
+		log:          opts.Logger,
+		owner:        opts.Owner,
+		pollInterval: opts.PollInterval,
+		leaseTTL:     opts.LeaseTTL,
+		startFromNow: opts.StartFromNow,
+	}, nil
+}
+
+// Handle registers the handler under the name. Handlers are called in the
+// order they are registered.
+func (p *ChangeFeedProcessor) Handle(name string, handler ChangeHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = append(p.handlers, namedChangeHandler{name: name, handler: handler})
+}
+
+// Run processes the partitions of the feed until the context is canceled.
+// New partitions, and partitions whose lease expired, are picked up on
+// every poll interval. The leases are released when Run returns.
+func (p *ChangeFeedProcessor) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	var mu sync.Mutex
+	running := make(map[string]bool)
+
+	for {
+		partitions, err := p.feed.Partitions(ctx)
+		if err != nil && ctx.Err() == nil {
+			p.log.ErrorContext(ctx, "Failed to list the partitions of the change feed.", "error", err)
+		}
+		for _, partition := range partitions {
+			mu.Lock()
+			isRunning := running[partition]
+			mu.Unlock()
+			if isRunning {
+				continue
+			}
+
+			lease, err := p.leases.Acquire(ctx, partition, p.owner, p.leaseTTL)
+			if err != nil {
+				if !errors.Is(err, ErrLeaseTaken) && ctx.Err() == nil {
+					p.log.ErrorContext(ctx, "Failed to acquire the lease of a partition.", "partition", partition, "error", err)
+				}
+				continue
+			}
+
+			mu.Lock()
+			running[partition] = true
+			mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.processPartition(ctx, lease)
+				mu.Lock()
+				delete(running, partition)
+				mu.Unlock()
+			}()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.pollInterval):
+		}
+	}
+}
+
+// processPartition reads and handles the changes of the partition until
+// the context is canceled, the lease is lost or the partition is gone.
+func (p *ChangeFeedProcessor) processPartition(ctx context.Context, lease Lease) {
+	p.log.InfoContext(ctx, "Processing the partition of the change feed.", "partition", lease.Partition, "owner", p.owner)
+	if len(lease.Continuation) == 0 && p.startFromNow {
+		lease.Continuation = ContinuationNow
+	}
+	defer func() {
+		// The lease is released with a new context, since the context of
+		// the processor is canceled when it stops.
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.leaseTTL)
+		defer cancel()
+		if err := p.leases.Release(releaseCtx, lease); err != nil && !errors.Is(err, ErrLeaseLost) {
+			p.log.ErrorContext(ctx, "Failed to release the lease of a partition.", "partition", lease.Partition, "error", err)
+		}
+	}()
+
+	for {
+		wait, err := p.processChanges(ctx, &lease)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrLeaseLost):
+			p.log.InfoContext(ctx, "Lost the lease of a partition of the change feed.", "partition", lease.Partition)
+			return
+		case errors.Is(err, ErrPartitionGone):
+			// The partitions that replace it are picked up with the
+			// partitions of the feed.
+			p.log.InfoContext(ctx, "Partition of the change feed is gone.", "partition", lease.Partition)
+			return
+		case err != nil:
+			p.log.ErrorContext(ctx, "Failed to process the changes of a partition.", "partition", lease.Partition, "error", err)
+		}
+		if !wait && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.pollInterval):
+		}
+	}
+}
+
+// processChanges reads a page of changes of the partition, handles it and
+// checkpoints the lease. It reports whether the partition has no new
+// changes, so that the next read waits for the poll interval.
+func (p *ChangeFeedProcessor) processChanges(ctx context.Context, lease *Lease) (bool, error) {
+	page, err := p.feed.ReadChanges(ctx, lease.Partition, lease.Continuation)
+	if err != nil {
+		return true, err
+	}
+
+	if len(page.Notes) > 0 {
+		p.mu.Lock()
+		handlers := slices.Clone(p.handlers)
+		p.mu.Unlock()
+
+		for _, h := range handlers {
+			if err := h.handler.HandleChanges(ctx, lease.Partition, page.Notes); err != nil {
+				// The changes are read again from the last checkpoint.
+				return true, fmt.Errorf("handler %s: %w", h.name, err)
+			}
+		}
+	}
+
+	// The lease is checkpointed without new changes as well, to extend it.
+	lease.Continuation = page.Continuation
+	checkpointed, err := p.leases.Checkpoint(ctx, *lease, p.leaseTTL)
+	if err != nil {
+		return true, err
+	}
+	*lease = checkpointed
+	return len(page.Notes) == 0, nil
+}
+
+// MemoryLeaseStore is a LeaseStore that keeps the leases in memory. The
+// continuations are lost when the process stops, so the change feed is
+// read from the beginning again, or from the next change with
+// StartFromNow. It suits handlers that keep state of the process only,
+// such as the invalidation of a Cache: every process reads all
+// partitions, and the changes made while it was stopped do not matter.
+// Handlers that must see every change once across restarts need a lease
+// store that is shared by the processors and persisted.
+type MemoryLeaseStore struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	leases map[string]Lease
+}
+
+// NewMemoryLeaseStore returns a new MemoryLeaseStore.
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{
+		now:    time.Now,
+		leases: make(map[string]Lease),
+	}
+}
+
+// Acquire acquires the lease of the partition for the owner.
+func (s *MemoryLeaseStore) Acquire(ctx context.Context, partition, owner string, ttl time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lease, ok := s.leases[partition]
+	if ok && len(lease.Owner) > 0 && lease.Owner != owner && s.now().Before(lease.ExpiresAt) {
+		return Lease{}, ErrLeaseTaken
+	}
+	lease.Partition = partition
+	lease.Owner = owner
+	lease.ExpiresAt = s.now().Add(ttl)
+	s.leases[partition] = lease
+	return lease, nil
+}
+
+// Checkpoint stores the continuation of the lease and extends it.
+func (s *MemoryLeaseStore) Checkpoint(ctx context.Context, lease Lease, ttl time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leases[lease.Partition].Owner != lease.Owner {
+		return Lease{}, ErrLeaseLost
+	}
+	lease.ExpiresAt = s.now().Add(ttl)
+	s.leases[lease.Partition] = lease
+	return lease, nil
+}
+
+// Release releases the lease, keeping its continuation.
+func (s *MemoryLeaseStore) Release(ctx context.Context, lease Lease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.leases[lease.Partition]
+	if stored.Owner != lease.Owner {
+		return ErrLeaseLost
+	}
+	stored.Owner = ""
+	stored.ExpiresAt = time.Time{}
+	s.leases[lease.Partition] = stored
+	return nil
+}
@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// cosmosAPIVersion is the version of the Cosmos DB REST API the change
+	// feed is read with.
+	cosmosAPIVersion = "2018-12-31"
+	// defaultChangeFeedPageSize is the default maximum number of changes
+	// read at once.
+	defaultChangeFeedPageSize = 100
+	// defaultChangeFeedTimeout is the default timeout of a change feed request.
+	defaultChangeFeedTimeout = 30 * time.Second
+)
+
+// CosmosChangeFeed reads the change feed of a Cosmos DB container with the
+// REST API, since the SDK does not support it. The partitions of the feed
+// are the partition key ranges of the container, and the continuations are
+// the ETags of the responses. ContinuationNow is sent as "If-None-Match: *".
+type CosmosChangeFeed struct {
+	client       *http.Client
+	endpoint     string
+	key          []byte
+	resourceLink string
+	pageSize     int
+	now          func() time.Time
+}
+
+// CosmosChangeFeedOptions contains options for the CosmosChangeFeed.
+type CosmosChangeFeedOptions struct {
+	// Client sends the requests. Its timeout is the timeout of a request.
+	Client *http.Client
+	// PageSize is the maximum number of changes read at once.
+	PageSize int
+}
+
+// CosmosChangeFeedOption is a function that sets options on the
+// CosmosChangeFeed.
+type CosmosChangeFeedOption func(o *CosmosChangeFeedOptions)
+
+// NewCosmosChangeFeed returns a new CosmosChangeFeed of the container. The
+// connection string must contain the account endpoint and key.
+func NewCosmosChangeFeed(connectionString, databaseID, containerID string, options ...CosmosChangeFeedOption) (*CosmosChangeFeed, error) {
+	opts := CosmosChangeFeedOptions{
+		Client:   &http.Client{Timeout: defaultChangeFeedTimeout},
+		PageSize: defaultChangeFeedPageSize,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	var endpoint, accountKey string
+	for _, part := range strings.Split(connectionString, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch {
+		case strings.EqualFold(key, "AccountEndpoint"):
+			endpoint = strings.TrimSuffix(value, "/")
+		case strings.EqualFold(key, "AccountKey"):
+			accountKey = value
+		}
+	}
+	if len(endpoint) == 0 || len(accountKey) == 0 {
+		return nil, errors.New("connection string must contain AccountEndpoint and AccountKey")
+	}
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("decoding account key: %w", err)
+	}
+
+	return &CosmosChangeFeed{
+		client:       opts.Client,
+		endpoint:     endpoint,
+		key:          key,
+		resourceLink: "dbs/" + databaseID + "/colls/" + containerID,
+		pageSize:     opts.PageSize,
+		now:          time.Now,
+	}, nil
+}
+
+// Partitions returns the IDs of the partition key ranges of the container.
+func (f *CosmosChangeFeed) Partitions(ctx context.Context) ([]string, error) {
+	resp, err := f.do(ctx, "pkranges", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var body struct {
+		PartitionKeyRanges []struct {
+			ID string `json:"id"`
+		} `json:"PartitionKeyRanges"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInternalDB, err)
+	}
+
+	partitions := make([]string, len(body.PartitionKeyRanges))
+	for i, r := range body.PartitionKeyRanges {
+		partitions[i] = r.ID
+	}
+	return partitions, nil
+}
+
+// ReadChanges returns the changes of the partition key range after the
+// continuation.
+func (f *CosmosChangeFeed) ReadChanges(ctx context.Context, partition, continuation string) (ChangeFeedPage, error) {
+	header := http.Header{}
+	header.Set("A-IM", "Incremental feed")
+	header.Set("x-ms-documentdb-partitionkeyrangeid", partition)
+	header.Set("x-ms-max-item-count", strconv.Itoa(f.pageSize))
+	if len(continuation) > 0 {
+		header.Set("If-None-Match", continuation)
+	}
+
+	resp, err := f.do(ctx, "docs", header)
+	if err != nil {
+		return ChangeFeedPage{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if etag := resp.Header.Get("etag"); len(etag) > 0 {
+			continuation = etag
+		}
+		return ChangeFeedPage{Continuation: continuation}, nil
+	case http.StatusGone:
+		return ChangeFeedPage{}, fmt.Errorf("%w: %s", ErrPartitionGone, partition)
+	default:
+		return ChangeFeedPage{}, responseError(resp)
+	}
+
+	var body struct {
+		Documents []Note `json:"Documents"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return ChangeFeedPage{}, fmt.Errorf("%w: %w", ErrInternalDB, err)
+	}
+	return ChangeFeedPage{
+		Notes:        body.Documents,
+		Continuation: resp.Header.Get("etag"),
+	}, nil
+}
+
+// do sends a GET request for the resources of the type of the container,
+// signed with the account key.
+func (f *CosmosChangeFeed) do(ctx context.Context, resourceType string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.endpoint+"/"+f.resourceLink+"/"+resourceType, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	date := f.now().UTC().Format(http.TimeFormat)
+	req.Header.Set("x-ms-date", date)
+	req.Header.Set("x-ms-version", cosmosAPIVersion)
+	req.Header.Set("Authorization", f.authorization(http.MethodGet, resourceType, date))
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return resp, nil
+}
+
+// authorization returns the master key token of a request.
+// See https://learn.microsoft.com/rest/api/cosmos-db/access-control-on-cosmosdb-resources.
+func (f *CosmosChangeFeed) authorization(method, resourceType, date string) string {
+	payload := strings.ToLower(method) + "\n" + resourceType + "\n" + f.resourceLink + "\n" + strings.ToLower(date) + "\n\n"
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(payload))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return url.QueryEscape("type=master&ver=1.0&sig=" + signature)
+}
+
+// responseError returns the error of an unexpected response.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	err := fmt.Errorf("unexpected status: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return fmt.Errorf("%w: %w", ErrInternalDB, err)
+}
@@ -0,0 +1,301 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ChangeFeedProcessor(t *testing.T) {
+	// Arrange
+	feed := newMemoryChangeFeed(2)
+	feed.Append("0", Note{ID: "1", Category: "work"}, Note{ID: "2", Category: "work"}, Note{ID: "3", Category: "work"})
+	feed.Append("1", Note{ID: "4", Category: "home"})
+	leases := NewMemoryLeaseStore()
+
+	processor, err := NewChangeFeedProcessor(feed, leases, func(o *ChangeFeedProcessorOptions) {
+		o.Logger = discardLogger{}
+		o.Owner = "processor"
+		o.PollInterval = time.Millisecond
+	})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	handled := make(map[string][]string)
+	failed := false
+	processor.Handle("collect", ChangeHandlerFunc(func(ctx context.Context, partition string, notes []Note) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// The first page of the partition fails once, and is read again.
+		if partition == "0" && !failed {
+			failed = true
+			return errors.New("handler failed")
+		}
+		for _, note := range notes {
+			handled[partition] = append(handled[partition], note.ID)
+		}
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		processor.Run(ctx)
+	}()
+
+	// Act
+	wait := func(want map[string][]string) {
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			for partition, ids := range want {
+				if len(handled[partition]) != len(ids) {
+					return false
+				}
+			}
+			return true
+		}, time.Second, time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, want, handled)
+	}
+	wait(map[string][]string{"0": {"1", "2", "3"}, "1": {"4"}})
+
+	// New changes and new partitions are picked up.
+	feed.Append("1", Note{ID: "5", Category: "home"})
+	feed.Append("2", Note{ID: "6", Category: "other"})
+	wait(map[string][]string{"0": {"1", "2", "3"}, "1": {"4", "5"}, "2": {"6"}})
+
+	cancel()
+	<-done
+
+	// Assert
+	for partition, want := range map[string]string{"0": "3", "1": "2", "2": "1"} {
+		lease := leases.leases[partition]
+		require.Equal(t, want, lease.Continuation, "partition %s", partition)
+		require.Empty(t, lease.Owner, "partition %s", partition)
+	}
+}
+
+func Test_ChangeFeedProcessor_leaseTaken(t *testing.T) {
+	feed := newMemoryChangeFeed(0)
+	feed.Append("0", Note{ID: "1"})
+	leases := NewMemoryLeaseStore()
+	_, err := leases.Acquire(context.Background(), "0", "other", time.Hour)
+	require.NoError(t, err)
+
+	processor, err := NewChangeFeedProcessor(feed, leases, func(o *ChangeFeedProcessorOptions) {
+		o.Logger = discardLogger{}
+		o.PollInterval = time.Millisecond
+	})
+	require.NoError(t, err)
+	processor.Handle("fail", ChangeHandlerFunc(func(ctx context.Context, partition string, notes []Note) error {
+		t.Error("the partition of another owner is processed")
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	processor.Run(ctx)
+}
+
+func Test_MemoryLeaseStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryLeaseStore()
+	store.now = func() time.Time { return now }
+
+	lease, err := store.Acquire(ctx, "0", "a", time.Minute)
+	require.NoError(t, err)
+	_, err = store.Acquire(ctx, "0", "b", time.Minute)
+	require.ErrorIs(t, err, ErrLeaseTaken)
+
+	lease.Continuation = "10"
+	lease, err = store.Checkpoint(ctx, lease, time.Minute)
+	require.NoError(t, err)
+
+	// The expired lease is taken over with its continuation.
+	now = now.Add(2 * time.Minute)
+	taken, err := store.Acquire(ctx, "0", "b", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, "10", taken.Continuation)
+
+	_, err = store.Checkpoint(ctx, lease, time.Minute)
+	require.ErrorIs(t, err, ErrLeaseLost)
+	require.ErrorIs(t, store.Release(ctx, lease), ErrLeaseLost)
+	require.NoError(t, store.Release(ctx, taken))
+
+	_, err = store.Acquire(ctx, "0", "a", time.Minute)
+	require.NoError(t, err)
+}
+
+func Test_CosmosChangeFeed_ReadChanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		continuation string
+		status       int
+		body         string
+		want         ChangeFeedPage
+		wantErr      error
+	}{
+		{
+			name:   "ReadChanges() - changes from the beginning",
+			status: http.StatusOK,
+			body:   `{"_rid":"x","Documents":[{"id":"1","category":"work","note":"note","_lsn":5}],"_count":1}`,
+			want: ChangeFeedPage{
+				Notes:        []Note{{ID: "1", Category: "work", Note: "note"}},
+				Continuation: `"6"`,
+			},
+		},
+		{
+			name:         "ReadChanges() - no new changes",
+			continuation: `"6"`,
+			status:       http.StatusNotModified,
+			want:         ChangeFeedPage{Continuation: `"6"`},
+		},
+		{
+			name:         "ReadChanges() - partition is split",
+			continuation: `"6"`,
+			status:       http.StatusGone,
+			wantErr:      ErrPartitionGone,
+		},
+		{
+			name:    "ReadChanges() - throttled",
+			status:  http.StatusTooManyRequests,
+			wantErr: ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req = r
+				w.Header().Set("etag", `"6"`)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			feed, err := NewCosmosChangeFeed("AccountEndpoint="+ts.URL+"/;AccountKey=c2VjcmV0;", "NotesDB", "notes")
+			require.NoError(t, err)
+			feed.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+			got, err := feed.ReadChanges(context.Background(), "0", tt.continuation)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.want, got)
+
+			require.Equal(t, "/dbs/NotesDB/colls/notes/docs", req.URL.Path)
+			require.Equal(t, "Incremental feed", req.Header.Get("A-IM"))
+			require.Equal(t, "0", req.Header.Get("x-ms-documentdb-partitionkeyrangeid"))
+			require.Equal(t, tt.continuation, req.Header.Get("If-None-Match"))
+			require.Equal(t, "Tue, 02 Jan 2024 03:04:05 GMT", req.Header.Get("x-ms-date"))
+			require.Equal(t, "type%3Dmaster%26ver%3D1.0%26sig%3Dgl7OXXcR%2FWiLEH6%2FKPcsiY8guLU92jLW1t3PntDtojU%3D", req.Header.Get("Authorization"))
+		})
+	}
+}
+
+func Test_CosmosChangeFeed_Partitions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/dbs/NotesDB/colls/notes/pkranges", r.URL.Path)
+		w.Write([]byte(`{"PartitionKeyRanges":[{"id":"0","minInclusive":"","maxExclusive":"80"},{"id":"1","minInclusive":"80","maxExclusive":"FF"}]}`))
+	}))
+	defer ts.Close()
+
+	feed, err := NewCosmosChangeFeed("AccountEndpoint="+ts.URL+";AccountKey=c2VjcmV0", "NotesDB", "notes")
+	require.NoError(t, err)
+
+	got, err := feed.Partitions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"0", "1"}, got)
+}
+
+// discardLogger discards the logged messages, and is safe for concurrent use.
+type discardLogger struct{}
+
+func (discardLogger) InfoContext(ctx context.Context, msg string, args ...any)  {}
+func (discardLogger) ErrorContext(ctx context.Context, msg string, args ...any) {}
+
+// memoryChangeFeed is a ChangeFeed of notes appended in memory, to test
+// the processor without Cosmos DB. Its continuations are the number of
+// changes read from a partition.
+type memoryChangeFeed struct {
+	mu         sync.Mutex
+	partitions map[string][]Note
+	pageSize   int
+}
+
+// newMemoryChangeFeed returns a new memoryChangeFeed that returns at most
+// pageSize changes per read, or all changes if pageSize is zero.
+func newMemoryChangeFeed(pageSize int) *memoryChangeFeed {
+	return &memoryChangeFeed{
+		partitions: make(map[string][]Note),
+		pageSize:   pageSize,
+	}
+}
+
+// Append appends the changes to the partition, creating it if it does not
+// exist.
+func (f *memoryChangeFeed) Append(partition string, notes ...Note) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.partitions[partition] = append(f.partitions[partition], notes...)
+}
+
+// Remove removes the partition, so that its reads fail with
+// ErrPartitionGone.
+func (f *memoryChangeFeed) Remove(partition string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.partitions, partition)
+}
+
+// Partitions returns the partitions of the feed, sorted.
+func (f *memoryChangeFeed) Partitions(ctx context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	partitions := make([]string, 0, len(f.partitions))
+	for partition := range f.partitions {
+		partitions = append(partitions, partition)
+	}
+	slices.Sort(partitions)
+	return partitions, nil
+}
+
+// ReadChanges returns the changes of the partition after the continuation.
+func (f *memoryChangeFeed) ReadChanges(ctx context.Context, partition, continuation string) (ChangeFeedPage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	notes, ok := f.partitions[partition]
+	if !ok {
+		return ChangeFeedPage{}, ErrPartitionGone
+	}
+
+	var offset int
+	if continuation == ContinuationNow {
+		offset = len(notes)
+	} else if len(continuation) > 0 {
+		if _, err := fmt.Sscan(continuation, &offset); err != nil || offset < 0 || offset > len(notes) {
+			return ChangeFeedPage{}, fmt.Errorf("%w: continuation %q", ErrInvalidInput, continuation)
+		}
+	}
+	end := len(notes)
+	if f.pageSize > 0 {
+		end = min(end, offset+f.pageSize)
+	}
+	return ChangeFeedPage{
+		Notes:        slices.Clone(notes[offset:end]),
+		Continuation: fmt.Sprint(end),
+	}, nil
+}
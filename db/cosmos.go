@@ -3,26 +3,85 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	// headerRequestCharge is the header Cosmos DB reports the request charge in.
+	headerRequestCharge = "x-ms-request-charge"
+)
+
+// tracer creates the spans of the Cosmos DB calls.
+var tracer = otel.Tracer("github.com/KatrinSalt/notes-service/db")
+
 type client interface {
 	CreateItem(ctx context.Context, partitionKey string, item []byte) ([]byte, error)
 	ReplaceItem(ctx context.Context, partitionKey string, id string, item []byte) ([]byte, error)
 	DeleteItem(ctx context.Context, partitionKey string, id string) error
 	ReadItem(ctx context.Context, partitionKey string, id string) ([]byte, error)
 	ListItems(ctx context.Context, partitionKey string) ([][]byte, error)
+	QueryItems(ctx context.Context, query string, parameters ...azcosmos.QueryParameter) ([][]byte, error)
+	ExecuteBatch(ctx context.Context, partitionKey string, operations []BatchOperation) ([][]byte, error)
+}
+
+// BatchOperationType is the type of an operation of a transactional batch.
+type BatchOperationType int
+
+const (
+	// BatchCreate creates the item.
+	BatchCreate BatchOperationType = iota
+	// BatchReplace replaces the item with the ID.
+	BatchReplace
+	// BatchDelete deletes the item with the ID.
+	BatchDelete
+)
+
+// BatchOperation is an operation of a transactional batch.
+type BatchOperation struct {
+	Type BatchOperationType
+	ID   string
+	Item []byte
+	// IfMatch is the ETag the item of a replace or delete must have. It
+	// is not checked if it is empty.
+	IfMatch string
 }
 
 type CosmosContainerClient struct {
-	cl *azcosmos.ContainerClient
+	cl              *azcosmos.ContainerClient
+	databaseID      string
+	containerID     string
+	onRequestCharge func(operation string, charge float32)
+}
+
+// CosmosContainerClientOptions contains options for the CosmosContainerClient.
+type CosmosContainerClientOptions struct {
+	// OnRequestCharge is called with the request charge (RU) of every
+	// request made to Cosmos DB.
+	OnRequestCharge func(operation string, charge float32)
 }
 
-func NewCosmosContainerClient(connectionString, databaseID, containerID string) (*CosmosContainerClient, error) {
+// CosmosContainerClientOption is a function that sets options on the CosmosContainerClient.
+type CosmosContainerClientOption func(o *CosmosContainerClientOptions)
+
+func NewCosmosContainerClient(connectionString, databaseID, containerID string, options ...CosmosContainerClientOption) (*CosmosContainerClient, error) {
+	opts := CosmosContainerClientOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
 	client, err := azcosmos.NewClientFromConnectionString(connectionString, nil)
 	if err != nil {
 		return nil, err
@@ -39,14 +98,27 @@ func NewCosmosContainerClient(connectionString, databaseID, containerID string)
 	}
 
 	return &CosmosContainerClient{
-		cl: containerClient,
+		cl:              containerClient,
+		databaseID:      databaseID,
+		containerID:     containerID,
+		onRequestCharge: opts.OnRequestCharge,
 	}, nil
 }
 
+// Ping checks that the container can be reached.
+func (c *CosmosContainerClient) Ping(ctx context.Context) error {
+	if _, err := c.cl.Read(ctx, nil); err != nil {
+		return fmt.Errorf("%w: %w", ErrClientConnection, err)
+	}
+	return nil
+}
+
 func (c *CosmosContainerClient) CreateItem(ctx context.Context, partitionKey string, item []byte) ([]byte, error) {
+	ctx, span := c.startSpan(ctx, "CreateItem", partitionKey)
 	resp, err := c.cl.CreateItem(ctx, azcosmos.NewPartitionKeyString(partitionKey), item, &azcosmos.ItemOptions{
 		EnableContentResponseOnWrite: true,
 	})
+	c.endSpan(span, c.requestCharge("CreateItem", resp.RequestCharge, err), err)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +126,11 @@ func (c *CosmosContainerClient) CreateItem(ctx context.Context, partitionKey str
 }
 
 func (c *CosmosContainerClient) ReplaceItem(ctx context.Context, partitionKey string, id string, item []byte) ([]byte, error) {
+	ctx, span := c.startSpan(ctx, "ReplaceItem", partitionKey)
 	resp, err := c.cl.ReplaceItem(ctx, azcosmos.NewPartitionKeyString(partitionKey), id, item, &azcosmos.ItemOptions{
 		EnableContentResponseOnWrite: true,
 	})
+	c.endSpan(span, c.requestCharge("ReplaceItem", resp.RequestCharge, err), err)
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +138,11 @@ func (c *CosmosContainerClient) ReplaceItem(ctx context.Context, partitionKey st
 }
 
 func (c *CosmosContainerClient) DeleteItem(ctx context.Context, partitionKey string, id string) error {
-	_, err := c.cl.DeleteItem(ctx, azcosmos.NewPartitionKeyString(partitionKey), id, &azcosmos.ItemOptions{
+	ctx, span := c.startSpan(ctx, "DeleteItem", partitionKey)
+	resp, err := c.cl.DeleteItem(ctx, azcosmos.NewPartitionKeyString(partitionKey), id, &azcosmos.ItemOptions{
 		EnableContentResponseOnWrite: true,
 	})
+	c.endSpan(span, c.requestCharge("DeleteItem", resp.RequestCharge, err), err)
 	if err != nil {
 		return err
 	}
@@ -74,9 +150,11 @@ func (c *CosmosContainerClient) DeleteItem(ctx context.Context, partitionKey str
 }
 
 func (c *CosmosContainerClient) ReadItem(ctx context.Context, partitionKey string, id string) ([]byte, error) {
+	ctx, span := c.startSpan(ctx, "ReadItem", partitionKey)
 	resp, err := c.cl.ReadItem(ctx, azcosmos.NewPartitionKeyString(partitionKey), id, &azcosmos.ItemOptions{
 		EnableContentResponseOnWrite: true,
 	})
+	c.endSpan(span, c.requestCharge("ReadItem", resp.RequestCharge, err), err)
 	if err != nil {
 		return nil, err
 	}
@@ -84,30 +162,179 @@ func (c *CosmosContainerClient) ReadItem(ctx context.Context, partitionKey strin
 }
 
 func (c *CosmosContainerClient) ListItems(ctx context.Context, partitionKey string) ([][]byte, error) {
+	ctx, span := c.startSpan(ctx, "ListItems", partitionKey)
+	var charge float32
 	query := "SELECT * FROM c"
 	pager := c.cl.NewQueryItemsPager(query, azcosmos.NewPartitionKeyString(partitionKey), nil)
 	var items [][]byte
 	for pager.More() {
 		resp, err := pager.NextPage(ctx)
+		charge += c.requestCharge("ListItems", resp.RequestCharge, err)
 		if err != nil {
+			c.endSpan(span, charge, err)
 			return nil, err
 		}
 		items = append(items, resp.Items...)
 	}
+	c.endSpan(span, charge, nil)
 	return items, nil
 }
 
+// QueryItems runs the query across all partitions. Only queries that can
+// be served by the gateway are supported, i.e. no aggregates, DISTINCT,
+// ORDER BY or GROUP BY.
+func (c *CosmosContainerClient) QueryItems(ctx context.Context, query string, parameters ...azcosmos.QueryParameter) ([][]byte, error) {
+	ctx, span := c.startSpan(ctx, "QueryItems", "")
+	var charge float32
+	pager := c.cl.NewQueryItemsPager(query, azcosmos.NewPartitionKey(), &azcosmos.QueryOptions{
+		QueryParameters: parameters,
+	})
+	var items [][]byte
+	for pager.More() {
+		resp, err := pager.NextPage(ctx)
+		charge += c.requestCharge("QueryItems", resp.RequestCharge, err)
+		if err != nil {
+			c.endSpan(span, charge, err)
+			return nil, err
+		}
+		items = append(items, resp.Items...)
+	}
+	c.endSpan(span, charge, nil)
+	return items, nil
+}
+
+// ExecuteBatch executes the operations on the items of the partition as
+// a transactional batch: either all operations succeed or none is applied.
+// It returns the items of the operations, in order. If the batch fails,
+// the error has the status code of the operation that failed.
+func (c *CosmosContainerClient) ExecuteBatch(ctx context.Context, partitionKey string, operations []BatchOperation) ([][]byte, error) {
+	ctx, span := c.startSpan(ctx, "ExecuteBatch", partitionKey)
+	batch := c.cl.NewTransactionalBatch(azcosmos.NewPartitionKeyString(partitionKey))
+	for _, op := range operations {
+		var options *azcosmos.TransactionalBatchItemOptions
+		if len(op.IfMatch) > 0 {
+			etag := azcore.ETag(op.IfMatch)
+			options = &azcosmos.TransactionalBatchItemOptions{IfMatchETag: &etag}
+		}
+		switch op.Type {
+		case BatchCreate:
+			batch.CreateItem(op.Item, nil)
+		case BatchReplace:
+			batch.ReplaceItem(op.ID, op.Item, options)
+		case BatchDelete:
+			batch.DeleteItem(op.ID, options)
+		}
+	}
+	resp, err := c.cl.ExecuteTransactionalBatch(ctx, batch, &azcosmos.TransactionalBatchOptions{
+		EnableContentResponseOnWrite: true,
+	})
+	if err == nil && !resp.Success {
+		err = batchError(resp)
+	}
+	c.endSpan(span, c.requestCharge("ExecuteBatch", resp.RequestCharge, err), err)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([][]byte, len(resp.OperationResults))
+	for i, result := range resp.OperationResults {
+		items[i] = result.ResourceBody
+	}
+	return items, nil
+}
+
+// batchError returns the error of a failed transactional batch, with the
+// status code of the operation that caused the failure.
+func batchError(resp azcosmos.TransactionalBatchResponse) error {
+	for i, result := range resp.OperationResults {
+		if result.StatusCode != http.StatusFailedDependency {
+			return &azcore.ResponseError{
+				StatusCode:  int(result.StatusCode),
+				ErrorCode:   fmt.Sprintf("operation %d of the transactional batch failed", i),
+				RawResponse: resp.RawResponse,
+			}
+		}
+	}
+	return &azcore.ResponseError{
+		StatusCode:  http.StatusInternalServerError,
+		ErrorCode:   "transactional batch failed",
+		RawResponse: resp.RawResponse,
+	}
+}
+
+// requestCharge reports and returns the request charge of a request.
+// The charge of a failed request is read from the headers of the error
+// response.
+func (c *CosmosContainerClient) requestCharge(operation string, charge float32, err error) float32 {
+	if err != nil {
+		var responseError *azcore.ResponseError
+		if !errors.As(err, &responseError) || responseError.RawResponse == nil {
+			return 0
+		}
+		f, perr := strconv.ParseFloat(responseError.RawResponse.Header.Get(headerRequestCharge), 32)
+		if perr != nil {
+			return 0
+		}
+		charge = float32(f)
+	}
+	if c.onRequestCharge != nil {
+		c.onRequestCharge(operation, charge)
+	}
+	return charge
+}
+
+// startSpan starts a client span for a Cosmos DB operation.
+func (c *CosmosContainerClient) startSpan(ctx context.Context, operation, partitionKey string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "cosmos."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "cosmosdb"),
+			attribute.String("db.namespace", c.databaseID),
+			attribute.String("db.collection.name", c.containerID),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.cosmosdb.partition_key", partitionKey),
+		),
+	)
+}
+
+// endSpan records the request charge and error of the operation and ends the span.
+func (c *CosmosContainerClient) endSpan(span trace.Span, charge float32, err error) {
+	span.SetAttributes(attribute.Float64("db.cosmosdb.request_charge", float64(charge)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 type NotesDB struct {
-	cl client
+	cl     client
+	outbox bool
+}
+
+// NotesDBOptions contains options for the NotesDB.
+type NotesDBOptions struct {
+	// Outbox writes an outbox record with every change of a note, in the
+	// same transactional batch.
+	Outbox bool
 }
 
-func NewNotesDB(client client) (*NotesDB, error) {
+// NotesDBOption is a function that sets options on the NotesDB.
+type NotesDBOption func(o *NotesDBOptions)
+
+func NewNotesDB(client client, options ...NotesDBOption) (*NotesDB, error) {
 	if client == nil {
 		return nil, ErrClientRequired
 	}
 
+	opts := NotesDBOptions{}
+	for _, option := range options {
+		option(&opts)
+	}
+
 	return &NotesDB{
-		cl: client,
+		cl:     client,
+		outbox: opts.Outbox,
 	}, nil
 }
 
@@ -134,9 +361,17 @@ func (c *NotesDB) CreateNote(ctx context.Context, note Note) (Note, error) {
 	}
 
 	// Q: would it a better practice to write a custom error message here, i.e. "Failed to create a note in CosmosDB"?
-	resp, err := c.cl.CreateItem(ctx, note.Category, bytes)
+	var resp []byte
+	if c.outbox {
+		resp, err = c.writeWithOutbox(ctx, BatchOperation{Type: BatchCreate, ID: note.ID, Item: bytes}, OutboxEventCreated, note)
+	} else {
+		resp, err = c.cl.CreateItem(ctx, note.Category, bytes)
+		if err != nil {
+			err = checkError(err)
+		}
+	}
 	if err != nil {
-		return Note{}, checkError(err)
+		return Note{}, err
 	}
 
 	var noteDB Note
@@ -146,16 +381,34 @@ func (c *NotesDB) CreateNote(ctx context.Context, note Note) (Note, error) {
 	return noteDB, nil
 }
 
+// UpdateNote replaces the note. The creation time of the note is kept if
+// the note does not have one.
 func (c *NotesDB) UpdateNote(ctx context.Context, note Note) (Note, error) {
+	if note.CreatedAt.IsZero() {
+		existing, err := c.GetNoteByID(ctx, note.Category, note.ID)
+		if err != nil {
+			return Note{}, err
+		}
+		note.CreatedAt = existing.CreatedAt
+	}
+
 	bytes, err := json.Marshal(&note)
 	if err != nil {
 		return Note{}, err
 	}
 
 	// Q: would it a better practice to write a custom error message here, i.e. "Failed to update a note in CosmosDB"?
-	resp, err := c.cl.ReplaceItem(ctx, note.Category, note.ID, bytes)
+	var resp []byte
+	if c.outbox {
+		resp, err = c.writeWithOutbox(ctx, BatchOperation{Type: BatchReplace, ID: note.ID, Item: bytes}, OutboxEventUpdated, note)
+	} else {
+		resp, err = c.cl.ReplaceItem(ctx, note.Category, note.ID, bytes)
+		if err != nil {
+			err = checkError(err)
+		}
+	}
 	if err != nil {
-		return Note{}, checkError(err)
+		return Note{}, err
 	}
 
 	var noteDB Note
@@ -166,6 +419,10 @@ func (c *NotesDB) UpdateNote(ctx context.Context, note Note) (Note, error) {
 }
 
 func (c *NotesDB) DeleteNote(ctx context.Context, id, category string) error {
+	if c.outbox {
+		_, err := c.writeWithOutbox(ctx, BatchOperation{Type: BatchDelete, ID: id}, OutboxEventDeleted, Note{ID: id, Category: category})
+		return err
+	}
 	err := c.cl.DeleteItem(ctx, category, id)
 	if err != nil {
 		return checkError(err)
@@ -180,6 +437,9 @@ func (c *NotesDB) GetNotesByCategory(ctx context.Context, category string) ([]No
 		return []Note{}, checkError(err)
 	}
 	for _, item := range respItems {
+		if isOutboxRecord(item) {
+			continue
+		}
 		var note Note
 		if err = json.Unmarshal(item, &note); err != nil {
 			return []Note{}, err
@@ -189,6 +449,50 @@ func (c *NotesDB) GetNotesByCategory(ctx context.Context, category string) ([]No
 	return notes, nil
 }
 
+// GetNotesByCategories returns the notes of the categories with a single
+// query across the partitions.
+func (c *NotesDB) GetNotesByCategories(ctx context.Context, categories []string) ([]Note, error) {
+	respItems, err := c.cl.QueryItems(ctx, "SELECT * FROM c WHERE ARRAY_CONTAINS(@categories, c.category) AND NOT IS_DEFINED(c.type)",
+		azcosmos.QueryParameter{Name: "@categories", Value: categories})
+	if err != nil {
+		return []Note{}, checkError(err)
+	}
+	notes := make([]Note, 0, len(respItems))
+	for _, item := range respItems {
+		var note Note
+		if err := json.Unmarshal(item, &note); err != nil {
+			return []Note{}, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+// ListCategories returns the categories that have notes, sorted by name.
+// The gateway does not run DISTINCT across partitions, so the category of
+// every note is read and deduplicated here. Wrap the database in a Cache
+// to avoid the scan on every call.
+func (c *NotesDB) ListCategories(ctx context.Context) ([]string, error) {
+	respItems, err := c.cl.QueryItems(ctx, "SELECT VALUE c.category FROM c WHERE NOT IS_DEFINED(c.type)")
+	if err != nil {
+		return []string{}, checkError(err)
+	}
+	seen := make(map[string]bool)
+	categories := []string{}
+	for _, item := range respItems {
+		var category string
+		if err := json.Unmarshal(item, &category); err != nil {
+			return []string{}, err
+		}
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	slices.Sort(categories)
+	return categories, nil
+}
+
 func (c *NotesDB) GetNoteByID(ctx context.Context, category, id string) (Note, error) {
 	// read the item from the container
 	response, err := c.cl.ReadItem(ctx, category, id)
@@ -196,6 +500,9 @@ func (c *NotesDB) GetNoteByID(ctx context.Context, category, id string) (Note, e
 	if err != nil {
 		return Note{}, checkError(err)
 	}
+	if isOutboxRecord(response) {
+		return Note{}, ErrNotFound
+	}
 
 	var note Note
 	if err = json.Unmarshal(response, &note); err != nil {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -149,6 +150,21 @@ func Test_UpdateNote(t *testing.T) {
 			expectError:   false,
 			expectedError: nil,
 		},
+		{
+			name: "UpdateNote() - keeps the creation time",
+			inputNote: Note{
+				ID:       mockID,
+				Category: "category",
+				Note:     "updated note",
+			},
+			mockResponse: []byte("{\"id\":\"" + mockID + "\",\"category\":\"category\",\"note\":\"updated note\",\"timestamp\":\"" + mockCreatedAt.Format(time.RFC3339Nano) + "\"}"),
+			expectedNote: Note{
+				ID:        mockID,
+				Category:  "category",
+				Note:      "updated note",
+				CreatedAt: mockCreatedAt,
+			},
+		},
 		{
 			name: "UpdateNote() - internal db error",
 			inputNote: Note{
@@ -563,6 +579,154 @@ func Test_GetNoteByID(t *testing.T) {
 	}
 }
 
+func Test_GetNotesByCategories(t *testing.T) {
+	tests := []struct {
+		name          string
+		mockResponse  [][]byte
+		mockError     error
+		expectedNotes []Note
+		expectError   bool
+		expectedError error
+	}{
+		{
+			name: "GetNotesByCategories() - successful execution",
+			mockResponse: [][]byte{
+				[]byte(`{"id":"1","category":"work","note":"note 1"}`),
+				[]byte(`{"id":"2","category":"personal","note":"note 2"}`),
+			},
+			mockError: nil,
+			expectedNotes: []Note{
+				{ID: "1", Category: "work", Note: "note 1"},
+				{ID: "2", Category: "personal", Note: "note 2"},
+			},
+			expectError:   false,
+			expectedError: nil,
+		},
+		{
+			name:          "GetNotesByCategories() - internal db error",
+			mockResponse:  nil,
+			mockError:     assert.AnError,
+			expectedNotes: []Note{},
+			expectError:   true,
+			expectedError: fmt.Errorf("%w: %w", ErrInternalDB, assert.AnError),
+		},
+		{
+			name:          "GetNotesByCategories() - error on non-json response",
+			mockResponse:  [][]byte{[]byte(`notajson`)},
+			mockError:     nil,
+			expectedNotes: []Note{},
+			expectError:   true,
+			expectedError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockClient := mockCosmosContainerClient{
+				t:         t,
+				responses: tt.mockResponse,
+				err:       tt.mockError,
+			}
+
+			cosmosDB, err := NewNotesDB(
+				&mockClient,
+			)
+			assert.NoError(t, err)
+
+			// Act
+			notes, err := cosmosDB.GetNotesByCategories(context.Background(), []string{"work", "personal"})
+
+			// Assert
+			require.True(t, mockClient.funcCalled)
+			require.Equal(t, tt.expectedNotes, notes)
+			if tt.expectError {
+				require.Error(t, err)
+				if tt.expectedError != nil {
+					require.Equal(t, tt.expectedError, err)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_ListCategories(t *testing.T) {
+	tests := []struct {
+		name               string
+		mockResponse       [][]byte
+		mockError          error
+		expectedCategories []string
+		expectError        bool
+		expectedError      error
+	}{
+		{
+			name:               "ListCategories() - successful execution",
+			mockResponse:       [][]byte{[]byte(`"work"`), []byte(`"personal"`), []byte(`"work"`)},
+			mockError:          nil,
+			expectedCategories: []string{"personal", "work"},
+			expectError:        false,
+			expectedError:      nil,
+		},
+		{
+			name:               "ListCategories() - no notes",
+			mockResponse:       [][]byte{},
+			mockError:          nil,
+			expectedCategories: []string{},
+			expectError:        false,
+			expectedError:      nil,
+		},
+		{
+			name:               "ListCategories() - internal db error",
+			mockResponse:       nil,
+			mockError:          assert.AnError,
+			expectedCategories: []string{},
+			expectError:        true,
+			expectedError:      fmt.Errorf("%w: %w", ErrInternalDB, assert.AnError),
+		},
+		{
+			name:               "ListCategories() - error on non-json response",
+			mockResponse:       [][]byte{[]byte(`notajson`)},
+			mockError:          nil,
+			expectedCategories: []string{},
+			expectError:        true,
+			expectedError:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockClient := mockCosmosContainerClient{
+				t:         t,
+				responses: tt.mockResponse,
+				err:       tt.mockError,
+			}
+
+			cosmosDB, err := NewNotesDB(
+				&mockClient,
+			)
+			assert.NoError(t, err)
+
+			// Act
+			categories, err := cosmosDB.ListCategories(context.Background())
+
+			// Assert
+			require.True(t, mockClient.funcCalled)
+			require.Equal(t, tt.expectedCategories, categories)
+			if tt.expectError {
+				require.Error(t, err)
+				if tt.expectedError != nil {
+					require.Equal(t, tt.expectedError, err)
+				}
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 type mockInput struct {
 	ctx          context.Context
 	partitionKey string
@@ -577,6 +741,8 @@ type mockCosmosContainerClient struct {
 	responses  [][]byte
 	err        error
 	funcCalled bool
+	// operations are the operations of the executed batch.
+	operations []BatchOperation
 }
 
 func (m *mockCosmosContainerClient) CreateItem(ctx context.Context, partitionKey string, item []byte) ([]byte, error) {
@@ -627,3 +793,19 @@ func (m *mockCosmosContainerClient) ListItems(ctx context.Context, partitionKey
 
 	return m.responses, m.err
 }
+
+func (m *mockCosmosContainerClient) QueryItems(ctx context.Context, query string, parameters ...azcosmos.QueryParameter) ([][]byte, error) {
+	m.funcCalled = true
+
+	return m.responses, m.err
+}
+
+func (m *mockCosmosContainerClient) ExecuteBatch(ctx context.Context, partitionKey string, operations []BatchOperation) ([][]byte, error) {
+	m.funcCalled = true
+	m.operations = operations
+
+	require.Equal(m.t, m.input.ctx, ctx)
+	require.Equal(m.t, m.input.partitionKey, partitionKey)
+
+	return m.responses, m.err
+}
@@ -20,10 +20,19 @@ var (
 	ErrLoggerRequired = errors.New("logger is not provided")
 	// ErrLoggerEmpty is returned when the logger instance is not provided.
 	ErrClientRequired = errors.New("database client is not provided")
+	// ErrDatabaseRequired is returned when the wrapped database is not provided.
+	ErrDatabaseRequired = errors.New("database is not provided")
+	// ErrObserverRequired is returned when the observer is not provided.
+	ErrObserverRequired = errors.New("observer is not provided")
 )
 
 var (
 	ErrClientConnection = errors.New("connection to the database failed")
+	// ErrUnavailable is returned when the database is temporarily unavailable.
+	ErrUnavailable = errors.New("database is unavailable")
+	// ErrCircuitOpen is returned when calls fail fast because the circuit
+	// breaker is open.
+	ErrCircuitOpen = fmt.Errorf("%w: circuit breaker is open", ErrUnavailable)
 )
 
 // Generic error for the DB layer.
@@ -40,11 +49,17 @@ var (
 	ErrAlreadyExists = errors.New("already exists")
 	// ErrInvalidID is returned when the ID is invalid.
 	ErrInvalidID = errors.New("invalid ID")
+	// ErrPreconditionFailed is returned when a conditional write fails,
+	// because the item was changed since it was read.
+	ErrPreconditionFailed = errors.New("precondition failed")
 )
 
 // checkError checks and returns the appropriate error.
 func checkError(err error) error {
 	if err != nil {
+		if errors.Is(err, ErrUnavailable) {
+			return err
+		}
 		var responseError *azcore.ResponseError
 		if errors.As(err, &responseError) {
 			switch responseError.StatusCode {
@@ -54,6 +69,12 @@ func checkError(err error) error {
 				return ErrNotFound
 			case http.StatusConflict:
 				return ErrAlreadyExists
+			case http.StatusPreconditionFailed:
+				return ErrPreconditionFailed
+			case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusRequestTimeout:
+				// The database is throttling or recovering, and the request
+				// can be retried later.
+				return fmt.Errorf("%w: %w", ErrUnavailable, err)
 			default:
 				return fmt.Errorf("%w: %w", ErrInternalDB, err)
 			}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "checkError() - not found",
+			err:  newResponseError(http.StatusNotFound, ""),
+			want: ErrNotFound,
+		},
+		{
+			name: "checkError() - throttled",
+			err:  newResponseError(http.StatusTooManyRequests, ""),
+			want: ErrUnavailable,
+		},
+		{
+			name: "checkError() - service unavailable",
+			err:  newResponseError(http.StatusServiceUnavailable, ""),
+			want: ErrUnavailable,
+		},
+		{
+			name: "checkError() - request timeout",
+			err:  newResponseError(http.StatusRequestTimeout, ""),
+			want: ErrUnavailable,
+		},
+		{
+			name: "checkError() - retries exhausted",
+			err:  fmt.Errorf("%w: %w", ErrUnavailable, context.DeadlineExceeded),
+			want: ErrUnavailable,
+		},
+		{
+			name: "checkError() - server error",
+			err:  newResponseError(http.StatusInternalServerError, ""),
+			want: ErrInternalDB,
+		},
+		{
+			name: "checkError() - other error",
+			err:  assert.AnError,
+			want: ErrInternalDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkError(tt.err)
+			require.ErrorIs(t, got, tt.want)
+			if errors.Is(tt.want, ErrUnavailable) {
+				require.NotErrorIs(t, got, ErrInternalDB)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/KatrinSalt/notes-service/markdown"
+)
+
+const (
+	// lockFileName is the name of the lock file in the directory of a
+	// FilesystemDB.
+	lockFileName = ".lock"
+	// lockRetryInterval is how often a lock held by another process is
+	// tried again.
+	lockRetryInterval = 10 * time.Millisecond
+)
+
+var (
+	// ErrDirRequired is returned when the directory is not provided.
+	ErrDirRequired = errors.New("directory is not provided")
+)
+
+// FilesystemDB stores the notes in a directory, with a directory per
+// category and a Markdown file with a front-matter per note. The name of
+// a note file is its ID, so that the files can be edited by hand and
+// tracked with git. The ID and category in the front-matter are ignored
+// when a note is read, the path of the file is used instead.
+//
+// Writes replace files atomically with a temporary file that is renamed.
+// Writes hold an exclusive lock on a lock file in the directory and reads
+// hold a shared lock, so that several processes can serve the same
+// directory.
+type FilesystemDB struct {
+	dir string
+	// mu serializes the requests of the process. The lock file only
+	// serializes the requests of different processes.
+	mu sync.RWMutex
+}
+
+// NewFilesystemDB returns a new FilesystemDB storing the notes in the
+// directory. The directory is created if it does not exist.
+func NewFilesystemDB(dir string) (*FilesystemDB, error) {
+	if len(dir) == 0 {
+		return nil, ErrDirRequired
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FilesystemDB{dir: dir}, nil
+}
+
+// Ping checks that the directory can be read.
+func (d *FilesystemDB) Ping(ctx context.Context) error {
+	if _, err := os.ReadDir(d.dir); err != nil {
+		return fmt.Errorf("%w: %w", ErrClientConnection, err)
+	}
+	return nil
+}
+
+func (d *FilesystemDB) CreateNote(ctx context.Context, note Note) (Note, error) {
+	if len(note.ID) == 0 {
+		note.ID = newUUID()
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now().UTC()
+	}
+	if err := validateNotePath(note.Category, note.ID); err != nil {
+		return Note{}, err
+	}
+
+	unlock, err := d.lock(ctx, true)
+	if err != nil {
+		return Note{}, err
+	}
+	defer unlock()
+
+	path := d.notePath(note.Category, note.ID)
+	if _, err := os.Stat(path); err == nil {
+		return Note{}, ErrAlreadyExists
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return Note{}, fsError(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return Note{}, fsError(err)
+	}
+	if err := writeNoteFile(path, note); err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+// UpdateNote replaces the note. The creation time of the note is kept if
+// the note does not have one.
+func (d *FilesystemDB) UpdateNote(ctx context.Context, note Note) (Note, error) {
+	if err := validateNotePath(note.Category, note.ID); err != nil {
+		return Note{}, err
+	}
+
+	unlock, err := d.lock(ctx, true)
+	if err != nil {
+		return Note{}, err
+	}
+	defer unlock()
+
+	path := d.notePath(note.Category, note.ID)
+	existing, err := readNoteFile(path, note.Category)
+	if err != nil {
+		return Note{}, err
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = existing.CreatedAt
+	}
+	if err := writeNoteFile(path, note); err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+// DeleteNote deletes the note, and the directory of its category if it
+// is empty afterwards.
+func (d *FilesystemDB) DeleteNote(ctx context.Context, id, category string) error {
+	if err := validateNotePath(category, id); err != nil {
+		return err
+	}
+
+	unlock, err := d.lock(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(d.notePath(category, id)); err != nil {
+		return fsError(err)
+	}
+	// The directory is only removed if it is empty.
+	_ = os.Remove(filepath.Join(d.dir, category))
+	return nil
+}
+
+func (d *FilesystemDB) GetNotesByCategory(ctx context.Context, category string) ([]Note, error) {
+	if err := validateName(category); err != nil {
+		return []Note{}, fmt.Errorf("%w: category: %w", ErrInvalidInput, err)
+	}
+
+	unlock, err := d.lock(ctx, false)
+	if err != nil {
+		return []Note{}, err
+	}
+	defer unlock()
+
+	return d.readCategory(category)
+}
+
+func (d *FilesystemDB) GetNotesByCategories(ctx context.Context, categories []string) ([]Note, error) {
+	for _, category := range categories {
+		if err := validateName(category); err != nil {
+			return []Note{}, fmt.Errorf("%w: category: %w", ErrInvalidInput, err)
+		}
+	}
+
+	unlock, err := d.lock(ctx, false)
+	if err != nil {
+		return []Note{}, err
+	}
+	defer unlock()
+
+	notes := []Note{}
+	for _, category := range categories {
+		categoryNotes, err := d.readCategory(category)
+		if err != nil {
+			return []Note{}, err
+		}
+		notes = append(notes, categoryNotes...)
+	}
+	return notes, nil
+}
+
+// ListCategories returns the categories that have notes, sorted by name.
+// Directories without note files are not categories.
+func (d *FilesystemDB) ListCategories(ctx context.Context) ([]string, error) {
+	unlock, err := d.lock(ctx, false)
+	if err != nil {
+		return []string{}, err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return []string{}, fsError(err)
+	}
+	categories := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || validateName(entry.Name()) != nil {
+			continue
+		}
+		names, err := noteFileNames(filepath.Join(d.dir, entry.Name()))
+		if err != nil {
+			return []string{}, err
+		}
+		if len(names) > 0 {
+			categories = append(categories, entry.Name())
+		}
+	}
+	return categories, nil
+}
+
+func (d *FilesystemDB) GetNoteByID(ctx context.Context, category, id string) (Note, error) {
+	if err := validateNotePath(category, id); err != nil {
+		return Note{}, err
+	}
+
+	unlock, err := d.lock(ctx, false)
+	if err != nil {
+		return Note{}, err
+	}
+	defer unlock()
+
+	return readNoteFile(d.notePath(category, id), category)
+}
+
+// lock locks the directory for the request, exclusively for writes. It
+// waits for the locks of other processes until the context is done. The
+// returned function releases the lock.
+func (d *FilesystemDB) lock(ctx context.Context, exclusive bool) (func(), error) {
+	unlockProcess := d.mu.RUnlock
+	if exclusive {
+		d.mu.Lock()
+		unlockProcess = d.mu.Unlock
+	} else {
+		d.mu.RLock()
+	}
+
+	f, err := os.OpenFile(filepath.Join(d.dir, lockFileName), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		unlockProcess()
+		return nil, fsError(err)
+	}
+	for {
+		locked, err := tryLockFile(f, exclusive)
+		if err != nil {
+			f.Close()
+			unlockProcess()
+			return nil, fsError(err)
+		}
+		if locked {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			f.Close()
+			unlockProcess()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	return func() {
+		_ = unlockFile(f)
+		f.Close()
+		unlockProcess()
+	}, nil
+}
+
+// notePath returns the path of the file of the note.
+func (d *FilesystemDB) notePath(category, id string) string {
+	return filepath.Join(d.dir, category, markdown.FileName(id))
+}
+
+// readCategory reads the notes of the category, sorted by ID. A category
+// without a directory has no notes.
+func (d *FilesystemDB) readCategory(category string) ([]Note, error) {
+	dir := filepath.Join(d.dir, category)
+	names, err := noteFileNames(dir)
+	if err != nil {
+		return []Note{}, err
+	}
+	var notes []Note
+	for _, name := range names {
+		note, err := readNoteFile(filepath.Join(dir, name), category)
+		if err != nil {
+			return []Note{}, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, nil
+}
+
+// noteFileNames returns the names of the note files in the directory,
+// sorted by name. Hidden files, such as the temporary files of writes,
+// are skipped.
+func noteFileNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fsError(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || filepath.Ext(name) != markdown.Extension || validateName(name) != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// readNoteFile reads the note of the file in the category.
+func readNoteFile(path, category string) (Note, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Note{}, fsError(err)
+	}
+	return parseNote(data, category, strings.TrimSuffix(filepath.Base(path), markdown.Extension))
+}
+
+// writeNoteFile writes the note to a temporary file in the directory of
+// the path, and renames it to the path, so that readers never see a
+// partly written note.
+func writeNoteFile(path string, note Note) error {
+	data, err := markdown.Marshal(toMarkdownNote(note))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInternalDB, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fsError(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fsError(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fsError(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fsError(err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fsError(err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fsError(err)
+	}
+	return nil
+}
+
+// parseNote returns the note of the file of a note in the category.
+func parseNote(data []byte, category, id string) (Note, error) {
+	note, err := markdown.Unmarshal(data)
+	if err != nil {
+		return Note{}, fmt.Errorf("%w: %s/%s: %w", ErrInternalDB, category, markdown.FileName(id), err)
+	}
+	return Note{
+		ID:        id,
+		Category:  category,
+		Note:      note.Body,
+		CreatedAt: note.Created,
+	}, nil
+}
+
+// toMarkdownNote returns the note as it is written to a file.
+func toMarkdownNote(note Note) markdown.Note {
+	return markdown.Note{
+		ID:       note.ID,
+		Category: note.Category,
+		Created:  note.CreatedAt,
+		Body:     note.Note,
+	}
+}
+
+// validateNotePath checks that the category and ID can be used as names
+// of a directory and a file.
+func validateNotePath(category, id string) error {
+	if err := validateName(category); err != nil {
+		return fmt.Errorf("%w: category: %w", ErrInvalidInput, err)
+	}
+	if err := validateName(id); err != nil {
+		return fmt.Errorf("%w: ID: %w", ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// validateName checks that the name is a single path element. Names
+// starting with a dot are not allowed, they are used by the lock file
+// and the temporary files.
+func validateName(name string) error {
+	switch {
+	case len(name) == 0:
+		return errors.New("is empty")
+	case strings.HasPrefix(name, "."):
+		return errors.New("starts with a dot")
+	case strings.ContainsAny(name, `/\`):
+		return errors.New("contains a path separator")
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return errors.New("contains a control character")
+	}
+	return nil
+}
+
+// fsError returns the database error of a filesystem error.
+func fsError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return fmt.Errorf("%w: %w", ErrInternalDB, err)
+}
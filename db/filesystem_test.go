@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FilesystemDB(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dir := t.TempDir()
+	d, err := NewFilesystemDB(dir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	created, err := d.CreateNote(ctx, Note{ID: "1", Category: "work", Note: "# Note\n\ntext", CreatedAt: createdAt})
+	require.NoError(t, err)
+	require.Equal(t, Note{ID: "1", Category: "work", Note: "# Note\n\ntext", CreatedAt: createdAt}, created)
+
+	data, err := os.ReadFile(filepath.Join(dir, "work", "1.md"))
+	require.NoError(t, err)
+	require.Equal(t, "---\nid: \"1\"\ncategory: work\ncreated: 2024-01-02T03:04:05Z\n---\n\n# Note\n\ntext\n", string(data))
+
+	_, err = d.CreateNote(ctx, Note{ID: "1", Category: "work", Note: "again"})
+	require.ErrorIs(t, err, ErrAlreadyExists)
+
+	updated, err := d.UpdateNote(ctx, Note{ID: "1", Category: "work", Note: "updated"})
+	require.NoError(t, err)
+	require.Equal(t, Note{ID: "1", Category: "work", Note: "updated", CreatedAt: createdAt}, updated)
+
+	got, err := d.GetNoteByID(ctx, "work", "1")
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+
+	_, err = d.UpdateNote(ctx, Note{ID: "2", Category: "work", Note: "missing"})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = d.CreateNote(ctx, Note{ID: "2", Category: "home", Note: "home", CreatedAt: createdAt})
+	require.NoError(t, err)
+
+	categories, err := d.ListCategories(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"home", "work"}, categories)
+
+	notes, err := d.GetNotesByCategories(ctx, []string{"home", "work", "other"})
+	require.NoError(t, err)
+	require.Equal(t, []Note{
+		{ID: "2", Category: "home", Note: "home", CreatedAt: createdAt},
+		updated,
+	}, notes)
+
+	require.NoError(t, d.DeleteNote(ctx, "2", "home"))
+	require.ErrorIs(t, d.DeleteNote(ctx, "2", "home"), ErrNotFound)
+	_, err = d.GetNoteByID(ctx, "home", "2")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoDirExists(t, filepath.Join(dir, "home"))
+
+	notes, err = d.GetNotesByCategory(ctx, "home")
+	require.NoError(t, err)
+	require.Empty(t, notes)
+
+	// No temporary files are left behind.
+	entries, err := os.ReadDir(filepath.Join(dir, "work"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func Test_FilesystemDB_CreateNote_generatesIDAndTime(t *testing.T) {
+	d, err := NewFilesystemDB(t.TempDir())
+	require.NoError(t, err)
+
+	original := newUUID
+	newUUID = func() string { return "generated" }
+	t.Cleanup(func() { newUUID = original })
+
+	note, err := d.CreateNote(context.Background(), Note{Category: "work", Note: "note"})
+	require.NoError(t, err)
+	require.Equal(t, "generated", note.ID)
+	require.False(t, note.CreatedAt.IsZero())
+}
+
+func Test_FilesystemDB_handEditedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "work", "drafts"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0o755))
+	files := map[string]string{
+		"work/a.md":          "---\r\nid: other\r\ncategory: home\r\n---\r\nedited",
+		"work/README.txt":    "not a note",
+		"work/.b.md.123.tmp": "temporary",
+		"empty/notes.txt":    "not a note",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	d, err := NewFilesystemDB(dir)
+	require.NoError(t, err)
+
+	notes, err := d.GetNotesByCategory(context.Background(), "work")
+	require.NoError(t, err)
+	require.Equal(t, []Note{{ID: "a", Category: "work", Note: "edited"}}, notes)
+
+	categories, err := d.ListCategories(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"work"}, categories)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "work", "c.md"), []byte("no front-matter"), 0o644))
+	_, err = d.GetNoteByID(context.Background(), "work", "c")
+	require.ErrorIs(t, err, ErrInternalDB)
+}
+
+func Test_FilesystemDB_invalidNames(t *testing.T) {
+	d, err := NewFilesystemDB(t.TempDir())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		note Note
+	}{
+		{name: "CreateNote() - category with a path separator", note: Note{ID: "1", Category: "../work"}},
+		{name: "CreateNote() - hidden category", note: Note{ID: "1", Category: ".git"}},
+		{name: "CreateNote() - ID with a path separator", note: Note{ID: `a\b`, Category: "work"}},
+		{name: "CreateNote() - empty category", note: Note{ID: "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.CreateNote(context.Background(), tt.note)
+			require.ErrorIs(t, err, ErrInvalidInput)
+		})
+	}
+}
+
+func Test_FilesystemDB_concurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	// Two instances on the same directory stand in for two processes.
+	first, err := NewFilesystemDB(dir)
+	require.NoError(t, err)
+	second, err := NewFilesystemDB(dir)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		d := first
+		if i%2 == 1 {
+			d = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := d.CreateNote(context.Background(), Note{ID: "same", Category: "work", Note: "note"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		require.ErrorIs(t, err, ErrAlreadyExists)
+	}
+	require.Equal(t, 1, created)
+}
+
+func Test_FilesystemDB_lockHeldByAnotherProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file locks are not supported on Windows")
+	}
+	dir := t.TempDir()
+	first, err := NewFilesystemDB(dir)
+	require.NoError(t, err)
+	second, err := NewFilesystemDB(dir)
+	require.NoError(t, err)
+
+	unlock, err := first.lock(context.Background(), true)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = second.GetNotesByCategory(ctx, "work")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlock()
+	_, err = second.GetNotesByCategory(context.Background(), "work")
+	require.NoError(t, err)
+}
@@ -0,0 +1,665 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/markdown"
+)
+
+const (
+	// defaultGitBranch is the branch the notes are committed to.
+	defaultGitBranch = "main"
+	// defaultGitBinary is the git binary.
+	defaultGitBinary = "git"
+	// maxCommitAttempts is how many times a change is committed when the
+	// branch is moved by another process at the same time.
+	maxCommitAttempts = 5
+)
+
+var (
+	// ErrRepositoryRequired is returned when the repository is not provided.
+	ErrRepositoryRequired = errors.New("repository is not provided")
+	// errBranchMoved is returned when the branch was moved while a change
+	// was committed.
+	errBranchMoved = errors.New("branch was moved")
+)
+
+// defaultGitAuthor is the author of changes without an author.
+var defaultGitAuthor = Author{Name: "notes-service", Email: "notes-service@localhost"}
+
+// GitDB stores the notes in a git repository, with the same layout as
+// the FilesystemDB: a directory per category and a Markdown file per
+// note. Every create, update and delete is a commit on the branch, with
+// the author of the context of the change, so that the history of the
+// notes can be read with git.
+//
+// The repository is used without a working tree, with the git binary and
+// a temporary index per change, so a bare repository is enough and no
+// network is used. A commit only moves the branch if it still points to
+// the commit the change was made on, and the change is made again on the
+// new commit otherwise, so that several processes can share a repository.
+type GitDB struct {
+	dir    string
+	ref    string
+	binary string
+	author Author
+	// mu serializes the changes of the process.
+	mu sync.Mutex
+}
+
+// GitDBOptions contains options for the GitDB.
+type GitDBOptions struct {
+	// Branch is the branch the notes are committed to. Defaults to main.
+	Branch string
+	// Author is the author of changes without an author in their context,
+	// and the committer of every change.
+	Author Author
+	// Binary is the git binary. Defaults to git.
+	Binary string
+}
+
+// GitDBOption is a function that sets options on the GitDB.
+type GitDBOption func(o *GitDBOptions)
+
+// NewGitDB returns a new GitDB on the git directory of a repository. A
+// bare repository is created if the directory does not exist.
+func NewGitDB(dir string, options ...GitDBOption) (*GitDB, error) {
+	if len(dir) == 0 {
+		return nil, ErrRepositoryRequired
+	}
+
+	opts := GitDBOptions{
+		Branch: defaultGitBranch,
+		Author: defaultGitAuthor,
+		Binary: defaultGitBinary,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if len(opts.Branch) == 0 {
+		opts.Branch = defaultGitBranch
+	}
+	if len(opts.Author.Name) == 0 {
+		opts.Author.Name = defaultGitAuthor.Name
+	}
+	if len(opts.Author.Email) == 0 {
+		opts.Author.Email = defaultGitAuthor.Email
+	}
+	if len(opts.Binary) == 0 {
+		opts.Binary = defaultGitBinary
+	}
+
+	d := &GitDB{
+		dir:    dir,
+		ref:    "refs/heads/" + opts.Branch,
+		binary: opts.Binary,
+		author: opts.Author,
+	}
+
+	ctx := context.Background()
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		cmd := exec.CommandContext(ctx, d.binary, "init", "--quiet", "--bare", "--initial-branch="+opts.Branch, dir)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("git init: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+	}
+	if _, err := d.run(ctx, nil, nil, "check-ref-format", d.ref); err != nil {
+		return nil, fmt.Errorf("invalid branch %q: %w", opts.Branch, err)
+	}
+	if err := d.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Ping checks that the repository can be read.
+func (d *GitDB) Ping(ctx context.Context) error {
+	if _, err := d.run(ctx, nil, nil, "rev-parse", "--git-dir"); err != nil {
+		return fmt.Errorf("%w: %w", ErrClientConnection, err)
+	}
+	return nil
+}
+
+func (d *GitDB) CreateNote(ctx context.Context, note Note) (Note, error) {
+	if len(note.ID) == 0 {
+		note.ID = newUUID()
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = time.Now().UTC()
+	}
+	if err := validateNotePath(note.Category, note.ID); err != nil {
+		return Note{}, err
+	}
+
+	err := d.commit(ctx, note.Category, note.ID, "Create", func(existing *Note) (*Note, error) {
+		if existing != nil {
+			return nil, ErrAlreadyExists
+		}
+		return &note, nil
+	})
+	if err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+// UpdateNote replaces the note. The creation time of the note is kept if
+// the note does not have one.
+func (d *GitDB) UpdateNote(ctx context.Context, note Note) (Note, error) {
+	if err := validateNotePath(note.Category, note.ID); err != nil {
+		return Note{}, err
+	}
+
+	err := d.commit(ctx, note.Category, note.ID, "Update", func(existing *Note) (*Note, error) {
+		if existing == nil {
+			return nil, ErrNotFound
+		}
+		if note.CreatedAt.IsZero() {
+			note.CreatedAt = existing.CreatedAt
+		}
+		return &note, nil
+	})
+	if err != nil {
+		return Note{}, err
+	}
+	return note, nil
+}
+
+func (d *GitDB) DeleteNote(ctx context.Context, id, category string) error {
+	if err := validateNotePath(category, id); err != nil {
+		return err
+	}
+
+	return d.commit(ctx, category, id, "Delete", func(existing *Note) (*Note, error) {
+		if existing == nil {
+			return nil, ErrNotFound
+		}
+		return nil, nil
+	})
+}
+
+func (d *GitDB) GetNotesByCategory(ctx context.Context, category string) ([]Note, error) {
+	if err := validateName(category); err != nil {
+		return []Note{}, fmt.Errorf("%w: category: %w", ErrInvalidInput, err)
+	}
+
+	head, err := d.head(ctx)
+	if err != nil {
+		return []Note{}, err
+	}
+	return d.readNotes(ctx, head, []string{category})
+}
+
+func (d *GitDB) GetNotesByCategories(ctx context.Context, categories []string) ([]Note, error) {
+	for _, category := range categories {
+		if err := validateName(category); err != nil {
+			return []Note{}, fmt.Errorf("%w: category: %w", ErrInvalidInput, err)
+		}
+	}
+
+	head, err := d.head(ctx)
+	if err != nil {
+		return []Note{}, err
+	}
+	notes, err := d.readNotes(ctx, head, categories)
+	if err != nil {
+		return []Note{}, err
+	}
+	if notes == nil {
+		notes = []Note{}
+	}
+	return notes, nil
+}
+
+// ListCategories returns the categories that have notes, sorted by name.
+func (d *GitDB) ListCategories(ctx context.Context) ([]string, error) {
+	head, err := d.head(ctx)
+	if err != nil {
+		return []string{}, err
+	}
+	files, err := d.noteFiles(ctx, head)
+	if err != nil {
+		return []string{}, err
+	}
+
+	categories := []string{}
+	for _, file := range files {
+		category := path.Dir(file)
+		if !slices.Contains(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	slices.Sort(categories)
+	return categories, nil
+}
+
+func (d *GitDB) GetNoteByID(ctx context.Context, category, id string) (Note, error) {
+	if err := validateNotePath(category, id); err != nil {
+		return Note{}, err
+	}
+
+	head, err := d.head(ctx)
+	if err != nil {
+		return Note{}, err
+	}
+	if len(head) == 0 {
+		return Note{}, ErrNotFound
+	}
+	return d.readNote(ctx, head, category, id)
+}
+
+// Revision is a commit that changed a note.
+type Revision struct {
+	Commit  string
+	Author  Author
+	Time    time.Time
+	Message string
+	// Deleted reports whether the commit deleted the note. The note is
+	// empty if it did.
+	Deleted bool
+	Note    Note
+}
+
+// History returns the commits that changed the note, newest first, with
+// the note as it was after every commit.
+func (d *GitDB) History(ctx context.Context, category, id string) ([]Revision, error) {
+	if err := validateNotePath(category, id); err != nil {
+		return []Revision{}, err
+	}
+
+	head, err := d.head(ctx)
+	if err != nil {
+		return []Revision{}, err
+	}
+	if len(head) == 0 {
+		return []Revision{}, ErrNotFound
+	}
+
+	file := notePath(category, id)
+	out, err := d.run(ctx, nil, nil, "log", "-z", "--format=%H%x1f%an%x1f%ae%x1f%aI%x1f%B", head, "--", file)
+	if err != nil {
+		return []Revision{}, gitError(err)
+	}
+
+	var revisions []Revision
+	var specs []string
+	for _, record := range strings.Split(string(out), "\x00") {
+		if len(strings.TrimSpace(record)) == 0 {
+			continue
+		}
+		fields := strings.SplitN(record, "\x1f", 5)
+		if len(fields) != 5 {
+			return []Revision{}, fmt.Errorf("%w: unexpected git log output", ErrInternalDB)
+		}
+		committed, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return []Revision{}, fmt.Errorf("%w: %w", ErrInternalDB, err)
+		}
+		revisions = append(revisions, Revision{
+			Commit:  strings.TrimSpace(fields[0]),
+			Author:  Author{Name: fields[1], Email: fields[2]},
+			Time:    committed,
+			Message: strings.TrimSpace(fields[4]),
+		})
+		specs = append(specs, strings.TrimSpace(fields[0])+":"+file)
+	}
+	if len(revisions) == 0 {
+		return []Revision{}, ErrNotFound
+	}
+
+	blobs, err := d.readBlobs(ctx, specs)
+	if err != nil {
+		return []Revision{}, err
+	}
+	for i, blob := range blobs {
+		if blob == nil {
+			revisions[i].Deleted = true
+			continue
+		}
+		if revisions[i].Note, err = parseNote(blob, category, id); err != nil {
+			return []Revision{}, err
+		}
+	}
+	return revisions, nil
+}
+
+// Revision returns the note as it was after the commit. It returns
+// ErrNotFound if the commit does not exist or the note did not exist
+// after it.
+func (d *GitDB) Revision(ctx context.Context, category, id, commit string) (Note, error) {
+	if err := validateNotePath(category, id); err != nil {
+		return Note{}, err
+	}
+	if !validCommit(commit) {
+		return Note{}, fmt.Errorf("%w: invalid commit", ErrInvalidInput)
+	}
+	return d.readNote(ctx, commit+"^{commit}", category, id)
+}
+
+// commit commits a change of the note to the branch. change is called
+// with the note as it is on the branch, nil if it does not exist, and
+// returns the note to write, or nil to delete the note. The change is
+// made again if the branch is moved by another process.
+func (d *GitDB) commit(ctx context.Context, category, id, action string, change func(existing *Note) (*Note, error)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	message, ok := ChangeMessageFromContext(ctx)
+	if !ok {
+		message = action + " note " + notePath(category, id)
+	}
+	author := d.author
+	if a, ok := AuthorFromContext(ctx); ok {
+		if len(a.Name) > 0 {
+			author.Name = a.Name
+		}
+		if len(a.Email) > 0 {
+			author.Email = a.Email
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		head, err := d.head(ctx)
+		if err != nil {
+			return err
+		}
+
+		var existing *Note
+		if len(head) > 0 {
+			note, err := d.readNote(ctx, head, category, id)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err == nil {
+				existing = &note
+			}
+		}
+
+		note, err := change(existing)
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if note != nil {
+			if data, err = markdown.Marshal(toMarkdownNote(*note)); err != nil {
+				return fmt.Errorf("%w: %w", ErrInternalDB, err)
+			}
+		}
+
+		err = d.commitFile(ctx, head, notePath(category, id), data, message, author)
+		if errors.Is(err, errBranchMoved) && attempt < maxCommitAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+// commitFile commits the file with the data on top of the head commit,
+// or deletes it if data is nil, and moves the branch to the commit. It
+// returns errBranchMoved if the branch no longer points to head.
+func (d *GitDB) commitFile(ctx context.Context, head, file string, data []byte, message string, author Author) error {
+	index, err := os.CreateTemp("", "notes-index-*")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInternalDB, err)
+	}
+	index.Close()
+	// git creates the index, it does not accept an empty file.
+	os.Remove(index.Name())
+	defer os.Remove(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+
+	if len(head) > 0 {
+		if _, err := d.run(ctx, env, nil, "read-tree", head); err != nil {
+			return gitError(err)
+		}
+	}
+	if data != nil {
+		blob, err := d.run(ctx, nil, bytes.NewReader(data), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return gitError(err)
+		}
+		if _, err := d.run(ctx, env, nil, "update-index", "--add", "--cacheinfo", "100644,"+strings.TrimSpace(string(blob))+","+file); err != nil {
+			return gitError(err)
+		}
+	} else {
+		// A mode of zero removes the path from the index. The object name
+		// is ignored, it only needs the length of the names of the
+		// repository, like the head commit.
+		removal := "0 " + strings.Repeat("0", len(head)) + "\t" + file + "\n"
+		if _, err := d.run(ctx, env, strings.NewReader(removal), "update-index", "--index-info"); err != nil {
+			return gitError(err)
+		}
+	}
+	tree, err := d.run(ctx, env, nil, "write-tree")
+	if err != nil {
+		return gitError(err)
+	}
+
+	args := []string{"commit-tree", strings.TrimSpace(string(tree)), "-F", "-"}
+	if len(head) > 0 {
+		args = append(args, "-p", head)
+	}
+	commitEnv := []string{
+		"GIT_AUTHOR_NAME=" + author.Name,
+		"GIT_AUTHOR_EMAIL=" + author.Email,
+		"GIT_COMMITTER_NAME=" + d.author.Name,
+		"GIT_COMMITTER_EMAIL=" + d.author.Email,
+	}
+	commit, err := d.run(ctx, commitEnv, strings.NewReader(message), args...)
+	if err != nil {
+		return gitError(err)
+	}
+
+	// The branch is only moved if it still points to head, or does not
+	// exist if head is empty.
+	if _, err := d.run(ctx, nil, nil, "update-ref", "-m", message, d.ref, strings.TrimSpace(string(commit)), head); err != nil {
+		current, headErr := d.head(ctx)
+		if headErr == nil && current != head {
+			return errBranchMoved
+		}
+		return gitError(err)
+	}
+	return nil
+}
+
+// head returns the commit the branch points to, or an empty string if
+// the branch has no commits.
+func (d *GitDB) head(ctx context.Context) (string, error) {
+	out, err := d.run(ctx, nil, nil, "rev-parse", "--verify", "--quiet", d.ref+"^{commit}")
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(out) == 0 {
+			return "", nil
+		}
+		return "", gitError(err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// noteFiles returns the paths of the note files of the categories in the
+// commit, or of all categories if none are given.
+func (d *GitDB) noteFiles(ctx context.Context, commit string, categories ...string) ([]string, error) {
+	if len(commit) == 0 {
+		return nil, nil
+	}
+	args := []string{"ls-tree", "-r", "-z", "--name-only", commit, "--"}
+	for _, category := range categories {
+		args = append(args, category+"/")
+	}
+	out, err := d.run(ctx, nil, nil, args...)
+	if err != nil {
+		return nil, gitError(err)
+	}
+
+	var files []string
+	for _, file := range strings.Split(string(out), "\x00") {
+		category, name, ok := strings.Cut(file, "/")
+		if !ok || path.Ext(name) != markdown.Extension || validateName(category) != nil || validateName(name) != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// readNotes reads the notes of the categories in the commit, by category
+// and sorted by ID within a category.
+func (d *GitDB) readNotes(ctx context.Context, commit string, categories []string) ([]Note, error) {
+	var notes []Note
+	for _, category := range categories {
+		files, err := d.noteFiles(ctx, commit, category)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			continue
+		}
+		specs := make([]string, len(files))
+		for i, file := range files {
+			specs[i] = commit + ":" + file
+		}
+		blobs, err := d.readBlobs(ctx, specs)
+		if err != nil {
+			return nil, err
+		}
+		for i, blob := range blobs {
+			note, err := parseNote(blob, category, strings.TrimSuffix(path.Base(files[i]), markdown.Extension))
+			if err != nil {
+				return nil, err
+			}
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+// readNote reads the note in the revision.
+func (d *GitDB) readNote(ctx context.Context, revision, category, id string) (Note, error) {
+	blobs, err := d.readBlobs(ctx, []string{revision + ":" + notePath(category, id)})
+	if err != nil {
+		return Note{}, err
+	}
+	if blobs[0] == nil {
+		return Note{}, ErrNotFound
+	}
+	return parseNote(blobs[0], category, id)
+}
+
+// readBlobs reads the blobs of the object names in a single git process.
+// The blobs of names that do not exist are nil.
+func (d *GitDB) readBlobs(ctx context.Context, names []string) ([][]byte, error) {
+	var input bytes.Buffer
+	for _, name := range names {
+		input.WriteString(name + "\n")
+	}
+	// The format has no spaces but the ones between its fields. A missing
+	// object is reported with its name, which may contain spaces.
+	out, err := d.run(ctx, nil, &input, "cat-file", "--batch=%(objectname) %(objecttype) %(objectsize)")
+	if err != nil {
+		return nil, gitError(err)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(out))
+	blobs := make([][]byte, len(names))
+	for i := range names {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("%w: unexpected git cat-file output", ErrInternalDB)
+		}
+		header = strings.TrimSuffix(header, "\n")
+		if strings.HasSuffix(header, " missing") || strings.HasSuffix(header, " ambiguous") {
+			continue
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%w: unexpected git cat-file output", ErrInternalDB)
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: unexpected git cat-file output", ErrInternalDB)
+		}
+		blob := make([]byte, size+1)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return nil, fmt.Errorf("%w: unexpected git cat-file output", ErrInternalDB)
+		}
+		if fields[1] == "blob" {
+			blobs[i] = blob[:size]
+		}
+	}
+	return blobs, nil
+}
+
+// run runs git on the repository with the environment and standard
+// input, and returns its standard output.
+func (d *GitDB) run(ctx context.Context, env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, d.binary, append([]string{"--git-dir=" + d.dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "LC_ALL=C")
+	cmd.Env = append(cmd.Env, env...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return stdout.Bytes(), &gitCommandError{args: args, stderr: strings.TrimSpace(stderr.String()), err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// gitCommandError is the error of a git command that failed.
+type gitCommandError struct {
+	args   []string
+	stderr string
+	err    error
+}
+
+func (e *gitCommandError) Error() string {
+	return fmt.Sprintf("git %s: %s: %s", e.args[0], e.err, e.stderr)
+}
+
+func (e *gitCommandError) Unwrap() error {
+	return e.err
+}
+
+// gitError returns the database error of a git error.
+func gitError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrInternalDB, err)
+}
+
+// notePath returns the path of the file of the note in the repository.
+func notePath(category, id string) string {
+	return category + "/" + markdown.FileName(id)
+}
+
+// validCommit reports whether the commit is an abbreviated or full
+// hexadecimal object name.
+func validCommit(commit string) bool {
+	if len(commit) < 4 || len(commit) > 64 {
+		return false
+	}
+	for _, c := range commit {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GitDB(t *testing.T) {
+	requireGit(t)
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	dir := filepath.Join(t.TempDir(), "notes.git")
+	d, err := NewGitDB(dir)
+	require.NoError(t, err)
+
+	alice := WithAuthor(context.Background(), Author{Name: "Alice", Email: "alice@example.com"})
+	bob := WithAuthor(context.Background(), Author{Name: "Bob"})
+
+	_, err = d.GetNoteByID(alice, "work", "1")
+	require.ErrorIs(t, err, ErrNotFound)
+	categories, err := d.ListCategories(alice)
+	require.NoError(t, err)
+	require.Empty(t, categories)
+
+	created, err := d.CreateNote(alice, Note{ID: "1", Category: "work", Note: "first", CreatedAt: createdAt})
+	require.NoError(t, err)
+	require.Equal(t, Note{ID: "1", Category: "work", Note: "first", CreatedAt: createdAt}, created)
+
+	_, err = d.CreateNote(alice, Note{ID: "1", Category: "work", Note: "again"})
+	require.ErrorIs(t, err, ErrAlreadyExists)
+
+	updated, err := d.UpdateNote(bob, Note{ID: "1", Category: "work", Note: "second"})
+	require.NoError(t, err)
+	require.Equal(t, Note{ID: "1", Category: "work", Note: "second", CreatedAt: createdAt}, updated)
+
+	_, err = d.CreateNote(bob, Note{ID: "2", Category: "home", Note: "home", CreatedAt: createdAt})
+	require.NoError(t, err)
+
+	got, err := d.GetNoteByID(bob, "work", "1")
+	require.NoError(t, err)
+	require.Equal(t, updated, got)
+
+	categories, err = d.ListCategories(bob)
+	require.NoError(t, err)
+	require.Equal(t, []string{"home", "work"}, categories)
+
+	notes, err := d.GetNotesByCategories(bob, []string{"work", "home", "other"})
+	require.NoError(t, err)
+	require.Equal(t, []Note{updated, {ID: "2", Category: "home", Note: "home", CreatedAt: createdAt}}, notes)
+
+	require.NoError(t, d.DeleteNote(alice, "1", "work"))
+	require.ErrorIs(t, d.DeleteNote(alice, "1", "work"), ErrNotFound)
+	notes, err = d.GetNotesByCategory(alice, "work")
+	require.NoError(t, err)
+	require.Empty(t, notes)
+
+	// The repository is readable with git.
+	out, err := exec.Command("git", "--git-dir="+dir, "log", "--format=%an <%ae> %s", "main").Output()
+	require.NoError(t, err)
+	require.Equal(t, "Alice <alice@example.com> Delete note work/1.md\n"+
+		"Bob <notes-service@localhost> Create note home/2.md\n"+
+		"Bob <notes-service@localhost> Update note work/1.md\n"+
+		"Alice <alice@example.com> Create note work/1.md\n", string(out))
+
+	history, err := d.History(alice, "work", "1")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.True(t, history[0].Deleted)
+	require.Equal(t, "Delete note work/1.md", history[0].Message)
+	require.Equal(t, Author{Name: "Bob", Email: "notes-service@localhost"}, history[1].Author)
+	require.Equal(t, updated, history[1].Note)
+	require.Equal(t, created, history[2].Note)
+
+	restored, err := d.Revision(alice, "work", "1", history[2].Commit)
+	require.NoError(t, err)
+	require.Equal(t, created, restored)
+
+	_, err = d.Revision(alice, "work", "1", history[0].Commit)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = d.Revision(alice, "work", "1", strings.Repeat("0", 40))
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = d.Revision(alice, "work", "1", "--all")
+	require.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = d.History(alice, "work", "3")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_GitDB_existingRepository(t *testing.T) {
+	requireGit(t)
+	dir := filepath.Join(t.TempDir(), "notes.git")
+	require.NoError(t, exec.Command("git", "init", "--quiet", "--bare", dir).Run())
+
+	d, err := NewGitDB(dir, func(o *GitDBOptions) {
+		o.Branch = "notes"
+		o.Author = Author{Name: "Service", Email: "service@example.com"}
+	})
+	require.NoError(t, err)
+
+	ctx := WithChangeMessage(context.Background(), "Import note")
+	_, err = d.CreateNote(ctx, Note{ID: "1", Category: "work", Note: "note"})
+	require.NoError(t, err)
+
+	out, err := exec.Command("git", "--git-dir="+dir, "log", "--format=%an %cn %s", "notes").Output()
+	require.NoError(t, err)
+	require.Equal(t, "Service Service Import note\n", string(out))
+
+	out, err = exec.Command("git", "--git-dir="+dir, "show", "notes:work/1.md").Output()
+	require.NoError(t, err)
+	require.Contains(t, string(out), "id: \"1\"\ncategory: work\n")
+	require.True(t, strings.HasSuffix(string(out), "---\n\nnote\n"))
+}
+
+func Test_GitDB_categoryWithSpaces(t *testing.T) {
+	requireGit(t)
+	d, err := NewGitDB(filepath.Join(t.TempDir(), "notes.git"))
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = d.CreateNote(ctx, Note{ID: "1", Category: "work", Note: "note"})
+	require.NoError(t, err)
+
+	// git cat-file reports a missing object with its name, which has
+	// as many fields as an object with a single space in its name.
+	_, err = d.GetNoteByID(ctx, "meeting notes", "1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	created, err := d.CreateNote(ctx, Note{ID: "1", Category: "meeting notes", Note: "note"})
+	require.NoError(t, err)
+	got, err := d.GetNoteByID(ctx, "meeting notes", "1")
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	_, err = d.GetNoteByID(ctx, "meeting notes", "2")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_GitDB_invalidRepository(t *testing.T) {
+	requireGit(t)
+	_, err := NewGitDB(t.TempDir())
+	require.ErrorIs(t, err, ErrClientConnection)
+
+	_, err = NewGitDB(filepath.Join(t.TempDir(), "notes.git"), func(o *GitDBOptions) {
+		o.Branch = "a..b"
+	})
+	require.Error(t, err)
+}
+
+func Test_GitDB_concurrentProcesses(t *testing.T) {
+	requireGit(t)
+	dir := filepath.Join(t.TempDir(), "notes.git")
+	// Two instances on the same repository stand in for two processes.
+	first, err := NewGitDB(dir)
+	require.NoError(t, err)
+	second, err := NewGitDB(dir)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := range 6 {
+		d := first
+		if i%2 == 1 {
+			d = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := d.CreateNote(context.Background(), Note{ID: fmt.Sprint(i), Category: "work", Note: "note"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	notes, err := first.GetNotesByCategory(context.Background(), "work")
+	require.NoError(t, err)
+	require.Len(t, notes, 6)
+}
+
+// requireGit skips the test if the git binary is not installed.
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+}
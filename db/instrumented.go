@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// observer is the interface that wraps around the method ObserveDBOperation.
+type observer interface {
+	ObserveDBOperation(operation string, duration time.Duration, err error)
+}
+
+// InstrumentedDB is a database that reports the latency and outcome of
+// every operation of the wrapped database to an observer.
+type InstrumentedDB struct {
+	db  database
+	obs observer
+}
+
+// NewInstrumentedDB returns a new InstrumentedDB wrapping the database.
+func NewInstrumentedDB(db database, observer observer) (*InstrumentedDB, error) {
+	if db == nil {
+		return nil, ErrDatabaseRequired
+	}
+	if observer == nil {
+		return nil, ErrObserverRequired
+	}
+
+	return &InstrumentedDB{
+		db:  db,
+		obs: observer,
+	}, nil
+}
+
+func (i *InstrumentedDB) CreateNote(ctx context.Context, note Note) (noteDB Note, err error) {
+	defer i.observe("CreateNote", time.Now(), &err)
+	return i.db.CreateNote(ctx, note)
+}
+
+func (i *InstrumentedDB) UpdateNote(ctx context.Context, note Note) (noteDB Note, err error) {
+	defer i.observe("UpdateNote", time.Now(), &err)
+	return i.db.UpdateNote(ctx, note)
+}
+
+func (i *InstrumentedDB) DeleteNote(ctx context.Context, id, category string) (err error) {
+	defer i.observe("DeleteNote", time.Now(), &err)
+	return i.db.DeleteNote(ctx, id, category)
+}
+
+func (i *InstrumentedDB) GetNotesByCategory(ctx context.Context, category string) (notes []Note, err error) {
+	defer i.observe("GetNotesByCategory", time.Now(), &err)
+	return i.db.GetNotesByCategory(ctx, category)
+}
+
+func (i *InstrumentedDB) GetNotesByCategories(ctx context.Context, categories []string) (notes []Note, err error) {
+	defer i.observe("GetNotesByCategories", time.Now(), &err)
+	return i.db.GetNotesByCategories(ctx, categories)
+}
+
+func (i *InstrumentedDB) ListCategories(ctx context.Context) (categories []string, err error) {
+	defer i.observe("ListCategories", time.Now(), &err)
+	return i.db.ListCategories(ctx)
+}
+
+func (i *InstrumentedDB) GetNoteByID(ctx context.Context, category, id string) (note Note, err error) {
+	defer i.observe("GetNoteByID", time.Now(), &err)
+	return i.db.GetNoteByID(ctx, category, id)
+}
+
+// observe reports the operation that started at start to the observer.
+func (i *InstrumentedDB) observe(operation string, start time.Time, err *error) {
+	i.obs.ObserveDBOperation(operation, time.Since(start), *err)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package db
+
+import "os"
+
+// tryLockFile is a no-op on platforms without flock. Requests are still
+// serialized within the process, but not across processes.
+func tryLockFile(f *os.File, exclusive bool) (bool, error) {
+	return true, nil
+}
+
+// unlockFile is a no-op on platforms without flock.
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// documentTypeOutbox is the type of the outbox records, which are stored
+// in the partitions of the notes they belong to.
+const documentTypeOutbox = "outbox"
+
+// ErrOutboxClaimed is returned when an outbox record is claimed by
+// another relay.
+var ErrOutboxClaimed = errors.New("outbox record is claimed")
+
+// Events of the outbox records.
+const (
+	OutboxEventCreated = "created"
+	OutboxEventUpdated = "updated"
+	OutboxEventDeleted = "deleted"
+)
+
+// OutboxRecord is an event of a note, written in the same transactional
+// batch as the change of the note, so that the event is not lost if the
+// process stops before it is published.
+type OutboxRecord struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Type     string `json:"type"`
+	Event    string `json:"event"`
+	// Note is the note after the change. A deleted note only has its ID
+	// and category.
+	Note      Note      `json:"note"`
+	CreatedAt time.Time `json:"timestamp"`
+	// ClaimedBy is the relay that delivers the event of the record, until
+	// ClaimedUntil.
+	ClaimedBy    string    `json:"claimedBy,omitempty"`
+	ClaimedUntil time.Time `json:"claimedUntil"`
+	// ETag is the ETag of the stored record, which is checked when the
+	// record is claimed.
+	ETag string `json:"_etag,omitempty"`
+}
+
+// EventID returns the ID of the event of the record. It is derived from
+// the stored record, the creation time in nanoseconds, so that every
+// relay and every restart delivers the event with the same ID.
+func (r OutboxRecord) EventID() uint64 {
+	return uint64(r.CreatedAt.UnixNano())
+}
+
+// document is the part of an item that tells notes and outbox records
+// apart.
+type document struct {
+	Type string `json:"type"`
+}
+
+// isOutboxRecord reports whether the item is an outbox record.
+func isOutboxRecord(item []byte) bool {
+	var doc document
+	return json.Unmarshal(item, &doc) == nil && doc.Type == documentTypeOutbox
+}
+
+// newOutboxRecord returns the operation that creates the outbox record of
+// the event of the note.
+func newOutboxRecord(event string, note Note) (BatchOperation, error) {
+	record := OutboxRecord{
+		ID:        newUUID(),
+		Category:  note.Category,
+		Type:      documentTypeOutbox,
+		Event:     event,
+		Note:      note,
+		CreatedAt: time.Now().UTC(),
+	}
+	item, err := json.Marshal(&record)
+	if err != nil {
+		return BatchOperation{}, err
+	}
+	return BatchOperation{Type: BatchCreate, ID: record.ID, Item: item}, nil
+}
+
+// writeWithOutbox executes the operation on the note together with the
+// creation of its outbox record, and returns the item of the operation.
+func (c *NotesDB) writeWithOutbox(ctx context.Context, op BatchOperation, event string, note Note) ([]byte, error) {
+	record, err := newOutboxRecord(event, note)
+	if err != nil {
+		return nil, err
+	}
+	items, err := c.cl.ExecuteBatch(ctx, note.Category, []BatchOperation{op, record})
+	if err != nil {
+		return nil, checkError(err)
+	}
+	return items[0], nil
+}
+
+// PendingOutbox returns the outbox records that are not published yet,
+// oldest first. Records that are claimed by a relay are returned as well.
+func (c *NotesDB) PendingOutbox(ctx context.Context) ([]OutboxRecord, error) {
+	respItems, err := c.cl.QueryItems(ctx, "SELECT * FROM c WHERE c.type = @type", azcosmos.QueryParameter{Name: "@type", Value: documentTypeOutbox})
+	if err != nil {
+		return nil, checkError(err)
+	}
+	records := make([]OutboxRecord, 0, len(respItems))
+	for _, item := range respItems {
+		var record OutboxRecord
+		if err := json.Unmarshal(item, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	slices.SortStableFunc(records, func(a, b OutboxRecord) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return records, nil
+}
+
+// ClaimOutbox claims the outbox record for the owner for the TTL, so that
+// other relays do not deliver its event in the meantime. The owner can
+// claim the record again to extend the claim. It returns ErrOutboxClaimed
+// if the record is claimed by another owner, or was changed since it was
+// read, and ErrNotFound if it was deleted.
+func (c *NotesDB) ClaimOutbox(ctx context.Context, record OutboxRecord, owner string, ttl time.Duration) (OutboxRecord, error) {
+	now := time.Now().UTC()
+	if len(record.ClaimedBy) > 0 && record.ClaimedBy != owner && now.Before(record.ClaimedUntil) {
+		return OutboxRecord{}, ErrOutboxClaimed
+	}
+
+	claimed := record
+	claimed.ClaimedBy = owner
+	claimed.ClaimedUntil = now.Add(ttl)
+	claimed.ETag = ""
+	item, err := json.Marshal(&claimed)
+	if err != nil {
+		return OutboxRecord{}, err
+	}
+	// The record is replaced in a batch, which checks the ETag, so that
+	// only one of the relays that read it can claim it.
+	items, err := c.cl.ExecuteBatch(ctx, record.Category, []BatchOperation{
+		{Type: BatchReplace, ID: record.ID, Item: item, IfMatch: record.ETag},
+	})
+	if err != nil {
+		err = checkError(err)
+		if errors.Is(err, ErrPreconditionFailed) {
+			return OutboxRecord{}, ErrOutboxClaimed
+		}
+		return OutboxRecord{}, err
+	}
+	if err := json.Unmarshal(items[0], &claimed); err != nil {
+		return OutboxRecord{}, err
+	}
+	return claimed, nil
+}
+
+// DeleteOutbox deletes the published outbox record. A record that was
+// already deleted is not an error.
+func (c *NotesDB) DeleteOutbox(ctx context.Context, record OutboxRecord) error {
+	err := c.cl.DeleteItem(ctx, record.Category, record.ID)
+	if err != nil {
+		if err := checkError(err); !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
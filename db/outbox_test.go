@@ -0,0 +1,298 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_NotesDB_outbox(t *testing.T) {
+	note := Note{
+		ID:        "123e4567-e89b-12d3-a456-426614174000",
+		Category:  "work",
+		Note:      "note",
+		CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	noteJSON, err := json.Marshal(note)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		write     func(db *NotesDB) error
+		wantOp    BatchOperation
+		wantEvent string
+		wantNote  Note
+		mockErr   error
+		wantErr   error
+		responses [][]byte
+	}{
+		{
+			name: "CreateNote() - note and outbox record",
+			write: func(db *NotesDB) error {
+				_, err := db.CreateNote(context.Background(), note)
+				return err
+			},
+			wantOp:    BatchOperation{Type: BatchCreate, ID: note.ID, Item: noteJSON},
+			wantEvent: OutboxEventCreated,
+			wantNote:  note,
+			responses: [][]byte{noteJSON, []byte(`{}`)},
+		},
+		{
+			name: "UpdateNote() - note and outbox record",
+			write: func(db *NotesDB) error {
+				_, err := db.UpdateNote(context.Background(), note)
+				return err
+			},
+			wantOp:    BatchOperation{Type: BatchReplace, ID: note.ID, Item: noteJSON},
+			wantEvent: OutboxEventUpdated,
+			wantNote:  note,
+			responses: [][]byte{noteJSON, []byte(`{}`)},
+		},
+		{
+			name: "DeleteNote() - note and outbox record",
+			write: func(db *NotesDB) error {
+				return db.DeleteNote(context.Background(), note.ID, note.Category)
+			},
+			wantOp:    BatchOperation{Type: BatchDelete, ID: note.ID},
+			wantEvent: OutboxEventDeleted,
+			wantNote:  Note{ID: note.ID, Category: note.Category},
+			responses: [][]byte{nil, []byte(`{}`)},
+		},
+		{
+			name: "DeleteNote() - batch fails",
+			write: func(db *NotesDB) error {
+				return db.DeleteNote(context.Background(), note.ID, note.Category)
+			},
+			wantOp:    BatchOperation{Type: BatchDelete, ID: note.ID},
+			wantEvent: OutboxEventDeleted,
+			wantNote:  Note{ID: note.ID, Category: note.Category},
+			mockErr:   newResponseError(http.StatusNotFound, ""),
+			wantErr:   ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockClient := mockCosmosContainerClient{
+				t: t,
+				input: mockInput{
+					ctx:          context.Background(),
+					partitionKey: note.Category,
+				},
+				responses: tt.responses,
+				err:       tt.mockErr,
+			}
+			notesDB, err := NewNotesDB(&mockClient, func(o *NotesDBOptions) {
+				o.Outbox = true
+			})
+			require.NoError(t, err)
+
+			// Act
+			err = tt.write(notesDB)
+
+			// Assert
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, mockClient.operations, 2)
+			require.Equal(t, tt.wantOp, mockClient.operations[0])
+
+			recordOp := mockClient.operations[1]
+			require.Equal(t, BatchCreate, recordOp.Type)
+			var record OutboxRecord
+			require.NoError(t, json.Unmarshal(recordOp.Item, &record))
+			require.Equal(t, recordOp.ID, record.ID)
+			require.Equal(t, documentTypeOutbox, record.Type)
+			require.Equal(t, note.Category, record.Category)
+			require.Equal(t, tt.wantEvent, record.Event)
+			require.Equal(t, tt.wantNote, record.Note)
+			require.True(t, isOutboxRecord(recordOp.Item))
+		})
+	}
+}
+
+func Test_NotesDB_readsSkipOutbox(t *testing.T) {
+	record := []byte(`{"id":"r1","category":"work","type":"outbox","event":"created","note":{"id":"1","category":"work"}}`)
+	note := []byte(`{"id":"1","category":"work","note":"note"}`)
+
+	t.Run("GetNotesByCategory()", func(t *testing.T) {
+		mockClient := mockCosmosContainerClient{
+			t:         t,
+			input:     mockInput{partitionKey: "work"},
+			responses: [][]byte{note, record},
+		}
+		notesDB, err := NewNotesDB(&mockClient)
+		require.NoError(t, err)
+
+		got, err := notesDB.GetNotesByCategory(context.Background(), "work")
+		require.NoError(t, err)
+		require.Equal(t, []Note{{ID: "1", Category: "work", Note: "note"}}, got)
+	})
+
+	t.Run("GetNoteByID()", func(t *testing.T) {
+		mockClient := mockCosmosContainerClient{
+			t:        t,
+			input:    mockInput{ctx: context.Background(), partitionKey: "work", id: "r1"},
+			response: record,
+		}
+		notesDB, err := NewNotesDB(&mockClient)
+		require.NoError(t, err)
+
+		_, err = notesDB.GetNoteByID(context.Background(), "work", "r1")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func Test_NotesDB_PendingOutbox(t *testing.T) {
+	mockClient := mockCosmosContainerClient{
+		t: t,
+		responses: [][]byte{
+			[]byte(`{"id":"2","category":"work","type":"outbox","event":"updated","note":{"id":"1","category":"work"},"timestamp":"2024-01-01T00:00:02Z"}`),
+			[]byte(`{"id":"1","category":"work","type":"outbox","event":"created","note":{"id":"1","category":"work"},"timestamp":"2024-01-01T00:00:01Z"}`),
+		},
+	}
+	notesDB, err := NewNotesDB(&mockClient)
+	require.NoError(t, err)
+
+	records, err := notesDB.PendingOutbox(context.Background())
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "1", records[0].ID)
+	assert.Equal(t, OutboxEventCreated, records[0].Event)
+	assert.Equal(t, "2", records[1].ID)
+	assert.Equal(t, OutboxEventUpdated, records[1].Event)
+}
+
+func Test_NotesDB_DeleteOutbox(t *testing.T) {
+	tests := []struct {
+		name    string
+		mockErr error
+		wantErr error
+	}{
+		{
+			name: "DeleteOutbox() - deleted",
+		},
+		{
+			name:    "DeleteOutbox() - already deleted",
+			mockErr: newResponseError(http.StatusNotFound, ""),
+		},
+		{
+			name:    "DeleteOutbox() - error",
+			mockErr: newResponseError(http.StatusInternalServerError, ""),
+			wantErr: ErrInternalDB,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := mockCosmosContainerClient{
+				t:     t,
+				input: mockInput{ctx: context.Background(), partitionKey: "work", id: "r1"},
+				err:   tt.mockErr,
+			}
+			notesDB, err := NewNotesDB(&mockClient)
+			require.NoError(t, err)
+
+			err = notesDB.DeleteOutbox(context.Background(), OutboxRecord{ID: "r1", Category: "work"})
+			require.ErrorIs(t, err, tt.wantErr)
+			require.True(t, mockClient.funcCalled)
+		})
+	}
+}
+
+func Test_NotesDB_ClaimOutbox(t *testing.T) {
+	now := time.Now().UTC()
+
+	tests := []struct {
+		name      string
+		record    OutboxRecord
+		mockErr   error
+		wantBatch bool
+		wantErr   error
+	}{
+		{
+			name:      "ClaimOutbox() - unclaimed record",
+			record:    OutboxRecord{ID: "r1", Category: "work", ETag: "etag-1"},
+			wantBatch: true,
+		},
+		{
+			name:      "ClaimOutbox() - expired claim of another owner",
+			record:    OutboxRecord{ID: "r1", Category: "work", ETag: "etag-1", ClaimedBy: "other", ClaimedUntil: now.Add(-time.Second)},
+			wantBatch: true,
+		},
+		{
+			name:      "ClaimOutbox() - extends the claim of the owner",
+			record:    OutboxRecord{ID: "r1", Category: "work", ETag: "etag-1", ClaimedBy: "relay", ClaimedUntil: now.Add(time.Minute)},
+			wantBatch: true,
+		},
+		{
+			name:    "ClaimOutbox() - claimed by another owner",
+			record:  OutboxRecord{ID: "r1", Category: "work", ETag: "etag-1", ClaimedBy: "other", ClaimedUntil: now.Add(time.Minute)},
+			wantErr: ErrOutboxClaimed,
+		},
+		{
+			name:      "ClaimOutbox() - claimed by another owner since it was read",
+			record:    OutboxRecord{ID: "r1", Category: "work", ETag: "etag-1"},
+			mockErr:   newResponseError(http.StatusPreconditionFailed, ""),
+			wantBatch: true,
+			wantErr:   ErrOutboxClaimed,
+		},
+		{
+			name:      "ClaimOutbox() - already deleted",
+			record:    OutboxRecord{ID: "r1", Category: "work", ETag: "etag-1"},
+			mockErr:   newResponseError(http.StatusNotFound, ""),
+			wantBatch: true,
+			wantErr:   ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockClient := mockCosmosContainerClient{
+				t:         t,
+				input:     mockInput{ctx: context.Background(), partitionKey: "work"},
+				responses: [][]byte{[]byte(`{"id":"r1","category":"work","type":"outbox","claimedBy":"relay","_etag":"etag-2"}`)},
+				err:       tt.mockErr,
+			}
+			notesDB, err := NewNotesDB(&mockClient)
+			require.NoError(t, err)
+
+			// Act
+			claimed, err := notesDB.ClaimOutbox(context.Background(), tt.record, "relay", time.Minute)
+
+			// Assert
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.wantBatch, mockClient.funcCalled)
+			if tt.wantBatch {
+				require.Len(t, mockClient.operations, 1)
+				op := mockClient.operations[0]
+				require.Equal(t, BatchReplace, op.Type)
+				require.Equal(t, "r1", op.ID)
+				require.Equal(t, "etag-1", op.IfMatch)
+
+				var item OutboxRecord
+				require.NoError(t, json.Unmarshal(op.Item, &item))
+				require.Equal(t, "relay", item.ClaimedBy)
+				require.WithinDuration(t, now.Add(time.Minute), item.ClaimedUntil, 5*time.Second)
+				require.Empty(t, item.ETag)
+			}
+			if tt.wantErr == nil {
+				require.Equal(t, "etag-2", claimed.ETag)
+				require.Equal(t, "relay", claimed.ClaimedBy)
+			}
+		})
+	}
+}
+
+func Test_OutboxRecord_EventID(t *testing.T) {
+	first := OutboxRecord{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 1, time.UTC)}
+	second := OutboxRecord{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 2, time.UTC)}
+
+	require.Equal(t, uint64(1704067200000000001), first.EventID())
+	require.Less(t, first.EventID(), second.EventID())
+}
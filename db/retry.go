@@ -0,0 +1,251 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+const (
+	// defaultMaxRetries is the default number of retries of an operation.
+	defaultMaxRetries = 3
+	// defaultRetryBaseDelay is the default delay before the first retry.
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	// defaultRetryMaxDelay is the default maximum delay between retries.
+	defaultRetryMaxDelay = 2 * time.Second
+	// headerRetryAfterMs is the header Cosmos DB sets on throttled responses.
+	headerRetryAfterMs = "x-ms-retry-after-ms"
+	// statusRetryWith is returned by Cosmos DB on write conflicts that can be retried.
+	statusRetryWith = 449
+)
+
+// RetryClient is a client that retries operations of the wrapped client
+// that failed with a transient error, with jittered exponential backoff.
+type RetryClient struct {
+	cl         client
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	jitter     func(d time.Duration) time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// RetryOptions contains options for the RetryClient.
+type RetryOptions struct {
+	// MaxRetries is the maximum number of retries of an operation.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry. It is doubled for
+	// every following retry.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between retries.
+	MaxDelay time.Duration
+}
+
+// RetryOption is a function that sets options on the RetryClient.
+type RetryOption func(o *RetryOptions)
+
+// NewRetryClient returns a new RetryClient wrapping the client.
+//
+// Reads are retried on throttling (429), unavailability (503), timeouts
+// and network errors. Writes are only retried on responses that guarantee
+// that the write was not applied (429 and 449), so that a retry can not
+// create a duplicate or overwrite a newer write.
+func NewRetryClient(client client, options ...RetryOption) (*RetryClient, error) {
+	if client == nil {
+		return nil, ErrClientRequired
+	}
+
+	opts := RetryOptions{
+		MaxRetries: defaultMaxRetries,
+		BaseDelay:  defaultRetryBaseDelay,
+		MaxDelay:   defaultRetryMaxDelay,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &RetryClient{
+		cl:         client,
+		maxRetries: opts.MaxRetries,
+		baseDelay:  opts.BaseDelay,
+		maxDelay:   opts.MaxDelay,
+		jitter:     fullJitter,
+		sleep:      sleep,
+	}, nil
+}
+
+func (c *RetryClient) CreateItem(ctx context.Context, partitionKey string, item []byte) ([]byte, error) {
+	var resp []byte
+	err := c.do(ctx, isTransientWriteError, func() error {
+		var err error
+		resp, err = c.cl.CreateItem(ctx, partitionKey, item)
+		return err
+	})
+	return resp, err
+}
+
+func (c *RetryClient) ReplaceItem(ctx context.Context, partitionKey string, id string, item []byte) ([]byte, error) {
+	var resp []byte
+	err := c.do(ctx, isTransientWriteError, func() error {
+		var err error
+		resp, err = c.cl.ReplaceItem(ctx, partitionKey, id, item)
+		return err
+	})
+	return resp, err
+}
+
+func (c *RetryClient) DeleteItem(ctx context.Context, partitionKey string, id string) error {
+	return c.do(ctx, isTransientWriteError, func() error {
+		return c.cl.DeleteItem(ctx, partitionKey, id)
+	})
+}
+
+func (c *RetryClient) ReadItem(ctx context.Context, partitionKey string, id string) ([]byte, error) {
+	var resp []byte
+	err := c.do(ctx, isTransientReadError, func() error {
+		var err error
+		resp, err = c.cl.ReadItem(ctx, partitionKey, id)
+		return err
+	})
+	return resp, err
+}
+
+func (c *RetryClient) ListItems(ctx context.Context, partitionKey string) ([][]byte, error) {
+	var resp [][]byte
+	err := c.do(ctx, isTransientReadError, func() error {
+		var err error
+		resp, err = c.cl.ListItems(ctx, partitionKey)
+		return err
+	})
+	return resp, err
+}
+
+func (c *RetryClient) QueryItems(ctx context.Context, query string, parameters ...azcosmos.QueryParameter) ([][]byte, error) {
+	var resp [][]byte
+	err := c.do(ctx, isTransientReadError, func() error {
+		var err error
+		resp, err = c.cl.QueryItems(ctx, query, parameters...)
+		return err
+	})
+	return resp, err
+}
+
+func (c *RetryClient) ExecuteBatch(ctx context.Context, partitionKey string, operations []BatchOperation) ([][]byte, error) {
+	var resp [][]byte
+	err := c.do(ctx, isTransientWriteError, func() error {
+		var err error
+		resp, err = c.cl.ExecuteBatch(ctx, partitionKey, operations)
+		return err
+	})
+	return resp, err
+}
+
+// do calls op until it succeeds, fails with an error that is not
+// transient, the retries are exhausted or the context is done.
+// The last error of op is returned. It is wrapped with ErrUnavailable if
+// it is transient and the retries are exhausted, since the database is
+// likely to recover.
+func (c *RetryClient) do(ctx context.Context, transient func(err error) bool, op func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || !transient(err) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= c.maxRetries {
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+
+		delay, ok := retryAfter(err)
+		if !ok {
+			delay = c.jitter(c.backoff(attempt))
+		}
+		// Do not wait for a retry that can not complete before the deadline.
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		if sleepErr := c.sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the exponential backoff delay for the attempt.
+func (c *RetryClient) backoff(attempt int) time.Duration {
+	delay := c.baseDelay
+	for i := 0; i < attempt && delay < c.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, c.maxDelay)
+}
+
+// retryAfter returns the delay requested by Cosmos DB in the
+// x-ms-retry-after-ms header of the response, if it is set.
+func retryAfter(err error) (time.Duration, bool) {
+	var responseError *azcore.ResponseError
+	if !errors.As(err, &responseError) || responseError.RawResponse == nil {
+		return 0, false
+	}
+	ms, err := strconv.ParseFloat(responseError.RawResponse.Header.Get(headerRetryAfterMs), 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms * float64(time.Millisecond)), true
+}
+
+// isTransientWriteError reports whether a failed write can be retried
+// without the risk of applying it twice.
+func isTransientWriteError(err error) bool {
+	var responseError *azcore.ResponseError
+	if errors.As(err, &responseError) {
+		switch responseError.StatusCode {
+		case http.StatusTooManyRequests, statusRetryWith:
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientReadError reports whether a failed read can be retried.
+func isTransientReadError(err error) bool {
+	var responseError *azcore.ResponseError
+	if errors.As(err, &responseError) {
+		switch responseError.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusRequestTimeout, statusRetryWith:
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// fullJitter returns a random duration in [0, d).
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// sleep waits for the duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
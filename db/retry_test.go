@@ -0,0 +1,260 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RetryClient(t *testing.T) {
+	throttled := newResponseError(http.StatusTooManyRequests, "")
+	throttledWithRetryAfter := newResponseError(http.StatusTooManyRequests, "250")
+	unavailable := newResponseError(http.StatusServiceUnavailable, "")
+	notFound := newResponseError(http.StatusNotFound, "")
+
+	tests := []struct {
+		name            string
+		op              func(ctx context.Context, c *RetryClient) error
+		errs            []error
+		timeout         time.Duration
+		wantCalls       int
+		wantSleeps      []time.Duration
+		expectError     error
+		wantUnavailable bool
+	}{
+		{
+			name:       "ReadItem() - retried until success",
+			op:         readItem,
+			errs:       []error{throttled, unavailable, nil},
+			wantCalls:  3,
+			wantSleeps: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:            "ReadItem() - retries exhausted",
+			op:              readItem,
+			errs:            []error{throttled, throttled, throttled, throttled, throttled},
+			wantCalls:       4,
+			wantSleeps:      []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond},
+			expectError:     throttled,
+			wantUnavailable: true,
+		},
+		{
+			name:            "ReadItem() - retries exhausted on timeouts",
+			op:              readItem,
+			errs:            []error{context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded, context.DeadlineExceeded},
+			wantCalls:       4,
+			wantSleeps:      []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond},
+			expectError:     context.DeadlineExceeded,
+			wantUnavailable: true,
+		},
+		{
+			name:        "ReadItem() - not retried on permanent error",
+			op:          readItem,
+			errs:        []error{notFound},
+			wantCalls:   1,
+			expectError: notFound,
+		},
+		{
+			name:       "ReadItem() - retried on timeout",
+			op:         readItem,
+			errs:       []error{context.DeadlineExceeded, nil},
+			wantCalls:  2,
+			wantSleeps: []time.Duration{100 * time.Millisecond},
+		},
+		{
+			name:       "ListItems() - honors x-ms-retry-after-ms",
+			op:         listItems,
+			errs:       []error{throttledWithRetryAfter, nil},
+			wantCalls:  2,
+			wantSleeps: []time.Duration{250 * time.Millisecond},
+		},
+		{
+			name:       "CreateItem() - retried when throttled",
+			op:         createItem,
+			errs:       []error{throttled, nil},
+			wantCalls:  2,
+			wantSleeps: []time.Duration{100 * time.Millisecond},
+		},
+		{
+			name:        "CreateItem() - not retried when unavailable",
+			op:          createItem,
+			errs:        []error{unavailable},
+			wantCalls:   1,
+			expectError: unavailable,
+		},
+		{
+			name:        "DeleteItem() - not retried on timeout",
+			op:          deleteItem,
+			errs:        []error{context.DeadlineExceeded},
+			wantCalls:   1,
+			expectError: context.DeadlineExceeded,
+		},
+		{
+			name:            "ReadItem() - not retried past the deadline",
+			op:              readItem,
+			errs:            []error{throttledWithRetryAfter},
+			timeout:         100 * time.Millisecond,
+			wantCalls:       1,
+			expectError:     throttledWithRetryAfter,
+			wantUnavailable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fake := &failingClient{errs: tt.errs}
+			client, err := NewRetryClient(fake)
+			require.NoError(t, err)
+
+			var sleeps []time.Duration
+			client.jitter = func(d time.Duration) time.Duration { return d }
+			client.sleep = func(ctx context.Context, d time.Duration) error {
+				sleeps = append(sleeps, d)
+				return nil
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			// Act
+			err = tt.op(ctx, client)
+
+			// Assert
+			require.Equal(t, tt.wantCalls, fake.calls)
+			require.Equal(t, tt.wantSleeps, sleeps)
+			if tt.expectError != nil {
+				require.ErrorIs(t, err, tt.expectError)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantUnavailable, errors.Is(err, ErrUnavailable))
+		})
+	}
+}
+
+func Test_RetryClient_contextCancelled(t *testing.T) {
+	fake := &failingClient{errs: []error{newResponseError(http.StatusTooManyRequests, ""), nil}}
+	client, err := NewRetryClient(fake, func(o *RetryOptions) {
+		o.BaseDelay = time.Minute
+		o.MaxDelay = time.Minute
+	})
+	require.NoError(t, err)
+	client.jitter = func(d time.Duration) time.Duration { return d }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err = client.ReadItem(ctx, "category", "id")
+
+	require.Error(t, err)
+	require.Equal(t, 1, fake.calls)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func Test_RetryClient_backoff(t *testing.T) {
+	client, err := NewRetryClient(&failingClient{}, func(o *RetryOptions) {
+		o.BaseDelay = 100 * time.Millisecond
+		o.MaxDelay = time.Second
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 100*time.Millisecond, client.backoff(0))
+	assert.Equal(t, 400*time.Millisecond, client.backoff(2))
+	assert.Equal(t, time.Second, client.backoff(4))
+	assert.Equal(t, time.Second, client.backoff(100))
+}
+
+func readItem(ctx context.Context, c *RetryClient) error {
+	_, err := c.ReadItem(ctx, "category", "id")
+	return err
+}
+
+func listItems(ctx context.Context, c *RetryClient) error {
+	_, err := c.ListItems(ctx, "category")
+	return err
+}
+
+func createItem(ctx context.Context, c *RetryClient) error {
+	_, err := c.CreateItem(ctx, "category", []byte(`{}`))
+	return err
+}
+
+func deleteItem(ctx context.Context, c *RetryClient) error {
+	return c.DeleteItem(ctx, "category", "id")
+}
+
+// newResponseError returns a Cosmos DB response error with the status code
+// and, if set, the x-ms-retry-after-ms header.
+func newResponseError(statusCode int, retryAfterMs string) error {
+	header := http.Header{}
+	if len(retryAfterMs) > 0 {
+		header.Set(headerRetryAfterMs, retryAfterMs)
+	}
+	return &azcore.ResponseError{
+		StatusCode:  statusCode,
+		RawResponse: &http.Response{StatusCode: statusCode, Header: header},
+	}
+}
+
+// failingClient is a client that fails its calls with the errors in errs,
+// in order. Calls after the last error succeed.
+type failingClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *failingClient) next() error {
+	c.calls++
+	if c.calls > len(c.errs) {
+		return nil
+	}
+	return c.errs[c.calls-1]
+}
+
+func (c *failingClient) CreateItem(ctx context.Context, partitionKey string, item []byte) ([]byte, error) {
+	return item, c.next()
+}
+
+func (c *failingClient) ReplaceItem(ctx context.Context, partitionKey string, id string, item []byte) ([]byte, error) {
+	return item, c.next()
+}
+
+func (c *failingClient) DeleteItem(ctx context.Context, partitionKey string, id string) error {
+	return c.next()
+}
+
+func (c *failingClient) ReadItem(ctx context.Context, partitionKey string, id string) ([]byte, error) {
+	return []byte(`{}`), c.next()
+}
+
+func (c *failingClient) ListItems(ctx context.Context, partitionKey string) ([][]byte, error) {
+	return [][]byte{}, c.next()
+}
+
+func (c *failingClient) ExecuteBatch(ctx context.Context, partitionKey string, operations []BatchOperation) ([][]byte, error) {
+	items := make([][]byte, len(operations))
+	for i, op := range operations {
+		items[i] = op.Item
+	}
+	return items, c.next()
+}
+
+func (c *failingClient) QueryItems(ctx context.Context, query string, parameters ...azcosmos.QueryParameter) ([][]byte, error) {
+	return [][]byte{}, c.next()
+}
@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"errors"
 	"log/slog"
 	"os"
@@ -13,8 +14,8 @@ type Logger struct {
 
 func New() *Logger {
 	return &Logger{
-		stderr: slog.New(slog.NewJSONHandler(os.Stderr, nil)),
-		stdout: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		stderr: slog.New(contextHandler{slog.NewJSONHandler(os.Stderr, nil)}),
+		stdout: slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, nil)}),
 	}
 }
 
@@ -37,8 +38,8 @@ func NewWithSetLevel(logLevel string) (*Logger, error) {
 	}
 
 	return &Logger{
-		stderr: slog.New(slog.NewJSONHandler(os.Stderr, nil)),
-		stdout: slog.New(slog.NewJSONHandler(os.Stdout, handlerOpts)),
+		stderr: slog.New(contextHandler{slog.NewJSONHandler(os.Stderr, nil)}),
+		stdout: slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, handlerOpts)}),
 	}, nil
 }
 
@@ -56,3 +57,18 @@ func (l Logger) Info(msg string, args ...any) {
 func (l Logger) Error(msg string, args ...any) {
 	l.stderr.Error(msg, args...)
 }
+
+// DebugContext logs at [LevelDebug] with the request ID of the context.
+func (l Logger) DebugContext(ctx context.Context, msg string, args ...any) {
+	l.stdout.DebugContext(ctx, msg, args...)
+}
+
+// InfoContext logs at [LevelInfo] with the request ID of the context.
+func (l Logger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.stdout.InfoContext(ctx, msg, args...)
+}
+
+// ErrorContext logs at [LevelError] with the request ID of the context.
+func (l Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.stderr.ErrorContext(ctx, msg, args...)
+}
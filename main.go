@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/KatrinSalt/notes-service/config"
 	"github.com/KatrinSalt/notes-service/log"
+	"github.com/KatrinSalt/notes-service/metrics"
 	"github.com/KatrinSalt/notes-service/server"
+	"github.com/KatrinSalt/notes-service/tracing"
+	"github.com/KatrinSalt/notes-service/ui"
 )
 
 func main() {
@@ -45,21 +50,99 @@ func run(log *log.Logger) error {
 
 	log.Debug("Loaded configuration.", "config", cfg)
 
-	services, err := config.SetupServices(cfg.Services)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, func(o *tracing.Options) {
+		o.SampleRatio = cfg.Tracing.SampleRatio
+	})
+	if err != nil {
+		return fmt.Errorf("could not setup tracing: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("Failed to shut down tracing.", "error", err)
+		}
+	}()
+
+	var m *metrics.Metrics
+	if cfg.Server.MetricsEnabled {
+		m = metrics.New()
+	}
+
+	services, err := config.SetupServices(cfg.Services, m)
 	if err != nil {
 		return fmt.Errorf("could not setup services: %w", err)
 	}
 
-	srv, err := server.New(
-		services.Note,
-		server.WithAddress(cfg.Server.Host+":"+cfg.Server.Port),
+	options := []server.Option{
+		server.WithAddress(cfg.Server.Host + ":" + cfg.Server.Port),
 		server.WithLogger(log),
-		server.WithLogger(log),
-	)
+		server.WithIdempotencyTTL(cfg.Server.IdempotencyTTL),
+		server.WithShutdownDelay(cfg.Server.ShutdownDelay),
+		server.WithReadinessCheck(server.ReadinessCheck{Name: "database", Check: services.NotesDBPing}),
+		server.WithEvents(services.NoteEvents),
+		server.WithWebhooks(services.Webhooks),
+		server.WithArchive(services.NotesArchive),
+	}
+	if services.NotesHistory != nil {
+		options = append(options, server.WithHistory(services.NotesHistory))
+	}
+	if services.NotesDBBreaker != nil {
+		options = append(options, server.WithReadinessCheck(server.ReadinessCheck{Name: "circuitBreaker", Check: services.NotesDBBreaker.Check, Optional: true}))
+	}
+	if m != nil {
+		options = append(options, server.WithMetrics(m))
+	}
+	if len(cfg.Server.TLS.CertFile) > 0 {
+		options = append(options, server.WithTLS(server.TLSOptions{
+			CertFile:     cfg.Server.TLS.CertFile,
+			KeyFile:      cfg.Server.TLS.KeyFile,
+			MinVersion:   cfg.Server.TLS.MinVersion,
+			ClientCAFile: cfg.Server.TLS.ClientCAFile,
+		}))
+	}
+	if len(cfg.Server.CORS.AllowedOrigins) > 0 {
+		options = append(options, server.WithCORS(server.CORSOptions{
+			AllowedOrigins:   cfg.Server.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.Server.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.Server.CORS.AllowedHeaders,
+			AllowCredentials: cfg.Server.CORS.AllowCredentials,
+			MaxAge:           cfg.Server.CORS.MaxAge,
+		}))
+	}
+	if len(cfg.Server.GRPCPort) > 0 {
+		options = append(options, server.WithGRPC(cfg.Server.Host+":"+cfg.Server.GRPCPort))
+	}
+	if cfg.Server.UIEnabled {
+		options = append(options, server.WithUI(ui.Handler()))
+	}
+	if cfg.Server.AdminEnabled {
+		options = append(options, server.WithAdmin())
+	}
+	if cfg.Server.RateLimit.Enabled {
+		options = append(options, server.WithRateLimit(
+			server.RateLimit{Rate: cfg.Server.RateLimit.ReadRate, Burst: cfg.Server.RateLimit.ReadBurst},
+			server.RateLimit{Rate: cfg.Server.RateLimit.WriteRate, Burst: cfg.Server.RateLimit.WriteBurst},
+		), server.WithAPIKeys(cfg.Server.RateLimit.APIKeys...))
+	}
+
+	srv, err := server.New(services.Note, options...)
 	if err != nil {
 		return fmt.Errorf("could not create server: %w", err)
 	}
 
+	// The webhooks are delivered, the change feed is processed and the
+	// outbox is relayed until the server stops.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go services.Webhooks.Run(ctx)
+	if services.NotesChangeFeed != nil {
+		go services.NotesChangeFeed.Run(ctx)
+	}
+	if services.OutboxRelay != nil {
+		go services.OutboxRelay.Run(ctx)
+	}
+
 	if err := srv.Start(); err != nil {
 		return fmt.Errorf("could not start server: %w", err)
 
@@ -0,0 +1,83 @@
+// Package markdown reads and writes notes as Markdown files with a YAML
+// front-matter.
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Extension is the file extension of notes.
+	Extension = ".md"
+	// delimiter opens and closes the front-matter.
+	delimiter = "---"
+)
+
+// ErrInvalidNote is returned when a file is not a note.
+var ErrInvalidNote = errors.New("invalid markdown note")
+
+// Note is a note as it is written to a file. The fields other than the
+// body are written to the front-matter.
+type Note struct {
+	ID       string    `yaml:"id"`
+	Category string    `yaml:"category"`
+	Created  time.Time `yaml:"created"`
+	Tags     []string  `yaml:"tags,omitempty"`
+	Body     string    `yaml:"-"`
+}
+
+// FileName returns the name of the file of the note with the ID.
+func FileName(id string) string {
+	return id + Extension
+}
+
+// Marshal returns the note as Markdown with a front-matter. The body
+// follows the front-matter after an empty line, and ends with a newline.
+func Marshal(note Note) ([]byte, error) {
+	frontMatter, err := yaml.Marshal(&note)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(delimiter + "\n")
+	buf.Write(frontMatter)
+	buf.WriteString(delimiter + "\n\n")
+	buf.WriteString(note.Body)
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// Unmarshal reads a note written by Marshal. Notes edited by hand can
+// have Windows line endings, and omit the empty line after the
+// front-matter and the newline at the end of the body.
+func Unmarshal(data []byte) (Note, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	rest, ok := strings.CutPrefix(text, delimiter+"\n")
+	if !ok {
+		return Note{}, fmt.Errorf("%w: front-matter is missing", ErrInvalidNote)
+	}
+	frontMatter, body, ok := strings.Cut(rest, "\n"+delimiter+"\n")
+	if !ok {
+		// The file ends with the front-matter.
+		frontMatter, ok = strings.CutSuffix(rest, "\n"+delimiter)
+		if !ok {
+			return Note{}, fmt.Errorf("%w: front-matter is not closed", ErrInvalidNote)
+		}
+	}
+
+	var note Note
+	if err := yaml.Unmarshal([]byte(frontMatter), &note); err != nil {
+		return Note{}, fmt.Errorf("%w: %w", ErrInvalidNote, err)
+	}
+	body = strings.TrimPrefix(body, "\n")
+	note.Body = strings.TrimSuffix(body, "\n")
+	return note, nil
+}
@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Marshal(t *testing.T) {
+	note := Note{
+		ID:       "3f2b",
+		Category: "work",
+		Created:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Body:     "# Time reporting\n\nDo it on Fridays.",
+	}
+
+	got, err := Marshal(note)
+	require.NoError(t, err)
+	require.Equal(t, "---\nid: 3f2b\ncategory: work\ncreated: 2024-01-02T03:04:05Z\n---\n\n# Time reporting\n\nDo it on Fridays.\n", string(got))
+
+	parsed, err := Unmarshal(got)
+	require.NoError(t, err)
+	require.Equal(t, note, parsed)
+}
+
+func Test_Unmarshal(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Note
+		wantErr error
+	}{
+		{
+			name: "Unmarshal() - with tags",
+			data: "---\nid: \"1\"\ncategory: work\ncreated: 2024-01-02T03:04:05Z\ntags: [a, b]\n---\n\nnote\n",
+			want: Note{ID: "1", Category: "work", Created: created, Tags: []string{"a", "b"}, Body: "note"},
+		},
+		{
+			name: "Unmarshal() - edited on Windows without empty line",
+			data: "---\r\nid: \"1\"\r\n---\r\nfirst\r\nsecond",
+			want: Note{ID: "1", Body: "first\nsecond"},
+		},
+		{
+			name: "Unmarshal() - body with a thematic break",
+			data: "---\nid: \"1\"\n---\n\nabove\n---\nbelow\n",
+			want: Note{ID: "1", Body: "above\n---\nbelow"},
+		},
+		{
+			name: "Unmarshal() - only front-matter",
+			data: "---\nid: \"1\"\n---",
+			want: Note{ID: "1"},
+		},
+		{
+			name:    "Unmarshal() - no front-matter",
+			data:    "# note\n",
+			wantErr: ErrInvalidNote,
+		},
+		{
+			name:    "Unmarshal() - front-matter is not closed",
+			data:    "---\nid: \"1\"\n",
+			wantErr: ErrInvalidNote,
+		},
+		{
+			name:    "Unmarshal() - invalid front-matter",
+			data:    "---\nid: [\n---\n",
+			wantErr: ErrInvalidNote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Unmarshal([]byte(tt.data))
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
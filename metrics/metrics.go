@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// namespace is the prefix of all metric names.
+	namespace = "notes"
+)
+
+// Metrics holds the Prometheus metrics of the service.
+type Metrics struct {
+	registry         *prometheus.Registry
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	dbDuration       *prometheus.HistogramVec
+	dbErrors         *prometheus.CounterVec
+	dbRequestCharges *prometheus.CounterVec
+}
+
+// New returns new Metrics registered in a new registry, together with
+// the Go runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of HTTP requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		dbDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "operation_duration_seconds",
+			Help:      "Latency of database operations by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+		dbErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "operation_errors_total",
+			Help:      "Total number of failed database operations by operation and error.",
+		}, []string{"operation", "error"}),
+		dbRequestCharges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "request_charge_total",
+			Help:      "Total Cosmos DB request charge (RU) by operation.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.dbDuration,
+		m.dbErrors,
+		m.dbRequestCharges,
+	)
+	return m
+}
+
+// Handler returns an http.Handler that serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a served HTTP request.
+func (m *Metrics) ObserveHTTPRequest(method, route string, statusCode int, duration time.Duration) {
+	status := strconv.Itoa(statusCode)
+	m.httpRequests.WithLabelValues(method, route, status).Inc()
+	m.httpDuration.WithLabelValues(method, route, status).Observe(duration.Seconds())
+}
+
+// ObserveDBOperation records a database operation.
+func (m *Metrics) ObserveDBOperation(operation string, duration time.Duration, err error) {
+	m.dbDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		m.dbErrors.WithLabelValues(operation, errorLabel(err)).Inc()
+	}
+}
+
+// ObserveRequestCharge records the request charge of a Cosmos DB request.
+func (m *Metrics) ObserveRequestCharge(operation string, charge float32) {
+	m.dbRequestCharges.WithLabelValues(operation).Add(float64(charge))
+}
+
+// RegisterCircuitBreaker registers a gauge with the state of the circuit breaker.
+func (m *Metrics) RegisterCircuitBreaker(breaker *db.CircuitBreaker) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "circuit_breaker_state",
+		Help:      "State of the database circuit breaker (0 closed, 1 open, 2 half-open).",
+	}, func() float64 {
+		return float64(breaker.State())
+	}))
+}
+
+// RegisterCache registers the statistics of the cache.
+func (m *Metrics) RegisterCache(cache *db.Cache) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Total number of cache hits.",
+		}, func() float64 {
+			return float64(cache.Stats().Hits)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Total number of cache misses.",
+		}, func() float64 {
+			return float64(cache.Stats().Misses)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "evictions_total",
+			Help:      "Total number of entries evicted from the cache.",
+		}, func() float64 {
+			return float64(cache.Stats().Evictions)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Number of entries in the cache.",
+		}, func() float64 {
+			return float64(cache.Stats().Size)
+		}),
+	)
+}
+
+// errorLabel returns the label value for a database error.
+func errorLabel(err error) string {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, db.ErrAlreadyExists):
+		return "already_exists"
+	case errors.Is(err, db.ErrInvalidInput):
+		return "invalid_input"
+	case errors.Is(err, db.ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, db.ErrUnavailable):
+		return "unavailable"
+	}
+	return "internal"
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Metrics(t *testing.T) {
+	m := New()
+
+	m.ObserveHTTPRequest(http.MethodGet, "GET /notes/categories/{category}", http.StatusOK, 10*time.Millisecond)
+	m.ObserveHTTPRequest(http.MethodGet, "GET /notes/categories/{category}", http.StatusOK, 20*time.Millisecond)
+	m.ObserveDBOperation("GetNoteByID", 5*time.Millisecond, nil)
+	m.ObserveDBOperation("GetNoteByID", 5*time.Millisecond, fmt.Errorf("category test: %w", db.ErrNotFound))
+	m.ObserveDBOperation("CreateNote", 5*time.Millisecond, db.ErrCircuitOpen)
+	m.ObserveRequestCharge("ReadItem", 1)
+	m.ObserveRequestCharge("ReadItem", 2.5)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "GET /notes/categories/{category}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.dbErrors.WithLabelValues("GetNoteByID", "not_found")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.dbErrors.WithLabelValues("CreateNote", "circuit_open")))
+	assert.Equal(t, 3.5, testutil.ToFloat64(m.dbRequestCharges.WithLabelValues("ReadItem")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.dbDuration))
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `notes_http_request_duration_seconds_count{method="GET",route="GET /notes/categories/{category}",status="200"} 2`)
+	assert.Contains(t, string(body), `notes_db_request_charge_total{operation="ReadItem"} 3.5`)
+}
@@ -0,0 +1,238 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ImportMode is how an import handles the notes that already exist.
+type ImportMode string
+
+const (
+	// ImportCreate only creates notes. Notes that already exist fail.
+	ImportCreate ImportMode = "create"
+	// ImportUpsert creates the notes that do not exist and replaces the
+	// notes that do.
+	ImportUpsert ImportMode = "upsert"
+	// ImportSkipExisting creates the notes that do not exist and leaves
+	// the notes that do unchanged.
+	ImportSkipExisting ImportMode = "skip-existing"
+)
+
+// ArchivedNote is a note with its creation time, as it is exported and
+// imported.
+type ArchivedNote struct {
+	ID        string
+	Category  string
+	Note      string
+	CreatedAt time.Time
+}
+
+// ImportOptions contains the options of an import.
+type ImportOptions struct {
+	Mode ImportMode
+	// DryRun reports what the import would do without writing the notes.
+	DryRun bool
+}
+
+// ImportReport is the result of an import.
+type ImportReport struct {
+	Mode    ImportMode
+	DryRun  bool
+	Total   int
+	Created int
+	Updated int
+	Skipped int
+	Failed  int
+	Errors  []ImportError
+}
+
+// ImportError is the error of a note that failed to import. Index is the
+// position of the note in the import, starting from zero.
+type ImportError struct {
+	Index int
+	ID    string
+	Err   error
+}
+
+// Archive exports and imports the notes of all categories, with the
+// creation times that the notes service does not return.
+type Archive struct {
+	db    database
+	notes Service
+}
+
+// NewArchive returns a new Archive of the notes of the database. Notes are
+// imported with the notes service, so that imports are cached, published
+// and recorded like any other change.
+func NewArchive(db database, notes Service) (*Archive, error) {
+	if db == nil {
+		return nil, ErrDbRequired
+	}
+	if notes == nil {
+		return nil, ErrServiceRequired
+	}
+	return &Archive{db: db, notes: notes}, nil
+}
+
+// Export calls fn with every note, by category and oldest first within a
+// category. The notes of a category are read when the notes of the
+// previous category are exported, so that they are not all held in
+// memory. Export stops at the first error of fn.
+func (a *Archive) Export(ctx context.Context, fn func(note ArchivedNote) error) error {
+	ctx, span := tracer.Start(ctx, "notes.Export")
+	defer span.End()
+
+	categories, err := a.db.ListCategories(ctx)
+	if err != nil {
+		return recordError(span, checkError(err))
+	}
+	for _, category := range categories {
+		err := a.exportCategory(ctx, category, fn)
+		if errors.Is(err, ErrNotFound) {
+			// The notes of the category were deleted since the
+			// categories were listed.
+			continue
+		}
+		if err != nil {
+			return recordError(span, err)
+		}
+	}
+	return nil
+}
+
+// ExportCategory calls fn with every note of the category, oldest first.
+// It returns ErrNotFound if the category has no notes.
+func (a *Archive) ExportCategory(ctx context.Context, category string, fn func(note ArchivedNote) error) error {
+	ctx, span := tracer.Start(ctx, "notes.ExportCategory", trace.WithAttributes(attribute.String("note.category", category)))
+	defer span.End()
+
+	if err := a.exportCategory(ctx, category, fn); err != nil {
+		return recordError(span, err)
+	}
+	return nil
+}
+
+// exportCategory calls fn with every note of the category, oldest first.
+func (a *Archive) exportCategory(ctx context.Context, category string, fn func(note ArchivedNote) error) error {
+	notesDB, err := a.db.GetNotesByCategory(ctx, category)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return fmt.Errorf("category %s: %w", category, ErrNotFound)
+		}
+		return checkError(err)
+	}
+	slices.SortFunc(notesDB, func(x, y db.Note) int {
+		if c := x.CreatedAt.Compare(y.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(x.ID, y.ID)
+	})
+	for _, noteDB := range notesDB {
+		if err := fn(toArchivedNote(noteDB)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import writes the notes according to the mode of the options. Notes
+// that fail are reported and do not stop the import, unless the context
+// is done. Notes without an ID are created with a new ID, and notes
+// without a creation time are created at the current time. The notes are
+// written with the notes service.
+func (a *Archive) Import(ctx context.Context, notes []ArchivedNote, options ImportOptions) (ImportReport, error) {
+	ctx, span := tracer.Start(ctx, "notes.Import", trace.WithAttributes(
+		attribute.String("import.mode", string(options.Mode)),
+		attribute.Bool("import.dry_run", options.DryRun),
+		attribute.Int("notes.count", len(notes)),
+	))
+	defer span.End()
+
+	switch options.Mode {
+	case ImportCreate, ImportUpsert, ImportSkipExisting:
+	default:
+		return ImportReport{}, recordError(span, fmt.Errorf("%w: unknown import mode %q", ErrInvalidInput, options.Mode))
+	}
+
+	report := ImportReport{Mode: options.Mode, DryRun: options.DryRun, Total: len(notes)}
+	for i, note := range notes {
+		if err := ctx.Err(); err != nil {
+			return report, recordError(span, checkError(err))
+		}
+		if err := a.importNote(ctx, note, options, &report); err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Index: i, ID: note.ID, Err: err})
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("import.created", report.Created),
+		attribute.Int("import.updated", report.Updated),
+		attribute.Int("import.skipped", report.Skipped),
+		attribute.Int("import.failed", report.Failed),
+	)
+	return report, nil
+}
+
+// importNote writes the note and counts it in the report, unless it fails.
+func (a *Archive) importNote(ctx context.Context, note ArchivedNote, options ImportOptions, report *ImportReport) error {
+	if len(note.Category) == 0 {
+		return fmt.Errorf("%w: category is required", ErrInvalidInput)
+	}
+
+	exists := false
+	if len(note.ID) > 0 {
+		_, err := a.db.GetNoteByID(ctx, note.Category, note.ID)
+		switch {
+		case err == nil:
+			exists = true
+		case !errors.Is(err, db.ErrNotFound):
+			return checkError(err)
+		}
+	}
+
+	switch {
+	case exists && options.Mode == ImportCreate:
+		return fmt.Errorf("category %s, id %s: %w", note.Category, note.ID, ErrAlreadyExists)
+	case exists && options.Mode == ImportSkipExisting:
+		report.Skipped++
+		return nil
+	}
+
+	ctx = withCreatedAt(ctx, note.CreatedAt)
+	imported := Note{ID: note.ID, Category: note.Category, Note: note.Note}
+	if exists {
+		if !options.DryRun {
+			if _, err := a.notes.UpdateNote(ctx, imported); err != nil {
+				return err
+			}
+		}
+		report.Updated++
+		return nil
+	}
+
+	if !options.DryRun {
+		if _, err := a.notes.CreateNote(ctx, imported); err != nil {
+			return err
+		}
+	}
+	report.Created++
+	return nil
+}
+
+func toArchivedNote(noteDB db.Note) ArchivedNote {
+	return ArchivedNote{
+		ID:        noteDB.ID,
+		Category:  noteDB.Category,
+		Note:      noteDB.Note,
+		CreatedAt: noteDB.CreatedAt,
+	}
+}
@@ -0,0 +1,265 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Archive_Export(t *testing.T) {
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	database := newMemoryDB(
+		db.Note{ID: "2", Category: "work", Note: "second", CreatedAt: second},
+		db.Note{ID: "1", Category: "work", Note: "first", CreatedAt: first},
+		db.Note{ID: "3", Category: "home", Note: "home", CreatedAt: first},
+	)
+	archive := newArchive(t, database)
+
+	var got []ArchivedNote
+	err := archive.Export(context.Background(), func(note ArchivedNote) error {
+		got = append(got, note)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []ArchivedNote{
+		{ID: "3", Category: "home", Note: "home", CreatedAt: first},
+		{ID: "1", Category: "work", Note: "first", CreatedAt: first},
+		{ID: "2", Category: "work", Note: "second", CreatedAt: second},
+	}, got)
+
+	errStop := errors.New("stop")
+	err = archive.Export(context.Background(), func(note ArchivedNote) error {
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+}
+
+func Test_Archive_ExportCategory(t *testing.T) {
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	database := newMemoryDB(
+		db.Note{ID: "2", Category: "work", Note: "second", CreatedAt: first.Add(time.Hour)},
+		db.Note{ID: "3", Category: "home", Note: "home", CreatedAt: first},
+		db.Note{ID: "1", Category: "work", Note: "first", CreatedAt: first},
+	)
+	archive := newArchive(t, database)
+
+	var got []string
+	err := archive.ExportCategory(context.Background(), "work", func(note ArchivedNote) error {
+		got = append(got, note.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"1", "2"}, got)
+
+	err = archive.ExportCategory(context.Background(), "other", func(note ArchivedNote) error {
+		return nil
+	})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_Archive_Import(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	existing := db.Note{ID: "1", Category: "work", Note: "existing", CreatedAt: createdAt}
+	input := []ArchivedNote{
+		{ID: "1", Category: "work", Note: "imported", CreatedAt: createdAt},
+		{ID: "2", Category: "work", Note: "new", CreatedAt: createdAt},
+		{ID: "3", Note: "no category"},
+	}
+
+	tests := []struct {
+		name       string
+		options    ImportOptions
+		wantReport ImportReport
+		wantNotes  []db.Note
+		wantErr    error
+	}{
+		{
+			name:    "Import() - create only",
+			options: ImportOptions{Mode: ImportCreate},
+			wantReport: ImportReport{
+				Mode: ImportCreate, Total: 3, Created: 1, Failed: 2,
+				Errors: []ImportError{{Index: 0, ID: "1", Err: ErrAlreadyExists}, {Index: 2, ID: "3", Err: ErrInvalidInput}},
+			},
+			wantNotes: []db.Note{existing, {ID: "2", Category: "work", Note: "new", CreatedAt: createdAt}},
+		},
+		{
+			name:    "Import() - upsert",
+			options: ImportOptions{Mode: ImportUpsert},
+			wantReport: ImportReport{
+				Mode: ImportUpsert, Total: 3, Created: 1, Updated: 1, Failed: 1,
+				Errors: []ImportError{{Index: 2, ID: "3", Err: ErrInvalidInput}},
+			},
+			wantNotes: []db.Note{
+				{ID: "1", Category: "work", Note: "imported", CreatedAt: createdAt},
+				{ID: "2", Category: "work", Note: "new", CreatedAt: createdAt},
+			},
+		},
+		{
+			name:    "Import() - skip existing",
+			options: ImportOptions{Mode: ImportSkipExisting},
+			wantReport: ImportReport{
+				Mode: ImportSkipExisting, Total: 3, Created: 1, Skipped: 1, Failed: 1,
+				Errors: []ImportError{{Index: 2, ID: "3", Err: ErrInvalidInput}},
+			},
+			wantNotes: []db.Note{existing, {ID: "2", Category: "work", Note: "new", CreatedAt: createdAt}},
+		},
+		{
+			name:    "Import() - dry run does not write",
+			options: ImportOptions{Mode: ImportUpsert, DryRun: true},
+			wantReport: ImportReport{
+				Mode: ImportUpsert, DryRun: true, Total: 3, Created: 1, Updated: 1, Failed: 1,
+				Errors: []ImportError{{Index: 2, ID: "3", Err: ErrInvalidInput}},
+			},
+			wantNotes: []db.Note{existing},
+		},
+		{
+			name:    "Import() - unknown mode",
+			options: ImportOptions{Mode: "merge"},
+			wantErr: ErrInvalidInput,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			database := newMemoryDB(existing)
+			archive := newArchive(t, database)
+
+			// Act
+			report, err := archive.Import(context.Background(), input, tt.options)
+
+			// Assert
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+			// The errors are compared by their kind.
+			require.Len(t, report.Errors, len(tt.wantReport.Errors))
+			for i, want := range tt.wantReport.Errors {
+				require.Equal(t, want.Index, report.Errors[i].Index)
+				require.Equal(t, want.ID, report.Errors[i].ID)
+				require.ErrorIs(t, report.Errors[i].Err, want.Err)
+			}
+			report.Errors, tt.wantReport.Errors = nil, nil
+			require.Equal(t, tt.wantReport, report)
+			require.Equal(t, tt.wantNotes, database.all())
+		})
+	}
+}
+
+func Test_Archive_Import_publishesEvents(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	broker := NewBroker()
+	sub := broker.Subscribe("", 0)
+	database := newMemoryDB(db.Note{ID: "1", Category: "work", Note: "existing", CreatedAt: createdAt})
+	service, err := NewService(database, &mockLogger{}, func(o *ServiceOptions) {
+		o.Broker = broker
+	})
+	require.NoError(t, err)
+	archive, err := NewArchive(database, service)
+	require.NoError(t, err)
+
+	_, err = archive.Import(context.Background(), []ArchivedNote{
+		{ID: "1", Category: "work", Note: "imported", CreatedAt: createdAt},
+		{ID: "2", Category: "work", Note: "new", CreatedAt: createdAt},
+	}, ImportOptions{Mode: ImportUpsert})
+	require.NoError(t, err)
+
+	require.Equal(t, Event{ID: 1, Type: EventUpdated, Note: Note{ID: "1", Category: "work", Note: "imported"}}, <-sub.Events())
+	require.Equal(t, Event{ID: 2, Type: EventCreated, Note: Note{ID: "2", Category: "work", Note: "new"}}, <-sub.Events())
+}
+
+func Test_NewArchive(t *testing.T) {
+	service, err := NewService(newMemoryDB(), &mockLogger{})
+	require.NoError(t, err)
+
+	_, err = NewArchive(nil, service)
+	require.ErrorIs(t, err, ErrDbRequired)
+	_, err = NewArchive(newMemoryDB(), nil)
+	require.ErrorIs(t, err, ErrServiceRequired)
+}
+
+// newArchive returns an Archive of the database, which imports the notes
+// with a service of the database.
+func newArchive(t *testing.T, database *memoryDB) *Archive {
+	t.Helper()
+	service, err := NewService(database, &mockLogger{})
+	require.NoError(t, err)
+	archive, err := NewArchive(database, service)
+	require.NoError(t, err)
+	return archive
+}
+
+// memoryDB keeps the notes in memory, in the order they were created.
+type memoryDB struct {
+	database
+	notes []db.Note
+}
+
+func newMemoryDB(notes ...db.Note) *memoryDB {
+	return &memoryDB{notes: notes}
+}
+
+func (d *memoryDB) all() []db.Note {
+	return d.notes
+}
+
+func (d *memoryDB) CreateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	if _, err := d.GetNoteByID(ctx, note.Category, note.ID); err == nil {
+		return db.Note{}, db.ErrAlreadyExists
+	}
+	d.notes = append(d.notes, note)
+	return note, nil
+}
+
+func (d *memoryDB) UpdateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	for i, n := range d.notes {
+		if n.ID == note.ID && n.Category == note.Category {
+			if note.CreatedAt.IsZero() {
+				note.CreatedAt = n.CreatedAt
+			}
+			d.notes[i] = note
+			return note, nil
+		}
+	}
+	return db.Note{}, db.ErrNotFound
+}
+
+func (d *memoryDB) GetNotesByCategory(ctx context.Context, category string) ([]db.Note, error) {
+	var notes []db.Note
+	for _, note := range d.notes {
+		if note.Category == category {
+			notes = append(notes, note)
+		}
+	}
+	if len(notes) == 0 {
+		return nil, db.ErrNotFound
+	}
+	return notes, nil
+}
+
+func (d *memoryDB) ListCategories(ctx context.Context) ([]string, error) {
+	var categories []string
+	for _, note := range d.notes {
+		if !slices.Contains(categories, note.Category) {
+			categories = append(categories, note.Category)
+		}
+	}
+	slices.Sort(categories)
+	return categories, nil
+}
+
+func (d *memoryDB) GetNoteByID(ctx context.Context, category, id string) (db.Note, error) {
+	for _, note := range d.notes {
+		if note.ID == id && note.Category == category {
+			return note, nil
+		}
+	}
+	return db.Note{}, db.ErrNotFound
+}
@@ -0,0 +1,212 @@
+package notes
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+const (
+	// defaultReplaySize is the default number of events kept for replay.
+	defaultReplaySize = 1000
+	// defaultSubscriberBuffer is the default number of events buffered
+	// for a subscriber.
+	defaultSubscriberBuffer = 64
+)
+
+// EventType is the type of change of a note.
+type EventType string
+
+const (
+	// EventCreated is published when a note is created.
+	EventCreated EventType = "created"
+	// EventUpdated is published when a note is updated.
+	EventUpdated EventType = "updated"
+	// EventDeleted is published when a note is deleted. The note of the
+	// event only has its ID and category.
+	EventDeleted EventType = "deleted"
+)
+
+// Event is a change of a note. The IDs of the events of a broker increase
+// by one with every published event, also for the events relayed from the
+// outbox, so that subscribers can resume after the last event they
+// received.
+type Event struct {
+	ID   uint64
+	Type EventType
+	Note Note
+}
+
+// Broker publishes the events of the service to its subscribers. The
+// latest events are kept in a bounded replay buffer, so that subscribers
+// can resume after the last event they received.
+type Broker struct {
+	mu               sync.Mutex
+	lastID           uint64
+	replay           []Event
+	replaySize       int
+	subscriberBuffer int
+	subscribers      map[*Subscription]struct{}
+	// evictedID is the ID of the latest event that was evicted from the
+	// replay buffer.
+	evictedID uint64
+	// delivered contains the IDs of the latest delivered events, as they
+	// were numbered by the outbox, to ignore the events that are delivered
+	// again.
+	delivered []uint64
+}
+
+// BrokerOptions contains options for the Broker.
+type BrokerOptions struct {
+	// ReplaySize is the number of events kept for replay.
+	ReplaySize int
+	// SubscriberBuffer is the number of events buffered for a subscriber.
+	// Subscribers that fall further behind are closed, and can resume
+	// from the replay buffer.
+	SubscriberBuffer int
+}
+
+// BrokerOption is a function that sets options on the Broker.
+type BrokerOption func(o *BrokerOptions)
+
+// NewBroker returns a new Broker.
+func NewBroker(options ...BrokerOption) *Broker {
+	opts := BrokerOptions{
+		ReplaySize:       defaultReplaySize,
+		SubscriberBuffer: defaultSubscriberBuffer,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return &Broker{
+		replaySize:       opts.ReplaySize,
+		subscriberBuffer: opts.SubscriberBuffer,
+		subscribers:      make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription is a subscription to the events of a category.
+type Subscription struct {
+	category string
+	events   chan Event
+	// Replay contains the events after the last event ID of the
+	// subscriber, to be handled before the events of the channel.
+	Replay []Event
+	// Missed is set when some events after the last event ID of the
+	// subscriber are no longer in the replay buffer.
+	Missed bool
+}
+
+// Events returns the channel of the events. The channel is closed when
+// the subscriber falls too far behind, or it is unsubscribed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Publish publishes an event of the note to the subscribers of its
+// category and returns it.
+func (b *Broker) Publish(eventType EventType, note Note) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Note: note}
+	b.publish(event)
+	return event
+}
+
+// Deliver publishes the event, so that the broker can be a sink of the
+// OutboxRelay. The ID of the event is derived from its outbox record, and
+// only used to ignore an event that is delivered again. The event is
+// published with the next ID of the broker instead, since the relay can
+// deliver an event after events with greater IDs, for example an event
+// whose write committed late, and subscribers resume after the greatest
+// ID they received.
+func (b *Broker) Deliver(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if slices.Contains(b.delivered, event.ID) {
+		return nil
+	}
+	b.delivered = append(b.delivered, event.ID)
+	if len(b.delivered) > b.replaySize {
+		b.delivered = b.delivered[len(b.delivered)-b.replaySize:]
+	}
+
+	b.lastID++
+	event.ID = b.lastID
+	b.publish(event)
+	return nil
+}
+
+// publish adds the event to the replay buffer and sends it to the
+// subscribers of its category. It must be called with the lock held.
+func (b *Broker) publish(event Event) {
+	b.replay = append(b.replay, event)
+	if len(b.replay) > b.replaySize {
+		for _, evicted := range b.replay[:len(b.replay)-b.replaySize] {
+			b.evictedID = max(b.evictedID, evicted.ID)
+		}
+		b.replay = b.replay[len(b.replay)-b.replaySize:]
+	}
+
+	for sub := range b.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// The subscriber is too slow, it resumes from the replay
+			// buffer when it subscribes again.
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe subscribes to the events of the category, or of all
+// categories if it is empty. If lastEventID is not zero, the events after
+// it that are in the replay buffer are returned in the Replay of the
+// subscription.
+func (b *Broker) Subscribe(category string, lastEventID uint64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{
+		category: category,
+		events:   make(chan Event, b.subscriberBuffer),
+	}
+	if lastEventID > 0 {
+		sub.Missed = lastEventID > b.lastID || lastEventID < b.evictedID
+		for _, event := range b.replay {
+			if event.ID > lastEventID && sub.matches(event) {
+				sub.Replay = append(sub.Replay, event)
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes the subscription and closes its channel.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove removes the subscription if it is subscribed. It must be called
+// with the lock held.
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// matches reports whether the event is of the category of the subscription.
+func (s *Subscription) matches(event Event) bool {
+	return len(s.category) == 0 || s.category == event.Note.Category
+}
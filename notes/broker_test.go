@@ -0,0 +1,170 @@
+package notes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Broker_Subscribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		category    string
+		lastEventID uint64
+		wantReplay  []uint64
+		wantMissed  bool
+	}{
+		{
+			name:     "Subscribe() - no replay without last event ID",
+			category: "work",
+		},
+		{
+			name:        "Subscribe() - replays the events of the category",
+			category:    "work",
+			lastEventID: 2,
+			wantReplay:  []uint64{3, 5},
+		},
+		{
+			name:        "Subscribe() - replays the events of all categories",
+			lastEventID: 3,
+			wantReplay:  []uint64{4, 5},
+		},
+		{
+			name:        "Subscribe() - events are missed before the replay buffer",
+			category:    "work",
+			lastEventID: 1,
+			wantReplay:  []uint64{3, 5},
+			wantMissed:  true,
+		},
+		{
+			name:        "Subscribe() - events are missed after a restart",
+			category:    "work",
+			lastEventID: 10,
+			wantMissed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			broker := NewBroker(func(o *BrokerOptions) {
+				o.ReplaySize = 3
+			})
+			for _, category := range []string{"work", "home", "work", "home", "work"} {
+				broker.Publish(EventCreated, Note{Category: category})
+			}
+
+			// Act
+			sub := broker.Subscribe(tt.category, tt.lastEventID)
+
+			// Assert
+			var replay []uint64
+			for _, event := range sub.Replay {
+				replay = append(replay, event.ID)
+			}
+			require.Equal(t, tt.wantReplay, replay)
+			require.Equal(t, tt.wantMissed, sub.Missed)
+		})
+	}
+}
+
+func Test_Broker_Publish(t *testing.T) {
+	broker := NewBroker(func(o *BrokerOptions) {
+		o.SubscriberBuffer = 1
+	})
+	work := broker.Subscribe("work", 0)
+	home := broker.Subscribe("home", 0)
+
+	broker.Publish(EventCreated, Note{ID: "1", Category: "work"})
+	require.Equal(t, Event{ID: 1, Type: EventCreated, Note: Note{ID: "1", Category: "work"}}, <-work.Events())
+	require.Empty(t, home.Events())
+
+	// A subscriber that falls behind is closed.
+	broker.Publish(EventUpdated, Note{ID: "1", Category: "work"})
+	broker.Publish(EventDeleted, Note{ID: "1", Category: "work"})
+	require.Equal(t, uint64(2), (<-work.Events()).ID)
+	_, ok := <-work.Events()
+	require.False(t, ok)
+
+	broker.Unsubscribe(home)
+	_, ok = <-home.Events()
+	require.False(t, ok)
+}
+
+func Test_Broker_Deliver(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe("", 0)
+
+	event := Event{ID: 5, Type: EventCreated, Note: Note{ID: "1", Category: "work"}}
+	require.NoError(t, broker.Deliver(context.Background(), event))
+	// An event that was already delivered is ignored.
+	require.NoError(t, broker.Deliver(context.Background(), event))
+	first := Event{ID: 1, Type: EventCreated, Note: Note{ID: "1", Category: "work"}}
+	require.Equal(t, first, <-sub.Events())
+	require.Empty(t, sub.Events())
+
+	// An event with a lower ID, whose write committed late, is published
+	// after the events that were delivered before it.
+	late := Event{ID: 3, Type: EventUpdated, Note: Note{ID: "2", Category: "work"}}
+	require.NoError(t, broker.Deliver(context.Background(), late))
+	second := Event{ID: 2, Type: EventUpdated, Note: Note{ID: "2", Category: "work"}}
+	require.Equal(t, second, <-sub.Events())
+
+	// A subscriber that resumes after the first event receives the late
+	// event.
+	resumed := broker.Subscribe("", first.ID)
+	require.False(t, resumed.Missed)
+	require.Equal(t, []Event{second}, resumed.Replay)
+}
+
+func Test_Broker_Deliver_evicted(t *testing.T) {
+	broker := NewBroker(func(o *BrokerOptions) {
+		o.ReplaySize = 1
+	})
+	require.NoError(t, broker.Deliver(context.Background(), Event{ID: 5, Type: EventCreated, Note: Note{ID: "1", Category: "work"}}))
+	require.NoError(t, broker.Deliver(context.Background(), Event{ID: 6, Type: EventCreated, Note: Note{ID: "2", Category: "work"}}))
+
+	// An event with a lower ID than the evicted events is not dropped.
+	sub := broker.Subscribe("", 0)
+	require.NoError(t, broker.Deliver(context.Background(), Event{ID: 1, Type: EventCreated, Note: Note{ID: "3", Category: "work"}}))
+	require.Equal(t, Event{ID: 3, Type: EventCreated, Note: Note{ID: "3", Category: "work"}}, <-sub.Events())
+}
+
+func Test_service_publishesEvents(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe("", 0)
+	svc, err := NewService(&echoDB{}, &mockLogger{}, func(o *ServiceOptions) {
+		o.Broker = broker
+	})
+	require.NoError(t, err)
+
+	note := Note{ID: "1", Category: "work", Note: "note"}
+	_, err = svc.CreateNote(context.Background(), note)
+	require.NoError(t, err)
+	_, err = svc.UpdateNote(context.Background(), note)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteNote(context.Background(), note))
+
+	require.Equal(t, Event{ID: 1, Type: EventCreated, Note: note}, <-sub.Events())
+	require.Equal(t, Event{ID: 2, Type: EventUpdated, Note: note}, <-sub.Events())
+	require.Equal(t, Event{ID: 3, Type: EventDeleted, Note: Note{ID: "1", Category: "work"}}, <-sub.Events())
+}
+
+// echoDB returns the notes it is called with.
+type echoDB struct {
+	database
+}
+
+func (d *echoDB) CreateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	return note, nil
+}
+
+func (d *echoDB) UpdateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	return note, nil
+}
+
+func (d *echoDB) DeleteNote(ctx context.Context, id, category string) error {
+	return nil
+}
@@ -1,6 +1,7 @@
 package notes
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -15,6 +16,12 @@ var (
 	ErrLoggerRequired = errors.New("logger is not provided")
 )
 
+// NewArchive and NewHistory errors.
+var (
+	// ErrServiceRequired is returned when the notes service is not provided.
+	ErrServiceRequired = errors.New("notes service is not provided")
+)
+
 var (
 	// ErrService is returned when the service fails.
 	ErrService = errors.New("service error")
@@ -27,6 +34,13 @@ var (
 	// ErrIDNotFound = errors.New("id not found")
 	// ErrAlreadyExists is returned when the resource already exists.
 	ErrAlreadyExists = errors.New("already exists")
+	// ErrUnavailable is returned when the service is temporarily unavailable.
+	ErrUnavailable = errors.New("service is unavailable")
+	// ErrTimeout is returned when the operation did not complete before
+	// its deadline.
+	ErrTimeout = errors.New("operation timed out")
+	// ErrCanceled is returned when the operation was canceled by the caller.
+	ErrCanceled = errors.New("operation canceled")
 )
 
 // checkError checks and returns the appropriate error.
@@ -41,6 +55,15 @@ func checkError(err error) error {
 		if errors.Is(err, db.ErrAlreadyExists) {
 			return ErrAlreadyExists
 		}
+		if errors.Is(err, db.ErrUnavailable) {
+			return ErrUnavailable
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return ErrTimeout
+		}
+		if errors.Is(err, context.Canceled) {
+			return ErrCanceled
+		}
 		return fmt.Errorf("%w: %w", ErrService, err)
 	}
 	return fmt.Errorf("%w: %w", ErrService, err)
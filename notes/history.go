@@ -0,0 +1,121 @@
+package notes
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shortCommitLength is the length of the commits in restore messages.
+const shortCommitLength = 12
+
+// historyDB is the interface that wraps around the methods of a database
+// that keeps the history of the notes.
+type historyDB interface {
+	// History returns the revisions of the note, newest first.
+	History(ctx context.Context, category, id string) ([]db.Revision, error)
+	// Revision returns the note as it was after the commit.
+	Revision(ctx context.Context, category, id, commit string) (db.Note, error)
+}
+
+// Author is the author of a change of a note.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// WithAuthor returns a copy of the context with the author of the
+// changes made with it. The author is recorded by the databases that
+// keep a history of the notes.
+func WithAuthor(ctx context.Context, author Author) context.Context {
+	return db.WithAuthor(ctx, db.Author(author))
+}
+
+// Revision is a change of a note. The note is empty if the change
+// deleted it.
+type Revision struct {
+	Commit  string
+	Author  Author
+	Time    time.Time
+	Message string
+	Deleted bool
+	Note    Note
+}
+
+// History reads the history of the notes, and restores the notes as they
+// were in it.
+type History struct {
+	db    historyDB
+	notes Service
+}
+
+// NewHistory returns a new History on the database. Notes are restored
+// with the notes service, so that restores are cached and published
+// like any other change.
+func NewHistory(db historyDB, notes Service) (*History, error) {
+	if db == nil {
+		return nil, ErrDbRequired
+	}
+	if notes == nil {
+		return nil, ErrServiceRequired
+	}
+	return &History{db: db, notes: notes}, nil
+}
+
+// Revisions returns the changes of the note, newest first.
+func (h *History) Revisions(ctx context.Context, category, id string) ([]Revision, error) {
+	ctx, span := tracer.Start(ctx, "notes.Revisions", trace.WithAttributes(noteAttributes(category, id)...))
+	defer span.End()
+
+	revisionsDB, err := h.db.History(ctx, category, id)
+	if err != nil {
+		return []Revision{}, recordError(span, checkError(err))
+	}
+
+	revisions := make([]Revision, 0, len(revisionsDB))
+	for _, revision := range revisionsDB {
+		revisions = append(revisions, Revision{
+			Commit:  revision.Commit,
+			Author:  Author(revision.Author),
+			Time:    revision.Time,
+			Message: revision.Message,
+			Deleted: revision.Deleted,
+			Note:    fromNoteDB(revision.Note),
+		})
+	}
+	return revisions, nil
+}
+
+// Restore writes the note as it was after the commit. A note that was
+// deleted since is created again.
+func (h *History) Restore(ctx context.Context, category, id, commit string) (Note, error) {
+	ctx, span := tracer.Start(ctx, "notes.Restore", trace.WithAttributes(noteAttributes(category, id)...))
+	defer span.End()
+
+	revision, err := h.db.Revision(ctx, category, id, commit)
+	if err != nil {
+		return Note{}, recordError(span, checkError(err))
+	}
+
+	short := commit
+	if len(short) > shortCommitLength {
+		short = short[:shortCommitLength]
+	}
+	ctx = db.WithChangeMessage(ctx, "Restore note "+category+"/"+id+" to "+short)
+	// The note is restored with its creation time, also when it is
+	// created again after it was deleted.
+	ctx = withCreatedAt(ctx, revision.CreatedAt)
+
+	note := Note{ID: id, Category: category, Note: revision.Note}
+	restored, err := h.notes.UpdateNote(ctx, note)
+	if errors.Is(err, ErrNotFound) {
+		restored, err = h.notes.CreateNote(ctx, note)
+	}
+	if err != nil {
+		return Note{}, recordError(span, err)
+	}
+	return restored, nil
+}
@@ -0,0 +1,137 @@
+package notes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_History_Revisions(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	database := &stubHistoryDB{revisions: []db.Revision{
+		{Commit: "b", Author: db.Author{Name: "Bob"}, Time: created.Add(time.Hour), Message: "Delete note work/1.md", Deleted: true},
+		{Commit: "a", Author: db.Author{Name: "Alice", Email: "alice@example.com"}, Time: created, Message: "Create note work/1.md", Note: db.Note{ID: "1", Category: "work", Note: "first", CreatedAt: created}},
+	}}
+	service, err := NewService(newMemoryDB(), &mockLogger{})
+	require.NoError(t, err)
+	history, err := NewHistory(database, service)
+	require.NoError(t, err)
+
+	got, err := history.Revisions(context.Background(), "work", "1")
+	require.NoError(t, err)
+	require.Equal(t, []Revision{
+		{Commit: "b", Author: Author{Name: "Bob"}, Time: created.Add(time.Hour), Message: "Delete note work/1.md", Deleted: true},
+		{Commit: "a", Author: Author{Name: "Alice", Email: "alice@example.com"}, Time: created, Message: "Create note work/1.md", Note: Note{ID: "1", Category: "work", Note: "first"}},
+	}, got)
+
+	_, err = history.Revisions(context.Background(), "work", "2")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_History_Restore(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		name        string
+		notes       []db.Note
+		commit      string
+		wantNote    Note
+		wantMessage string
+		wantErr     error
+	}{
+		{
+			name:        "restore existing note",
+			notes:       []db.Note{{ID: "1", Category: "work", Note: "second"}},
+			commit:      "0123456789abcdef",
+			wantNote:    Note{ID: "1", Category: "work", Note: "first"},
+			wantMessage: "Restore note work/1 to 0123456789ab",
+		},
+		{
+			name:        "restore deleted note",
+			commit:      "0123",
+			wantNote:    Note{ID: "1", Category: "work", Note: "first"},
+			wantMessage: "Restore note work/1 to 0123",
+		},
+		{
+			name:    "unknown commit",
+			commit:  "fff",
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			database := &messageDB{memoryDB: newMemoryDB(test.notes...)}
+			service, err := NewService(database, &mockLogger{})
+			require.NoError(t, err)
+			history, err := NewHistory(&stubHistoryDB{revisions: []db.Revision{
+				{Commit: "0123456789abcdef", Note: db.Note{ID: "1", Category: "work", Note: "first", CreatedAt: created}},
+				{Commit: "0123", Note: db.Note{ID: "1", Category: "work", Note: "first", CreatedAt: created}},
+			}}, service)
+			require.NoError(t, err)
+
+			got, err := history.Restore(context.Background(), "work", "1", test.commit)
+			require.ErrorIs(t, err, test.wantErr)
+			require.Equal(t, test.wantNote, got)
+			require.Equal(t, test.wantMessage, database.message)
+			if test.wantErr == nil {
+				note, err := service.GetNoteByID(context.Background(), "work", "1")
+				require.NoError(t, err)
+				require.Equal(t, test.wantNote, note)
+
+				noteDB, err := database.GetNoteByID(context.Background(), "work", "1")
+				require.NoError(t, err)
+				require.Equal(t, created, noteDB.CreatedAt)
+			}
+		})
+	}
+}
+
+func Test_NewHistory(t *testing.T) {
+	service, err := NewService(newMemoryDB(), &mockLogger{})
+	require.NoError(t, err)
+
+	_, err = NewHistory(nil, service)
+	require.ErrorIs(t, err, ErrDbRequired)
+	_, err = NewHistory(&stubHistoryDB{}, nil)
+	require.ErrorIs(t, err, ErrServiceRequired)
+}
+
+type stubHistoryDB struct {
+	revisions []db.Revision
+}
+
+func (d *stubHistoryDB) History(ctx context.Context, category, id string) ([]db.Revision, error) {
+	// The stub only has the history of a single note.
+	if category != "work" || id != "1" {
+		return nil, db.ErrNotFound
+	}
+	return d.revisions, nil
+}
+
+func (d *stubHistoryDB) Revision(ctx context.Context, category, id, commit string) (db.Note, error) {
+	for _, revision := range d.revisions {
+		if revision.Commit == commit && !revision.Deleted {
+			return revision.Note, nil
+		}
+	}
+	return db.Note{}, db.ErrNotFound
+}
+
+// messageDB records the change message of the last write.
+type messageDB struct {
+	*memoryDB
+	message string
+}
+
+func (d *messageDB) CreateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	d.message, _ = db.ChangeMessageFromContext(ctx)
+	return d.memoryDB.CreateNote(ctx, note)
+}
+
+func (d *messageDB) UpdateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	d.message, _ = db.ChangeMessageFromContext(ctx)
+	return d.memoryDB.UpdateNote(ctx, note)
+}
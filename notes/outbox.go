@@ -0,0 +1,253 @@
+package notes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultOutboxPollInterval is the default interval between the
+	// reads of the pending outbox records.
+	defaultOutboxPollInterval = time.Second
+	// defaultOutboxClaimTTL is the default time an outbox record is
+	// claimed for by the relay that delivers its event.
+	defaultOutboxClaimTTL = 30 * time.Second
+)
+
+// NewOutboxRelay errors.
+var (
+	// ErrOutboxRequired is returned when the outbox is not provided.
+	ErrOutboxRequired = errors.New("outbox is not provided")
+)
+
+// outbox is the interface that wraps around the methods to read, claim
+// and delete the pending outbox records.
+type outbox interface {
+	// PendingOutbox returns the outbox records that are not published
+	// yet, oldest first.
+	PendingOutbox(ctx context.Context) ([]db.OutboxRecord, error)
+	// ClaimOutbox claims an outbox record for the owner for the TTL. It
+	// returns db.ErrOutboxClaimed if the record is claimed by another
+	// owner.
+	ClaimOutbox(ctx context.Context, record db.OutboxRecord, owner string, ttl time.Duration) (db.OutboxRecord, error)
+	// DeleteOutbox deletes a published outbox record.
+	DeleteOutbox(ctx context.Context, record db.OutboxRecord) error
+}
+
+// EventSink receives the events relayed from the outbox. An event is
+// delivered again if Deliver returns an error, or if the process stops
+// before its outbox record is deleted.
+type EventSink interface {
+	Deliver(ctx context.Context, event Event) error
+}
+
+// relayedEvent is an event of an outbox record and the sinks it was
+// delivered to.
+type relayedEvent struct {
+	event     Event
+	delivered []bool
+}
+
+// OutboxRelay delivers the events of the outbox records to the sinks, and
+// deletes the records once every sink has received their event. Records
+// are relayed oldest first, and a failed delivery stops the relay until
+// the next poll, so that the events of a note are delivered in order.
+// Every record is claimed before its event is delivered, so that the
+// relays of several instances do not deliver the same event, and a relay
+// stops at a record claimed by another relay. Delivery is at-least-once:
+// sinks can receive an event more than once, for example when a claim
+// expires, always with the same event ID.
+type OutboxRelay struct {
+	outbox       outbox
+	sinks        []EventSink
+	log          logger
+	owner        string
+	pollInterval time.Duration
+	claimTTL     time.Duration
+
+	// pending are the events of the records that are not deleted yet. It
+	// is only used by Run.
+	pending map[string]*relayedEvent
+}
+
+// OutboxRelayOptions contains options for the OutboxRelay.
+type OutboxRelayOptions struct {
+	// Sinks receive the events of the outbox records.
+	Sinks []EventSink
+	// PollInterval is the interval between the reads of the pending
+	// outbox records.
+	PollInterval time.Duration
+	// Owner identifies the relay in the claims of the outbox records. A
+	// random ID is used if it is empty.
+	Owner string
+	// ClaimTTL is the time an outbox record is claimed for. It should be
+	// longer than the delivery of an event to the sinks.
+	ClaimTTL time.Duration
+}
+
+// OutboxRelayOption is a function that sets options on the OutboxRelay.
+type OutboxRelayOption func(o *OutboxRelayOptions)
+
+// NewOutboxRelay returns a new OutboxRelay of the outbox.
+func NewOutboxRelay(outbox outbox, logger logger, options ...OutboxRelayOption) (*OutboxRelay, error) {
+	if outbox == nil {
+		return nil, ErrOutboxRequired
+	}
+	if logger == nil {
+		return nil, ErrLoggerRequired
+	}
+
+	opts := OutboxRelayOptions{
+		PollInterval: defaultOutboxPollInterval,
+		ClaimTTL:     defaultOutboxClaimTTL,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if len(opts.Owner) == 0 {
+		opts.Owner = uuid.NewString()
+	}
+
+	return &OutboxRelay{
+		outbox:       outbox,
+		sinks:        opts.Sinks,
+		log:          logger,
+		owner:        opts.Owner,
+		pollInterval: opts.PollInterval,
+		claimTTL:     opts.ClaimTTL,
+		pending:      make(map[string]*relayedEvent),
+	}, nil
+}
+
+// Run relays the pending outbox records until the context is canceled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.relay(ctx); err != nil && ctx.Err() == nil {
+			r.log.ErrorContext(ctx, "Failed to relay the outbox.", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay delivers the events of the pending outbox records, and stops at
+// the first record that fails or is claimed by another relay.
+func (r *OutboxRelay) relay(ctx context.Context) error {
+	records, err := r.outbox.PendingOutbox(ctx)
+	if err != nil {
+		return fmt.Errorf("reading the outbox: %w", err)
+	}
+	for _, record := range records {
+		claimed, err := r.outbox.ClaimOutbox(ctx, record, r.owner, r.claimTTL)
+		switch {
+		case errors.Is(err, db.ErrOutboxClaimed):
+			// Another relay delivers the events, starting with this one.
+			return nil
+		case errors.Is(err, db.ErrNotFound):
+			// Another relay delivered the event since it was read.
+			delete(r.pending, record.ID)
+			continue
+		case err != nil:
+			return fmt.Errorf("outbox record %s: claiming: %w", record.ID, err)
+		}
+		if err := r.relayRecord(ctx, claimed); err != nil {
+			return fmt.Errorf("outbox record %s: %w", record.ID, err)
+		}
+	}
+	return nil
+}
+
+// relayRecord delivers the event of the record to the sinks that did not
+// receive it yet, and deletes the record.
+func (r *OutboxRelay) relayRecord(ctx context.Context, record db.OutboxRecord) error {
+	relayed, ok := r.pending[record.ID]
+	if !ok {
+		relayed = &relayedEvent{
+			event: Event{
+				ID:   record.EventID(),
+				Type: EventType(record.Event),
+				Note: fromNoteDB(record.Note),
+			},
+			delivered: make([]bool, len(r.sinks)),
+		}
+		r.pending[record.ID] = relayed
+	}
+
+	for i, sink := range r.sinks {
+		if relayed.delivered[i] {
+			continue
+		}
+		if err := sink.Deliver(ctx, relayed.event); err != nil {
+			return fmt.Errorf("delivering event %d: %w", relayed.event.ID, err)
+		}
+		relayed.delivered[i] = true
+	}
+
+	if err := r.outbox.DeleteOutbox(ctx, record); err != nil {
+		return fmt.Errorf("deleting: %w", err)
+	}
+	delete(r.pending, record.ID)
+	r.log.DebugContext(ctx, "Outbox record relayed.", "recordId", record.ID, "eventId", relayed.event.ID, "event", relayed.event.Type)
+	return nil
+}
+
+// fileEvent is an event written by the FileSink.
+type fileEvent struct {
+	ID        uint64    `json:"id"`
+	Event     EventType `json:"event"`
+	Note      Note      `json:"note"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FileSink appends the events to a file, one JSON object per line.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink returns a new FileSink that appends to the file at the path.
+// The file is created if it does not exist.
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: file}, nil
+}
+
+// Deliver appends the event to the file.
+func (s *FileSink) Deliver(ctx context.Context, event Event) error {
+	line, err := json.Marshal(fileEvent{
+		ID:        event.ID,
+		Event:     event.Type,
+		Note:      event.Note,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
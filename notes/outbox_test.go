@@ -0,0 +1,214 @@
+package notes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_OutboxRelay_relay(t *testing.T) {
+	// The event IDs are the creation times of the records in nanoseconds.
+	records := []db.OutboxRecord{
+		{ID: "r1", Category: "work", Event: db.OutboxEventCreated, Note: db.Note{ID: "1", Category: "work", Note: "note"}, CreatedAt: time.Unix(0, 1)},
+		{ID: "r2", Category: "work", Event: db.OutboxEventDeleted, Note: db.Note{ID: "1", Category: "work"}, CreatedAt: time.Unix(0, 2)},
+	}
+
+	tests := []struct {
+		name        string
+		failures    map[uint64]int
+		claims      map[string]string
+		deleted     []string
+		passes      int
+		wantEvents  []uint64
+		wantFlaky   []uint64
+		wantPending []string
+	}{
+		{
+			name:       "relay() - delivers the records in order and deletes them",
+			passes:     1,
+			wantEvents: []uint64{1, 2},
+			wantFlaky:  []uint64{1, 2},
+		},
+		{
+			name:        "relay() - stops at a failed delivery",
+			failures:    map[uint64]int{2: 1},
+			passes:      1,
+			wantEvents:  []uint64{1, 2},
+			wantFlaky:   []uint64{1},
+			wantPending: []string{"r2"},
+		},
+		{
+			name:     "relay() - retries the failed sink with the same event",
+			failures: map[uint64]int{1: 2},
+			passes:   3,
+			// The first sink received the event once, the failing sink
+			// receives it again with the same ID.
+			wantEvents: []uint64{1, 2},
+			wantFlaky:  []uint64{1, 2},
+		},
+		{
+			name:        "relay() - stops at a record claimed by another relay",
+			claims:      map[string]string{"r2": "other"},
+			passes:      1,
+			wantEvents:  []uint64{1},
+			wantFlaky:   []uint64{1},
+			wantPending: []string{"r2"},
+		},
+		{
+			name:       "relay() - skips a record deleted by another relay",
+			deleted:    []string{"r1"},
+			passes:     1,
+			wantEvents: []uint64{2},
+			wantFlaky:  []uint64{2},
+			// The outbox keeps returning the deleted record.
+			wantPending: []string{"r1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			outbox := &memoryOutbox{records: append([]db.OutboxRecord{}, records...), claims: tt.claims, deleted: tt.deleted}
+			sink := &recordingSink{}
+			flaky := &recordingSink{failures: tt.failures}
+			relay, err := NewOutboxRelay(outbox, &mockLogger{}, func(o *OutboxRelayOptions) {
+				o.Sinks = []EventSink{sink, flaky}
+			})
+			require.NoError(t, err)
+
+			// Act
+			for range tt.passes {
+				relay.relay(context.Background())
+			}
+
+			// Assert
+			require.Equal(t, tt.wantEvents, sink.ids())
+			require.Equal(t, tt.wantFlaky, flaky.ids())
+			var pending []string
+			for _, record := range outbox.records {
+				pending = append(pending, record.ID)
+			}
+			require.Equal(t, tt.wantPending, pending)
+		})
+	}
+}
+
+func Test_OutboxRelay_events(t *testing.T) {
+	outbox := &memoryOutbox{records: []db.OutboxRecord{
+		{ID: "r1", Category: "work", Event: db.OutboxEventUpdated, Note: db.Note{ID: "1", Category: "work", Note: "note"}, CreatedAt: time.Unix(0, 1)},
+	}}
+	sink := &recordingSink{}
+	relay, err := NewOutboxRelay(outbox, &mockLogger{}, func(o *OutboxRelayOptions) {
+		o.Sinks = []EventSink{sink}
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, relay.relay(context.Background()))
+	require.Equal(t, []Event{{ID: 1, Type: EventUpdated, Note: Note{ID: "1", Category: "work", Note: "note"}}}, sink.events)
+
+	outbox.err = errors.New("unavailable")
+	require.ErrorIs(t, relay.relay(context.Background()), outbox.err)
+}
+
+func Test_FileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	sink, err := NewFileSink(path)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Deliver(context.Background(), Event{ID: 1, Type: EventCreated, Note: Note{ID: "1", Category: "work", Note: "note"}}))
+	require.NoError(t, sink.Deliver(context.Background(), Event{ID: 2, Type: EventDeleted, Note: Note{ID: "1", Category: "work"}}))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var got []fileEvent
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event fileEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		require.WithinDuration(t, time.Now(), event.Timestamp, time.Minute)
+		event.Timestamp = time.Time{}
+		got = append(got, event)
+	}
+	require.Equal(t, []fileEvent{
+		{ID: 1, Event: EventCreated, Note: Note{ID: "1", Category: "work", Note: "note"}},
+		{ID: 2, Event: EventDeleted, Note: Note{ID: "1", Category: "work"}},
+	}, got)
+}
+
+// memoryOutbox keeps the outbox records in memory. Claims maps the IDs of
+// the records to their owners. The deleted records are returned by
+// PendingOutbox, like records that are deleted after they are read.
+type memoryOutbox struct {
+	records []db.OutboxRecord
+	claims  map[string]string
+	deleted []string
+	err     error
+}
+
+func (o *memoryOutbox) PendingOutbox(ctx context.Context) ([]db.OutboxRecord, error) {
+	if o.err != nil {
+		return nil, o.err
+	}
+	return append([]db.OutboxRecord{}, o.records...), nil
+}
+
+func (o *memoryOutbox) ClaimOutbox(ctx context.Context, record db.OutboxRecord, owner string, ttl time.Duration) (db.OutboxRecord, error) {
+	if slices.Contains(o.deleted, record.ID) {
+		return db.OutboxRecord{}, db.ErrNotFound
+	}
+	if claimedBy, ok := o.claims[record.ID]; ok && claimedBy != owner {
+		return db.OutboxRecord{}, db.ErrOutboxClaimed
+	}
+	if o.claims == nil {
+		o.claims = make(map[string]string)
+	}
+	o.claims[record.ID] = owner
+	record.ClaimedBy = owner
+	return record, nil
+}
+
+func (o *memoryOutbox) DeleteOutbox(ctx context.Context, record db.OutboxRecord) error {
+	for i, r := range o.records {
+		if r.ID == record.ID {
+			o.records = append(o.records[:i], o.records[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// recordingSink records the delivered events. The deliveries of an event
+// fail as many times as its failures.
+type recordingSink struct {
+	failures map[uint64]int
+	events   []Event
+}
+
+func (s *recordingSink) Deliver(ctx context.Context, event Event) error {
+	if s.failures[event.ID] > 0 {
+		s.failures[event.ID]--
+		return errors.New("delivery failed")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) ids() []uint64 {
+	var ids []uint64
+	for _, event := range s.events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
@@ -7,6 +7,10 @@ import (
 	"time"
 
 	"github.com/KatrinSalt/notes-service/db"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -14,11 +18,15 @@ const (
 	defaultServiceTimeout = 15 * time.Second
 )
 
-// logger is the interface that wraps around methods Debug, Info and Error.
+// tracer creates the spans of the service operations.
+var tracer = otel.Tracer("github.com/KatrinSalt/notes-service/notes")
+
+// logger is the interface that wraps around methods DebugContext,
+// InfoContext and ErrorContext. The context carries the request ID.
 type logger interface {
-	Debug(msg string, args ...any)
-	Info(msg string, args ...any)
-	Error(msg string, args ...any)
+	DebugContext(ctx context.Context, msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
 }
 
 type database interface {
@@ -30,35 +38,48 @@ type database interface {
 	DeleteNote(ctx context.Context, id, category string) error
 	// GetNotesByCategory returns a list of notes stored in DB.
 	GetNotesByCategory(ctx context.Context, category string) ([]db.Note, error)
+	// GetNotesByCategories returns the notes of the categories.
+	GetNotesByCategories(ctx context.Context, categories []string) ([]db.Note, error)
+	// ListCategories returns the categories that have notes.
+	ListCategories(ctx context.Context) ([]string, error)
 	// GetNoteByID returns a notes with id <id>.
 	GetNoteByID(ctx context.Context, category, id string) (db.Note, error)
 }
 
 type Service interface {
 	// CreateNote creates a new note.
-	CreateNote(note Note) (Note, error)
+	CreateNote(ctx context.Context, note Note) (Note, error)
 	// GetNoteByID returns a note by its ID.
 	// GetNoteByID(id string) (string, error)
 	// UpdateNote updates a note.
-	UpdateNote(note Note) (Note, error)
+	UpdateNote(ctx context.Context, note Note) (Note, error)
 	// DeleteNote deletes a note by its ID.
-	DeleteNote(note Note) error
+	DeleteNote(ctx context.Context, note Note) error
 	// GetNotesByCategory returns a list of notes stored in DB.
-	GetNotesByCategory(category string) ([]Note, error)
+	GetNotesByCategory(ctx context.Context, category string) ([]Note, error)
+	// GetNotesByCategories returns the notes of the categories, grouped by
+	// category. Categories without notes have an empty list.
+	GetNotesByCategories(ctx context.Context, categories []string) (map[string][]Note, error)
+	// ListCategories returns the categories that have notes.
+	ListCategories(ctx context.Context) ([]string, error)
 	// GetNoteByID returns a notes with id <id>.
-	GetNoteByID(category, id string) (Note, error)
+	GetNoteByID(ctx context.Context, category, id string) (Note, error)
 }
 
 type service struct {
 	db      database
 	log     logger
 	timeout time.Duration
+	events  *Broker
 }
 
 // ServiceOptions contains options for the service.
 type ServiceOptions struct {
 	Logger  logger
 	Timeout time.Duration
+	// Broker publishes the create, update and delete events of the
+	// service. Events are not published if it is nil.
+	Broker *Broker
 }
 
 // ServiceOption is a function that sets options on the service.
@@ -83,56 +104,82 @@ func NewService(db database, logger logger, options ...ServiceOption) (*service,
 		db:      db,
 		log:     logger,
 		timeout: opts.Timeout,
+		events:  opts.Broker,
 	}, nil
 }
 
-func (s service) CreateNote(note Note) (Note, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+func (s service) CreateNote(ctx context.Context, note Note) (Note, error) {
+	ctx, span := tracer.Start(ctx, "notes.CreateNote", trace.WithAttributes(attribute.String("note.category", note.Category)))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	noteDB, err := s.db.CreateNote(ctx, toNoteDB(note))
+	noteDB := toNoteDB(ctx, note)
+	if noteDB.CreatedAt.IsZero() {
+		noteDB.CreatedAt = time.Now().UTC()
+	}
+	noteDB, err := s.db.CreateNote(ctx, noteDB)
 	if err != nil {
-		return Note{}, checkError(err)
+		return Note{}, recordError(span, checkError(err))
 	}
+	span.SetAttributes(attribute.String("note.id", noteDB.ID))
 
-	return fromNoteDB(noteDB), nil
+	created := fromNoteDB(noteDB)
+	s.publish(ctx, EventCreated, created)
+	return created, nil
 }
 
-func (s service) UpdateNote(note Note) (Note, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+// UpdateNote replaces the text of the note. The note keeps its creation
+// time, unless an import or restore sets it.
+func (s service) UpdateNote(ctx context.Context, note Note) (Note, error) {
+	ctx, span := tracer.Start(ctx, "notes.UpdateNote", trace.WithAttributes(noteAttributes(note.Category, note.ID)...))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	noteDB, err := s.db.UpdateNote(ctx, toNoteDB(note))
+	noteDB, err := s.db.UpdateNote(ctx, toNoteDB(ctx, note))
 	if err != nil {
-		return Note{}, checkError(err)
+		return Note{}, recordError(span, checkError(err))
 	}
 
-	return fromNoteDB(noteDB), nil
+	updated := fromNoteDB(noteDB)
+	s.publish(ctx, EventUpdated, updated)
+	return updated, nil
 }
 
-func (s service) DeleteNote(note Note) error {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+func (s service) DeleteNote(ctx context.Context, note Note) error {
+	ctx, span := tracer.Start(ctx, "notes.DeleteNote", trace.WithAttributes(noteAttributes(note.Category, note.ID)...))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	err := s.db.DeleteNote(ctx, note.ID, note.Category)
 	if err != nil {
-		return checkError(err)
+		return recordError(span, checkError(err))
 	}
 
+	s.publish(ctx, EventDeleted, Note{ID: note.ID, Category: note.Category})
 	return nil
 }
 
-func (s service) GetNotesByCategory(category string) ([]Note, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+func (s service) GetNotesByCategory(ctx context.Context, category string) ([]Note, error) {
+	ctx, span := tracer.Start(ctx, "notes.GetNotesByCategory", trace.WithAttributes(attribute.String("note.category", category)))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	notesDB, err := s.db.GetNotesByCategory(ctx, category)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
-			return nil, fmt.Errorf("category %s: %w", category, ErrNotFound)
+			return nil, recordError(span, fmt.Errorf("category %s: %w", category, ErrNotFound))
 		}
-		return nil, checkError(err)
+		return nil, recordError(span, checkError(err))
 	}
+	span.SetAttributes(attribute.Int("notes.count", len(notesDB)))
 
 	notes := make([]Note, len(notesDB))
 	for i := range notesDB {
@@ -142,27 +189,118 @@ func (s service) GetNotesByCategory(category string) ([]Note, error) {
 	return notes, nil
 }
 
-func (s service) GetNoteByID(category, id string) (Note, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+func (s service) GetNotesByCategories(ctx context.Context, categories []string) (map[string][]Note, error) {
+	ctx, span := tracer.Start(ctx, "notes.GetNotesByCategories", trace.WithAttributes(attribute.StringSlice("note.categories", categories)))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	notes := make(map[string][]Note, len(categories))
+	var unique []string
+	for _, category := range categories {
+		if _, ok := notes[category]; !ok {
+			notes[category] = []Note{}
+			unique = append(unique, category)
+		}
+	}
+	if len(unique) == 0 {
+		return notes, nil
+	}
+
+	notesDB, err := s.db.GetNotesByCategories(ctx, unique)
+	if err != nil {
+		return nil, recordError(span, checkError(err))
+	}
+	span.SetAttributes(attribute.Int("notes.count", len(notesDB)))
+
+	for _, noteDB := range notesDB {
+		notes[noteDB.Category] = append(notes[noteDB.Category], fromNoteDB(noteDB))
+	}
+
+	return notes, nil
+}
+
+func (s service) ListCategories(ctx context.Context) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "notes.ListCategories")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	categories, err := s.db.ListCategories(ctx)
+	if err != nil {
+		return nil, recordError(span, checkError(err))
+	}
+	span.SetAttributes(attribute.Int("categories.count", len(categories)))
+	return categories, nil
+}
+
+func (s service) GetNoteByID(ctx context.Context, category, id string) (Note, error) {
+	ctx, span := tracer.Start(ctx, "notes.GetNoteByID", trace.WithAttributes(noteAttributes(category, id)...))
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
 	noteDB, err := s.db.GetNoteByID(ctx, category, id)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
-			return Note{}, fmt.Errorf("category %s, id %s: %w", category, id, ErrNotFound)
+			return Note{}, recordError(span, fmt.Errorf("category %s, id %s: %w", category, id, ErrNotFound))
 		}
-		return Note{}, checkError(err)
+		return Note{}, recordError(span, checkError(err))
 	}
 
 	return fromNoteDB(noteDB), nil
 }
 
-func toNoteDB(note Note) db.Note {
+// publish publishes the event of the note if the service has a broker.
+func (s service) publish(ctx context.Context, eventType EventType, note Note) {
+	if s.events != nil {
+		event := s.events.Publish(eventType, note)
+		s.log.DebugContext(ctx, "Note event published.", "type", "service", "name", "noteService", "eventId", event.ID, "event", event.Type, "noteCategory", note.Category, "noteID", note.ID)
+	}
+}
+
+// noteAttributes returns the span attributes of a note.
+func noteAttributes(category, id string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("note.category", category),
+		attribute.String("note.id", id),
+	}
+}
+
+// recordError records the error on the span and returns it.
+func recordError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+// createdAtKey is the context key of the creation time of the written
+// notes.
+type createdAtKey struct{}
+
+// withCreatedAt returns a copy of the context with the creation time of
+// the notes written with it, so that imports and restores keep the
+// creation times of the notes. A zero time is ignored.
+func withCreatedAt(ctx context.Context, createdAt time.Time) context.Context {
+	if createdAt.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, createdAtKey{}, createdAt)
+}
+
+// toNoteDB returns the note of the database, with the creation time of
+// the context. The creation time is zero if the context has none, so that
+// the database keeps the creation time of an updated note.
+func toNoteDB(ctx context.Context, note Note) db.Note {
+	createdAt, _ := ctx.Value(createdAtKey{}).(time.Time)
 	noteDB := db.Note{
 		ID:        note.ID,
 		Category:  note.Category,
 		Note:      note.Note,
-		CreatedAt: time.Now().UTC(),
+		CreatedAt: createdAt,
 	}
 	return noteDB
 }
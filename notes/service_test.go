@@ -0,0 +1,208 @@
+package notes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_service_contextPropagation(t *testing.T) {
+	tests := []struct {
+		name        string
+		call        func(ctx context.Context, s *service) error
+		timeout     time.Duration
+		cancel      bool
+		wantDBErr   error
+		expectError error
+	}{
+		{
+			name: "CreateNote() - canceled request aborts the database call",
+			call: func(ctx context.Context, s *service) error {
+				_, err := s.CreateNote(ctx, Note{Category: "category", Note: "note"})
+				return err
+			},
+			timeout:     time.Minute,
+			cancel:      true,
+			wantDBErr:   context.Canceled,
+			expectError: ErrCanceled,
+		},
+		{
+			name: "UpdateNote() - canceled request aborts the database call",
+			call: func(ctx context.Context, s *service) error {
+				_, err := s.UpdateNote(ctx, Note{ID: "1", Category: "category", Note: "note"})
+				return err
+			},
+			timeout:     time.Minute,
+			cancel:      true,
+			wantDBErr:   context.Canceled,
+			expectError: ErrCanceled,
+		},
+		{
+			name: "DeleteNote() - canceled request aborts the database call",
+			call: func(ctx context.Context, s *service) error {
+				return s.DeleteNote(ctx, Note{ID: "1", Category: "category"})
+			},
+			timeout:     time.Minute,
+			cancel:      true,
+			wantDBErr:   context.Canceled,
+			expectError: ErrCanceled,
+		},
+		{
+			name: "GetNotesByCategory() - canceled request aborts the database call",
+			call: func(ctx context.Context, s *service) error {
+				_, err := s.GetNotesByCategory(ctx, "category")
+				return err
+			},
+			timeout:     time.Minute,
+			cancel:      true,
+			wantDBErr:   context.Canceled,
+			expectError: ErrCanceled,
+		},
+		{
+			name: "GetNoteByID() - canceled request aborts the database call",
+			call: func(ctx context.Context, s *service) error {
+				_, err := s.GetNoteByID(ctx, "category", "1")
+				return err
+			},
+			timeout:     time.Minute,
+			cancel:      true,
+			wantDBErr:   context.Canceled,
+			expectError: ErrCanceled,
+		},
+		{
+			name: "GetNoteByID() - service timeout aborts the database call",
+			call: func(ctx context.Context, s *service) error {
+				_, err := s.GetNoteByID(ctx, "category", "1")
+				return err
+			},
+			timeout:     10 * time.Millisecond,
+			wantDBErr:   context.DeadlineExceeded,
+			expectError: ErrTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			database := &blockingDB{called: make(chan struct{})}
+			svc, err := NewService(database, &mockLogger{}, func(o *ServiceOptions) {
+				o.Timeout = tt.timeout
+			})
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				go func() {
+					<-database.called
+					cancel()
+				}()
+			}
+
+			// Act
+			start := time.Now()
+			err = tt.call(ctx, svc)
+
+			// Assert
+			require.ErrorIs(t, err, tt.expectError)
+			require.ErrorIs(t, database.err, tt.wantDBErr)
+			require.Less(t, time.Since(start), 5*time.Second)
+		})
+	}
+}
+
+func Test_service_requestScopedValues(t *testing.T) {
+	type key struct{}
+
+	database := &blockingDB{called: make(chan struct{}), noBlock: true}
+	svc, err := NewService(database, &mockLogger{})
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), key{}, "request")
+	_, err = svc.GetNoteByID(ctx, "category", "1")
+
+	require.NoError(t, err)
+	require.Equal(t, "request", database.ctx.Value(key{}))
+	_, hasDeadline := database.ctx.Deadline()
+	require.True(t, hasDeadline)
+}
+
+func Test_service_UpdateNote_keepsCreatedAt(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.NewFilesystemDB(dir)
+	require.NoError(t, err)
+	svc, err := NewService(database, &mockLogger{})
+	require.NoError(t, err)
+
+	created, err := svc.CreateNote(context.Background(), Note{Category: "work", Note: "first"})
+	require.NoError(t, err)
+	path := filepath.Join(dir, "work", created.ID+".md")
+	before, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = svc.UpdateNote(context.Background(), Note{ID: created.ID, Category: "work", Note: "first"})
+	require.NoError(t, err)
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(before), string(after))
+}
+
+// blockingDB is a database whose calls block until their context is done,
+// and record the context and its error.
+type blockingDB struct {
+	called  chan struct{}
+	noBlock bool
+	ctx     context.Context
+	err     error
+}
+
+func (d *blockingDB) wait(ctx context.Context) error {
+	d.ctx = ctx
+	close(d.called)
+	if d.noBlock {
+		return nil
+	}
+	<-ctx.Done()
+	d.err = ctx.Err()
+	return d.err
+}
+
+func (d *blockingDB) CreateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	return note, d.wait(ctx)
+}
+
+func (d *blockingDB) UpdateNote(ctx context.Context, note db.Note) (db.Note, error) {
+	return note, d.wait(ctx)
+}
+
+func (d *blockingDB) DeleteNote(ctx context.Context, id, category string) error {
+	return d.wait(ctx)
+}
+
+func (d *blockingDB) GetNotesByCategory(ctx context.Context, category string) ([]db.Note, error) {
+	return nil, d.wait(ctx)
+}
+
+func (d *blockingDB) GetNotesByCategories(ctx context.Context, categories []string) ([]db.Note, error) {
+	return nil, d.wait(ctx)
+}
+
+func (d *blockingDB) ListCategories(ctx context.Context) ([]string, error) {
+	return nil, d.wait(ctx)
+}
+
+func (d *blockingDB) GetNoteByID(ctx context.Context, category, id string) (db.Note, error) {
+	return db.Note{ID: id, Category: category}, d.wait(ctx)
+}
+
+// mockLogger discards the logged messages.
+type mockLogger struct{}
+
+func (l *mockLogger) DebugContext(ctx context.Context, msg string, args ...any) {}
+func (l *mockLogger) InfoContext(ctx context.Context, msg string, args ...any)  {}
+func (l *mockLogger) ErrorContext(ctx context.Context, msg string, args ...any) {}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults for CORS configuration.
+var (
+	defaultCORSAllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	defaultCORSAllowedHeaders = []string{"Content-Type", headerIdempotencyKey, headerAPIKey, headerRequestID, headerAuthorName, headerAuthorEmail}
+	// corsExposedHeaders are the response headers a browser client can read.
+	corsExposedHeaders = []string{headerRequestID, headerIdempotentReplayed, "Retry-After", "Location", "Deprecation", "Link"}
+)
+
+// CORSOptions holds the CORS configuration for the server.
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to call the API, "*" allows
+	// every origin. "*" cannot be used with AllowCredentials.
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in cross-origin requests.
+	// Defaults to GET, POST, PUT and DELETE.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in cross-origin
+	// requests. Defaults to the headers used by the API.
+	AllowedHeaders []string
+	// AllowCredentials allows cookies and authorization headers to be
+	// sent with cross-origin requests.
+	AllowCredentials bool
+	// MaxAge is how long the result of a preflight request can be cached.
+	MaxAge time.Duration
+}
+
+// cors handles cross-origin requests.
+type cors struct {
+	allowAllOrigins  bool
+	allowedOrigins   []string
+	allowedMethods   []string
+	allowedHeaders   []string
+	allowCredentials bool
+	maxAge           time.Duration
+}
+
+// newCORS returns a new cors with the options and the defaults for the
+// options that are not set. Credentials cannot be allowed for every
+// origin, since any site could then make requests with the credentials
+// of its visitors.
+func newCORS(options CORSOptions) (*cors, error) {
+	c := &cors{
+		allowedMethods:   options.AllowedMethods,
+		allowedHeaders:   options.AllowedHeaders,
+		allowCredentials: options.AllowCredentials,
+		maxAge:           options.MaxAge,
+	}
+	for _, origin := range options.AllowedOrigins {
+		if origin == "*" {
+			c.allowAllOrigins = true
+			continue
+		}
+		c.allowedOrigins = append(c.allowedOrigins, strings.TrimSuffix(origin, "/"))
+	}
+	if c.allowAllOrigins && c.allowCredentials {
+		return nil, ErrCORSCredentialsWithAnyOrigin
+	}
+	if len(c.allowedMethods) == 0 {
+		c.allowedMethods = defaultCORSAllowedMethods
+	}
+	if len(c.allowedHeaders) == 0 {
+		c.allowedHeaders = defaultCORSAllowedHeaders
+	}
+	return c, nil
+}
+
+// originAllowed reports whether the origin is allowed.
+func (c *cors) originAllowed(origin string) bool {
+	return c.allowAllOrigins || slices.Contains(c.allowedOrigins, origin)
+}
+
+// methodAllowed reports whether the method is allowed.
+func (c *cors) methodAllowed(method string) bool {
+	return slices.Contains(c.allowedMethods, method)
+}
+
+// headersAllowed reports whether all headers of the comma separated list
+// are allowed.
+func (c *cors) headersAllowed(headers string) bool {
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.TrimSpace(header)
+		if len(header) == 0 {
+			continue
+		}
+		if !slices.ContainsFunc(c.allowedHeaders, func(h string) bool {
+			return strings.EqualFold(h, header)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// setOrigin sets the headers that allow the origin to read the response.
+func (c *cors) setOrigin(h http.Header, origin string) {
+	if c.allowAllOrigins {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// cors is a middleware that handles cross-origin requests. Preflight
+// requests are answered for every route registered on the router, other
+// requests from allowed origins get the CORS headers set.
+func (s server) cors(next http.Handler) http.Handler {
+	if s.corsConfig == nil {
+		return next
+	}
+	c := s.corsConfig
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if len(origin) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method != http.MethodOptions || len(requestMethod) == 0 {
+			w.Header().Add("Vary", "Origin")
+			if c.originAllowed(origin) {
+				c.setOrigin(w.Header(), origin)
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A preflight request is answered if the route exists for the
+		// requested method, otherwise the router responds with 404 or 405.
+		route := r.Clone(r.Context())
+		route.Method = requestMethod
+		if _, pattern := s.router.Handler(route); len(pattern) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		if !c.originAllowed(origin) {
+			statusCode, code := errorCodes(ErrOriginNotAllowed)
+			writeError(w, statusCode, code, ErrOriginNotAllowed)
+			return
+		}
+		requestHeaders := r.Header.Get("Access-Control-Request-Headers")
+		if !c.methodAllowed(requestMethod) || !c.headersAllowed(requestHeaders) {
+			statusCode, code := errorCodes(ErrCORSRequestNotAllowed)
+			writeError(w, statusCode, code, ErrCORSRequestNotAllowed)
+			return
+		}
+
+		c.setOrigin(w.Header(), origin)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.allowedMethods, ", "))
+		if len(requestHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.allowedHeaders, ", "))
+		}
+		if c.maxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cors_preflight(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithCORS(CORSOptions{
+		AllowedOrigins: []string{"https://notes.example.com"},
+		MaxAge:         10 * time.Minute,
+	}))
+	require.NoError(t, err)
+	s.routes()
+	handler := s.middleware(s.router)
+
+	routes := []struct {
+		method string
+		target string
+	}{
+		{method: http.MethodPost, target: "/notes/create/category"},
+		{method: http.MethodPut, target: "/notes/update/category/1"},
+		{method: http.MethodDelete, target: "/notes/delete/category/1"},
+		{method: http.MethodGet, target: "/notes/categories/category/ids/1"},
+		{method: http.MethodGet, target: "/notes/categories/category"},
+		{method: http.MethodGet, target: "/notes/categories"},
+		{method: http.MethodPost, target: "/v1/categories/category/notes"},
+		{method: http.MethodGet, target: "/v1/categories/category/notes"},
+		{method: http.MethodGet, target: "/v1/categories/category/notes/1"},
+		{method: http.MethodPut, target: "/v1/categories/category/notes/1"},
+		{method: http.MethodDelete, target: "/v1/categories/category/notes/1"},
+		{method: http.MethodGet, target: "/v1/categories"},
+		{method: http.MethodGet, target: pathLiveness},
+		{method: http.MethodGet, target: pathReadiness},
+	}
+
+	for _, route := range routes {
+		t.Run("preflight "+route.method+" "+route.target, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, route.target, nil)
+			req.Header.Set("Origin", "https://notes.example.com")
+			req.Header.Set("Access-Control-Request-Method", route.method)
+			req.Header.Set("Access-Control-Request-Headers", "content-type, idempotency-key")
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusNoContent, rec.Code)
+			require.Equal(t, "https://notes.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+			require.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), route.method)
+			require.Contains(t, rec.Header().Get("Access-Control-Allow-Headers"), "Idempotency-Key")
+			require.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		})
+	}
+}
+
+func Test_cors(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       CORSOptions
+		method        string
+		target        string
+		headers       map[string]string
+		wantStatus    int
+		wantOrigin    string
+		wantCredsFlag string
+	}{
+		{
+			name:    "cors() - disallowed origin preflight is rejected",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}},
+			method:  http.MethodOptions,
+			target:  "/notes/categories/category",
+			headers: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": http.MethodGet,
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:    "cors() - disallowed header preflight is rejected",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}},
+			method:  http.MethodOptions,
+			target:  "/notes/categories/category",
+			headers: map[string]string{
+				"Origin":                         "https://notes.example.com",
+				"Access-Control-Request-Method":  http.MethodGet,
+				"Access-Control-Request-Headers": "X-Custom",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:    "cors() - preflight for a method the route does not have",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}},
+			method:  http.MethodOptions,
+			target:  "/notes/categories/category",
+			headers: map[string]string{
+				"Origin":                        "https://notes.example.com",
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:    "cors() - preflight for an unknown route",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}},
+			method:  http.MethodOptions,
+			target:  "/unknown",
+			headers: map[string]string{
+				"Origin":                        "https://notes.example.com",
+				"Access-Control-Request-Method": http.MethodGet,
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:    "cors() - request from allowed origin",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}},
+			method:  http.MethodGet,
+			target:  pathLiveness,
+			headers: map[string]string{
+				"Origin": "https://notes.example.com",
+			},
+			wantStatus: http.StatusOK,
+			wantOrigin: "https://notes.example.com",
+		},
+		{
+			name:    "cors() - request from disallowed origin",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}},
+			method:  http.MethodGet,
+			target:  pathLiveness,
+			headers: map[string]string{
+				"Origin": "https://evil.example.com",
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "cors() - any origin",
+			options: CORSOptions{AllowedOrigins: []string{"*"}},
+			method:  http.MethodGet,
+			target:  pathLiveness,
+			headers: map[string]string{
+				"Origin": "https://notes.example.com",
+			},
+			wantStatus: http.StatusOK,
+			wantOrigin: "*",
+		},
+		{
+			name:    "cors() - allowed origin with credentials",
+			options: CORSOptions{AllowedOrigins: []string{"https://notes.example.com"}, AllowCredentials: true},
+			method:  http.MethodGet,
+			target:  pathLiveness,
+			headers: map[string]string{
+				"Origin": "https://notes.example.com",
+			},
+			wantStatus:    http.StatusOK,
+			wantOrigin:    "https://notes.example.com",
+			wantCredsFlag: "true",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithCORS(tt.options))
+			require.NoError(t, err)
+			s.routes()
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			s.middleware(s.router).ServeHTTP(rec, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Equal(t, tt.wantOrigin, rec.Header().Get("Access-Control-Allow-Origin"))
+			require.Equal(t, tt.wantCredsFlag, rec.Header().Get("Access-Control-Allow-Credentials"))
+		})
+	}
+}
+
+func Test_WithCORS_credentialsWithAnyOrigin(t *testing.T) {
+	_, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithCORS(CORSOptions{
+		AllowedOrigins:   []string{"https://notes.example.com", "*"},
+		AllowCredentials: true,
+	}))
+	require.ErrorIs(t, err, ErrCORSCredentialsWithAnyOrigin)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// batchFunc loads the values of the keys with one call. Keys without a
+// value are loaded as the zero value.
+type batchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// loadResult is the loaded value of a key, or the error of its batch.
+type loadResult[V any] struct {
+	value V
+	err   error
+}
+
+// dataLoader batches and caches the loading of values for the lifetime of
+// a GraphQL request. Keys are collected while a level of the query is
+// resolved, and all collected keys are loaded with one call of the batch
+// function when the first value is needed, so that nested fields do not
+// load their values one by one.
+type dataLoader[K comparable, V any] struct {
+	batch   batchFunc[K, V]
+	mu      sync.Mutex
+	pending []K
+	results map[K]loadResult[V]
+}
+
+// newDataLoader returns a dataLoader that loads values with the batch function.
+func newDataLoader[K comparable, V any](batch batchFunc[K, V]) *dataLoader[K, V] {
+	return &dataLoader[K, V]{
+		batch:   batch,
+		results: make(map[K]loadResult[V]),
+	}
+}
+
+// load adds the key to the next batch and returns a thunk that returns
+// its value. The batch is loaded when the first of its thunks is called.
+func (l *dataLoader[K, V]) load(ctx context.Context, key K) func() (V, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok && !l.isPending(key) {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if l.isPending(key) {
+			l.dispatch(ctx)
+		}
+		result := l.results[key]
+		return result.value, result.err
+	}
+}
+
+// clear removes the loaded values, so that they are loaded again after
+// they are changed.
+func (l *dataLoader[K, V]) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.results = make(map[K]loadResult[V])
+}
+
+// dispatch loads the pending keys. It must be called with the lock held.
+func (l *dataLoader[K, V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		l.results[key] = loadResult[V]{value: values[key], err: err}
+	}
+}
+
+// isPending reports whether the key is in the next batch. It must be
+// called with the lock held.
+func (l *dataLoader[K, V]) isPending(key K) bool {
+	for _, k := range l.pending {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
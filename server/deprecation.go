@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyRoutesDeprecatedAt is when the legacy routes were deprecated in
+// favor of the /v1 routes.
+var legacyRoutesDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// deprecated is a middleware that marks the responses of a deprecated
+// route with a Deprecation header (RFC 9745) and links the route that
+// replaces it. The path values of the successor, such as {category},
+// are filled in from the request.
+func deprecated(next http.Handler, successor string) http.Handler {
+	deprecation := "@" + strconv.FormatInt(legacyRoutesDeprecatedAt.Unix(), 10)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", deprecation)
+		w.Header().Set("Link", "<"+successorPath(r, successor)+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// successorPath replaces the wildcards of the successor pattern with the
+// escaped path values of the request.
+func successorPath(r *http.Request, successor string) string {
+	segments := strings.Split(successor, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = url.PathEscape(r.PathValue(segment[1 : len(segment)-1]))
+		}
+	}
+	return strings.Join(segments, "/")
+}
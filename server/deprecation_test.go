@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_deprecated(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		wantDeprecation string
+		wantLink        string
+	}{
+		{
+			name:            "legacy create route is deprecated",
+			method:          http.MethodPost,
+			target:          "/notes/create/work",
+			wantDeprecation: "@1792368000",
+			wantLink:        `</v1/categories/work/notes>; rel="successor-version"`,
+		},
+		{
+			name:            "legacy update route is deprecated",
+			method:          http.MethodPut,
+			target:          "/notes/update/work/1",
+			wantDeprecation: "@1792368000",
+			wantLink:        `</v1/categories/work/notes/1>; rel="successor-version"`,
+		},
+		{
+			name:            "legacy delete route is deprecated",
+			method:          http.MethodDelete,
+			target:          "/notes/delete/work/1",
+			wantDeprecation: "@1792368000",
+			wantLink:        `</v1/categories/work/notes/1>; rel="successor-version"`,
+		},
+		{
+			name:            "legacy get route is deprecated",
+			method:          http.MethodGet,
+			target:          "/notes/categories/my%20work/ids/1",
+			wantDeprecation: "@1792368000",
+			wantLink:        `</v1/categories/my%20work/notes/1>; rel="successor-version"`,
+		},
+		{
+			name:            "legacy list route is deprecated",
+			method:          http.MethodGet,
+			target:          "/notes/categories/work",
+			wantDeprecation: "@1792368000",
+			wantLink:        `</v1/categories/work/notes>; rel="successor-version"`,
+		},
+		{
+			name:            "legacy list categories route is deprecated",
+			method:          http.MethodGet,
+			target:          "/notes/categories",
+			wantDeprecation: "@1792368000",
+			wantLink:        `</v1/categories>; rel="successor-version"`,
+		},
+		{
+			name:   "v1 route is not deprecated",
+			method: http.MethodGet,
+			target: "/v1/categories/work/notes/1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{called: make(chan struct{})}, WithLogger(&mockLogger{}))
+			require.NoError(t, err)
+			s.routes()
+
+			// The request is canceled, so that the service returns at once.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil).WithContext(ctx))
+
+			require.Equal(t, tt.wantDeprecation, rec.Header().Get("Deprecation"))
+			require.Equal(t, tt.wantLink, rec.Header().Get("Link"))
+		})
+	}
+}
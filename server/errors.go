@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/KatrinSalt/notes-service/webhooks"
 )
 
 var (
@@ -18,6 +19,22 @@ var (
 	ErrForbidden = errors.New("forbidden")
 	// ErrCategoryRequired is returned when a category is required.
 	ErrCategoryRequired = errors.New("category is required")
+	// ErrRateLimited is returned when a client exceeds its rate limit.
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrInvalidIdempotencyKey is returned when the idempotency key is invalid.
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	// ErrIdempotencyKeyReused is returned when an idempotency key is reused
+	// with a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key is reused with a different request")
+	// ErrOriginNotAllowed is returned when a cross-origin request is made
+	// from an origin that is not allowed.
+	ErrOriginNotAllowed = errors.New("origin is not allowed")
+	// ErrCORSRequestNotAllowed is returned when a cross-origin request uses
+	// a method or header that is not allowed.
+	ErrCORSRequestNotAllowed = errors.New("cross-origin request is not allowed")
+	// ErrCORSCredentialsWithAnyOrigin is returned when CORS is configured
+	// to allow credentials for every origin.
+	ErrCORSCredentialsWithAnyOrigin = errors.New("cors credentials cannot be allowed for every origin")
 
 	// // ErrIDRequired is returned when an id is required.
 	// ErrIDRequired = errors.New("id is required")
@@ -51,23 +68,51 @@ const (
 	CodeIDRequired = "IDRequired"
 	// CodeCategoryRequired is the error code for category required.
 	CodeCategoryRequired = "CategoryRequired"
+	// CodeServiceUnavailable is the error code for service unavailable.
+	CodeServiceUnavailable = "ServiceUnavailable"
 )
 
+// statusClientClosedRequest is the non-standard status code for requests
+// that were canceled by the client before the response was written.
+const statusClientClosedRequest = 499
+
 // errorCodeMaps contains a map with HTTP status codes and a map with errors
 // and their codes.
 var errorCodeMaps = map[int]map[error]string{
 	http.StatusBadRequest: {
-		ErrInvalidRequest:       "InvalidRequest",
-		ErrMalformedRequestBody: "MalformedRequestBody",
-		ErrEmptyRequestBody:     "EmptyRequestBody",
-		notes.ErrInvalidInput:   "InvalidInput",
+		ErrInvalidRequest:          "InvalidRequest",
+		ErrMalformedRequestBody:    "MalformedRequestBody",
+		ErrEmptyRequestBody:        "EmptyRequestBody",
+		notes.ErrInvalidInput:      "InvalidInput",
+		ErrInvalidIdempotencyKey:   "InvalidIdempotencyKey",
+		webhooks.ErrInvalidWebhook: "InvalidWebhook",
+	},
+	http.StatusForbidden: {
+		ErrOriginNotAllowed:      "OriginNotAllowed",
+		ErrCORSRequestNotAllowed: "CORSRequestNotAllowed",
 	},
 	http.StatusNotFound: {
-		notes.ErrNotFound: "NotFound",
+		notes.ErrNotFound:    "NotFound",
+		webhooks.ErrNotFound: "WebhookNotFound",
 	},
 	http.StatusConflict: {
 		notes.ErrAlreadyExists: "AlreadyExists",
 	},
+	http.StatusUnprocessableEntity: {
+		ErrIdempotencyKeyReused: "IdempotencyKeyReused",
+	},
+	http.StatusTooManyRequests: {
+		ErrRateLimited: "RateLimited",
+	},
+	http.StatusServiceUnavailable: {
+		notes.ErrUnavailable: CodeServiceUnavailable,
+	},
+	http.StatusGatewayTimeout: {
+		notes.ErrTimeout: "Timeout",
+	},
+	statusClientClosedRequest: {
+		notes.ErrCanceled: "RequestCanceled",
+	},
 }
 
 // errorCodes returns the status and error code for the given error.
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+const (
+	// pathEvents is the path of the stream of note events.
+	pathEvents = "/notes/events"
+	// headerLastEventID is the header a client resumes an event stream with.
+	headerLastEventID = "Last-Event-ID"
+	// eventsKeepAlive is the interval of the comments that keep an idle
+	// event stream open through proxies.
+	eventsKeepAlive = 15 * time.Second
+	// eventReset is the event sent when events after the last event ID of
+	// the client were lost, so that the client reloads its notes.
+	eventReset = "reset"
+)
+
+// eventBroker is the interface that wraps around the methods to subscribe
+// to the events of the notes service.
+type eventBroker interface {
+	Subscribe(category string, lastEventID uint64) *notes.Subscription
+	Unsubscribe(sub *notes.Subscription)
+}
+
+// noteEvents streams the create, update and delete events of the notes
+// of a category, or of all categories, as Server-Sent Events.
+func (s server) noteEvents() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var lastEventID uint64
+		if id := r.Header.Get(headerLastEventID); len(id) > 0 {
+			var err error
+			if lastEventID, err = strconv.ParseUint(id, 10, 64); err != nil {
+				writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Errorf("%w: invalid %s", ErrInvalidRequest, headerLastEventID))
+				return
+			}
+		}
+
+		sub := s.events.Subscribe(r.URL.Query().Get("category"), lastEventID)
+		defer s.events.Unsubscribe(sub)
+
+		// The stream is open until the client disconnects, so the write
+		// timeout of the server does not apply.
+		rc := http.NewResponseController(w)
+		rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if sub.Missed {
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventReset)
+		}
+		for _, event := range sub.Replay {
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-s.streams.Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case event, ok := <-sub.Events():
+				if !ok {
+					// The client fell behind, it resumes with its last
+					// event ID when it reconnects.
+					return
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	})
+}
+
+// writeEvent writes the event in the Server-Sent Events format.
+func writeEvent(w http.ResponseWriter, event notes.Event) error {
+	data, err := json.Marshal(toNoteAPI(event.Note))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_noteEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		lastEventID string
+		wantStatus  int
+		wantEvents  []string
+	}{
+		{
+			name:       "noteEvents() - streams the events of the category",
+			target:     pathEvents + "?category=work",
+			wantStatus: http.StatusOK,
+			wantEvents: []string{
+				"id: 4\nevent: updated\ndata: {\"id\":\"1\",\"category\":\"work\",\"note\":\"updated\"}",
+			},
+		},
+		{
+			name:        "noteEvents() - resumes after the last event ID",
+			target:      pathEvents + "?category=work",
+			lastEventID: "1",
+			wantStatus:  http.StatusOK,
+			wantEvents: []string{
+				"id: 3\nevent: deleted\ndata: {\"id\":\"2\",\"category\":\"work\"}",
+				"id: 4\nevent: updated\ndata: {\"id\":\"1\",\"category\":\"work\",\"note\":\"updated\"}",
+			},
+		},
+		{
+			name:        "noteEvents() - resets when events were lost",
+			target:      pathEvents,
+			lastEventID: "9",
+			wantStatus:  http.StatusOK,
+			wantEvents: []string{
+				"event: reset\ndata: {}",
+				"id: 4\nevent: updated\ndata: {\"id\":\"1\",\"category\":\"work\",\"note\":\"updated\"}",
+			},
+		},
+		{
+			name:        "noteEvents() - invalid last event ID",
+			target:      pathEvents,
+			lastEventID: "abc",
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := notes.NewBroker()
+			broker.Publish(notes.EventCreated, notes.Note{ID: "1", Category: "work", Note: "note"})
+			broker.Publish(notes.EventCreated, notes.Note{ID: "1", Category: "home", Note: "note"})
+			broker.Publish(notes.EventDeleted, notes.Note{ID: "2", Category: "work"})
+
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithEvents(broker))
+			require.NoError(t, err)
+			s.routes()
+			ts := httptest.NewServer(s.middleware(s.router))
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+tt.target, nil)
+			require.NoError(t, err)
+			if len(tt.lastEventID) > 0 {
+				req.Header.Set(headerLastEventID, tt.lastEventID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+			// The headers are flushed after the subscription, so the event
+			// is published to the stream.
+			broker.Publish(notes.EventUpdated, notes.Note{ID: "1", Category: "work", Note: "updated"})
+
+			events := readEvents(t, bufio.NewReader(resp.Body), len(tt.wantEvents))
+			require.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
+
+// readEvents reads n events from the stream, skipping comments.
+func readEvents(t *testing.T, r *bufio.Reader, n int) []string {
+	t.Helper()
+
+	var events []string
+	var lines []string
+	for len(events) < n {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, ":"):
+		case len(line) == 0:
+			if len(lines) > 0 {
+				events = append(events, strings.Join(lines, "\n"))
+				lines = nil
+			}
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return events
+}
@@ -0,0 +1,304 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/graphql-go/graphql"
+)
+
+// pathGraphQL is the path of the GraphQL endpoint.
+const pathGraphQL = "/graphql"
+
+// graphQLRequest is a GraphQL request.
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// noteKey is the key of a note.
+type noteKey struct {
+	category string
+	id       string
+}
+
+// graphQLLoaders are the data loaders of a GraphQL request.
+type graphQLLoaders struct {
+	service notes.Service
+	// note loads notes by their key. Notes are read one by one, but every
+	// note is read once per request.
+	note *dataLoader[noteKey, *notes.Note]
+	// notesByCategory loads the notes of all requested categories with
+	// one call.
+	notesByCategory *dataLoader[string, []notes.Note]
+}
+
+// graphQLLoadersKey is the context key of the data loaders of a request.
+type graphQLLoadersKey struct{}
+
+// newGraphQLLoaders returns the data loaders for a request.
+func newGraphQLLoaders(service notes.Service) *graphQLLoaders {
+	return &graphQLLoaders{
+		service: service,
+		note: newDataLoader(func(ctx context.Context, keys []noteKey) (map[noteKey]*notes.Note, error) {
+			found := make(map[noteKey]*notes.Note, len(keys))
+			for _, key := range keys {
+				note, err := service.GetNoteByID(ctx, key.category, key.id)
+				if errors.Is(err, notes.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				found[key] = &note
+			}
+			return found, nil
+		}),
+		notesByCategory: newDataLoader(service.GetNotesByCategories),
+	}
+}
+
+// clear removes the loaded values after a mutation.
+func (l *graphQLLoaders) clear() {
+	l.note.clear()
+	l.notesByCategory.clear()
+}
+
+// loaders returns the data loaders of the request.
+func loaders(ctx context.Context) *graphQLLoaders {
+	return ctx.Value(graphQLLoadersKey{}).(*graphQLLoaders)
+}
+
+// graphQLError is an error of a GraphQL resolver with the error code of
+// the API in its extensions.
+type graphQLError struct {
+	code string
+	err  error
+}
+
+// Error returns the error message.
+func (e *graphQLError) Error() string {
+	return e.err.Error()
+}
+
+// Extensions returns the extensions of the error in the response.
+func (e *graphQLError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// resolveError returns the error of a resolver. Errors without an error
+// code are returned as server errors without details.
+func resolveError(err error) error {
+	if _, code := errorCodes(err); len(code) > 0 {
+		return &graphQLError{code: code, err: err}
+	}
+	return &graphQLError{code: CodeServerError, err: errors.New("internal server error")}
+}
+
+// newGraphQLSchema returns the GraphQL schema of the notes API. The
+// resolvers use the data loaders in the context of the request.
+func newGraphQLSchema() (graphql.Schema, error) {
+	noteType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Note",
+		Description: "A note in a category.",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"category": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"note":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+	notesType := graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(noteType)))
+
+	categoryType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Category",
+		Description: "A category that has notes.",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return p.Source, nil
+				},
+			},
+			"notes": &graphql.Field{
+				Type: notesType,
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return resolveNotes(p.Context, p.Source.(string)), nil
+				},
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"note": &graphql.Field{
+				Type:        noteType,
+				Description: "The note with the ID in the category, or null if it does not exist.",
+				Args: graphql.FieldConfigArgument{
+					"category": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					thunk := loaders(p.Context).note.load(p.Context, noteKey{
+						category: p.Args["category"].(string),
+						id:       p.Args["id"].(string),
+					})
+					return func() (any, error) {
+						note, err := thunk()
+						if err != nil {
+							return nil, resolveError(err)
+						}
+						if note == nil {
+							return nil, nil
+						}
+						return note, nil
+					}, nil
+				},
+			},
+			"notes": &graphql.Field{
+				Type:        notesType,
+				Description: "The notes in the category.",
+				Args: graphql.FieldConfigArgument{
+					"category": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return resolveNotes(p.Context, p.Args["category"].(string)), nil
+				},
+			},
+			"categories": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+				Description: "The categories that have notes.",
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					categories, err := loaders(p.Context).service.ListCategories(p.Context)
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return categories, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createNote": &graphql.Field{
+				Type:        graphql.NewNonNull(noteType),
+				Description: "Creates a note in the category.",
+				Args: graphql.FieldConfigArgument{
+					"category": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"note":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					l := loaders(p.Context)
+					defer l.clear()
+					note, err := l.service.CreateNote(p.Context, notes.Note{
+						Category: p.Args["category"].(string),
+						Note:     p.Args["note"].(string),
+					})
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return note, nil
+				},
+			},
+			"updateNote": &graphql.Field{
+				Type:        graphql.NewNonNull(noteType),
+				Description: "Updates the text of a note.",
+				Args: graphql.FieldConfigArgument{
+					"category": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"note":     &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					l := loaders(p.Context)
+					defer l.clear()
+					note, err := l.service.UpdateNote(p.Context, notes.Note{
+						ID:       p.Args["id"].(string),
+						Category: p.Args["category"].(string),
+						Note:     p.Args["note"].(string),
+					})
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return note, nil
+				},
+			},
+			"deleteNote": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Deletes a note.",
+				Args: graphql.FieldConfigArgument{
+					"category": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					l := loaders(p.Context)
+					defer l.clear()
+					err := l.service.DeleteNote(p.Context, toDeleteNote(p.Args["category"].(string), p.Args["id"].(string)))
+					if err != nil {
+						return nil, resolveError(err)
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+// resolveNotes returns a thunk that resolves the notes of the category
+// with the notes loader.
+func resolveNotes(ctx context.Context, category string) func() (any, error) {
+	thunk := loaders(ctx).notesByCategory.load(ctx, category)
+	return func() (any, error) {
+		notes, err := thunk()
+		if err != nil {
+			return nil, resolveError(err)
+		}
+		if notes == nil {
+			return []any{}, nil
+		}
+		return notes, nil
+	}
+}
+
+// graphQL serves GraphQL queries and mutations over the notes service.
+func (s server) graphQL() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := decode[graphQLRequest](r)
+		if err != nil {
+			statusCode, code := errorCodes(err)
+			if statusCode == 0 {
+				statusCode, code = http.StatusBadRequest, "InvalidRequest"
+			}
+			writeError(w, statusCode, code, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), graphQLLoadersKey{}, newGraphQLLoaders(s.notes))
+		result := graphql.Do(graphql.Params{
+			Schema:         *s.graphQLSchema,
+			RequestString:  req.Query,
+			OperationName:  req.OperationName,
+			VariableValues: req.Variables,
+			Context:        ctx,
+		})
+		for _, err := range result.Errors {
+			s.log.ErrorContext(r.Context(), "Failed to resolve a GraphQL request.", logError(err, "graphQL")...)
+		}
+
+		// Errors are returned in the response body, as GraphQL clients expect.
+		if err := encode(w, http.StatusOK, result); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to resolve a GraphQL request.", logError(err, "graphQL")...)
+			writeServerError(w)
+			return
+		}
+	})
+}
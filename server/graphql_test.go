@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_graphQL(t *testing.T) {
+	tests := []struct {
+		name        string
+		service     *stubNotesService
+		body        string
+		wantStatus  int
+		wantBody    string
+		wantBatches [][]string
+		wantReads   int
+	}{
+		{
+			name: "graphQL() - nested notes are loaded in one batch",
+			service: &stubNotesService{
+				notes: []notes.Note{
+					{ID: "1", Category: "home", Note: "first"},
+					{ID: "2", Category: "work", Note: "second"},
+				},
+				categories: []string{"home", "work"},
+			},
+			body:        `{"query":"{ categories { name notes { id } } work: notes(category: \"work\") { note } }"}`,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"data":{"categories":[{"name":"home","notes":[{"id":"1"}]},{"name":"work","notes":[{"id":"2"}]}],"work":[{"note":"second"}]}}`,
+			wantBatches: [][]string{{"home", "work"}},
+		},
+		{
+			name:       "graphQL() - note is read once",
+			service:    &stubNotesService{note: notes.Note{ID: "1", Category: "work", Note: "note"}},
+			body:       `{"query":"query($id: String!) { a: note(category: \"work\", id: $id) { id } b: note(category: \"work\", id: $id) { note } }","variables":{"id":"1"}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"a":{"id":"1"},"b":{"note":"note"}}}`,
+			wantReads:  1,
+		},
+		{
+			name:       "graphQL() - note not found",
+			service:    &stubNotesService{err: notes.ErrNotFound},
+			body:       `{"query":"{ note(category: \"work\", id: \"1\") { id } }"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":{"note":null}}`,
+			wantReads:  1,
+		},
+		{
+			name:       "graphQL() - mutation error has the error code",
+			service:    &stubNotesService{err: notes.ErrInvalidInput},
+			body:       `{"query":"mutation { createNote(category: \"work\", note: \"\") { id } }"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":null,"errors":[{"message":"invalid input","locations":[{"line":1,"column":12}],"path":["createNote"],"extensions":{"code":"InvalidInput"}}]}`,
+		},
+		{
+			name:       "graphQL() - internal error is not exposed",
+			service:    &stubNotesService{err: notes.ErrService},
+			body:       `{"query":"mutation { deleteNote(category: \"work\", id: \"1\") }"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"data":null,"errors":[{"message":"internal server error","locations":[{"line":1,"column":12}],"path":["deleteNote"],"extensions":{"code":"ServerError"}}]}`,
+		},
+		{
+			name:       "graphQL() - malformed request body",
+			service:    &stubNotesService{},
+			body:       `{"query":`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"InvalidRequest","message":"unexpected EOF"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &countingNotesService{stubNotesService: tt.service}
+			s, err := New(service, WithLogger(&mockLogger{}))
+			require.NoError(t, err)
+			s.routes()
+
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, pathGraphQL, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.JSONEq(t, tt.wantBody, rec.Body.String())
+			require.Equal(t, tt.wantBatches, service.batches)
+			require.Equal(t, tt.wantReads, service.reads)
+		})
+	}
+}
+
+// countingNotesService records the batches of categories, sorted by name,
+// and the number of notes read by ID.
+type countingNotesService struct {
+	*stubNotesService
+	batches [][]string
+	reads   int
+}
+
+func (s *countingNotesService) GetNotesByCategories(ctx context.Context, categories []string) (map[string][]notes.Note, error) {
+	batch := slices.Clone(categories)
+	slices.Sort(batch)
+	s.batches = append(s.batches, batch)
+	return s.stubNotesService.GetNotesByCategories(ctx, categories)
+}
+
+func (s *countingNotesService) GetNoteByID(ctx context.Context, category, id string) (notes.Note, error) {
+	s.reads++
+	return s.stubNotesService.GetNoteByID(ctx, category, id)
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+
+	"github.com/KatrinSalt/notes-service/api/notespb"
+	"github.com/KatrinSalt/notes-service/notes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// grpcStatusCodes maps the errors of the notes service to gRPC status codes.
+var grpcStatusCodes = map[error]codes.Code{
+	notes.ErrInvalidInput:  codes.InvalidArgument,
+	notes.ErrNotFound:      codes.NotFound,
+	notes.ErrAlreadyExists: codes.AlreadyExists,
+	notes.ErrUnavailable:   codes.Unavailable,
+	notes.ErrTimeout:       codes.DeadlineExceeded,
+	notes.ErrCanceled:      codes.Canceled,
+}
+
+// grpcReadMethods are the unary methods that are counted against the read
+// rate limit. Streams only read, and other unary methods write.
+var grpcReadMethods = map[string]bool{
+	notespb.NotesService_GetNote_FullMethodName: true,
+}
+
+// grpcError returns the gRPC status error for the given error. Errors
+// without a status code are returned as internal errors without details,
+// so that the caller does not get any information about the internal error.
+func grpcError(err error) error {
+	for e, code := range grpcStatusCodes {
+		if errors.Is(err, e) {
+			return status.Error(code, err.Error())
+		}
+	}
+	return status.Error(codes.Internal, "internal server error")
+}
+
+// grpcService implements the gRPC API on top of the notes service.
+type grpcService struct {
+	notespb.UnimplementedNotesServiceServer
+	notes notes.Service
+	log   logger
+}
+
+// newGRPCServer returns a gRPC server with the notes API registered. The
+// server uses the TLS configuration of the HTTP server when TLS is enabled.
+func (s server) newGRPCServer() *grpc.Server {
+	options := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.grpcRateLimit, grpcAuthor),
+		grpc.StreamInterceptor(s.grpcStreamRateLimit),
+	}
+	if s.tls != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tls.tlsConfig())))
+	}
+
+	srv := grpc.NewServer(options...)
+	notespb.RegisterNotesServiceServer(srv, &grpcService{notes: s.notes, log: s.log})
+	return srv
+}
+
+// grpcAuthor is an interceptor that adds the author of the x-author-name
+// and x-author-email metadata to the request context, like the author
+// middleware of the HTTP API.
+func grpcAuthor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if author, ok := toAuthor(firstValue(md, "x-author-name"), firstValue(md, "x-author-email")); ok {
+		ctx = notes.WithAuthor(ctx, author)
+	}
+	return handler(ctx, req)
+}
+
+// grpcRateLimit is an interceptor that limits requests per client with the
+// rate limits of the HTTP API, so that they are not bypassed on the gRPC
+// port. Clients are identified by the x-api-key metadata or their address.
+func (s server) grpcRateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	limiter := s.writeLimiter
+	if grpcReadMethods[info.FullMethod] {
+		limiter = s.readLimiter
+	}
+	if err := s.grpcAllow(ctx, limiter); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// grpcStreamRateLimit is an interceptor that limits the streams per client
+// with the read rate limit of the HTTP API.
+func (s server) grpcStreamRateLimit(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.grpcAllow(ss.Context(), s.readLimiter); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// grpcAllow returns a ResourceExhausted error, with the seconds to wait
+// in the retry-after header, if the client of the request is limited.
+func (s server) grpcAllow(ctx context.Context, limiter *rateLimiter) error {
+	if limiter == nil {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = hostOf(p.Addr.String())
+	}
+	if ok, retryAfter := limiter.allow(s.clientKeyOf(firstValue(md, "x-api-key"), ip)); !ok {
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))))
+		return status.Error(codes.ResourceExhausted, ErrRateLimited.Error())
+	}
+	return nil
+}
+
+// firstValue returns the first value of the key of the metadata.
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serveGRPC listens on the gRPC address of the server and serves requests.
+func (s server) serveGRPC() error {
+	listener, err := net.Listen("tcp", s.grpcAddress)
+	if err != nil {
+		return err
+	}
+	return s.grpcServer.Serve(listener)
+}
+
+// stopGRPC stops the gRPC server after the pending requests are finished,
+// or right away when the context is done first.
+func (s server) stopGRPC(ctx context.Context) {
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+	}
+}
+
+func (g *grpcService) CreateNote(ctx context.Context, req *notespb.CreateNoteRequest) (*notespb.Note, error) {
+	note, err := g.notes.CreateNote(ctx, notes.Note{Category: req.GetCategory(), Note: req.GetNote()})
+	if err != nil {
+		g.log.ErrorContext(ctx, "Failed to create a note.", logError(err, "grpc.CreateNote")...)
+		return nil, grpcError(err)
+	}
+	g.log.InfoContext(ctx, "Note is created.", "type", "service", "name", "noteService", "method", "Create", "noteCategory", note.Category, "noteID", note.ID)
+	return toNotePB(note), nil
+}
+
+func (g *grpcService) GetNote(ctx context.Context, req *notespb.GetNoteRequest) (*notespb.Note, error) {
+	note, err := g.notes.GetNoteByID(ctx, req.GetCategory(), req.GetId())
+	if err != nil {
+		g.log.ErrorContext(ctx, "Failed to get the note.", logError(err, "grpc.GetNote")...)
+		return nil, grpcError(err)
+	}
+	return toNotePB(note), nil
+}
+
+func (g *grpcService) UpdateNote(ctx context.Context, req *notespb.UpdateNoteRequest) (*notespb.Note, error) {
+	note, err := g.notes.UpdateNote(ctx, notes.Note{ID: req.GetId(), Category: req.GetCategory(), Note: req.GetNote()})
+	if err != nil {
+		g.log.ErrorContext(ctx, "Failed to update the note.", logError(err, "grpc.UpdateNote")...)
+		return nil, grpcError(err)
+	}
+	g.log.InfoContext(ctx, "Note is updated.", "type", "service", "name", "noteService", "method", "Update", "noteCategory", note.Category, "noteID", note.ID)
+	return toNotePB(note), nil
+}
+
+func (g *grpcService) DeleteNote(ctx context.Context, req *notespb.DeleteNoteRequest) (*notespb.DeleteNoteResponse, error) {
+	if err := g.notes.DeleteNote(ctx, toDeleteNote(req.GetCategory(), req.GetId())); err != nil {
+		g.log.ErrorContext(ctx, "Failed to delete the note.", logError(err, "grpc.DeleteNote")...)
+		return nil, grpcError(err)
+	}
+	g.log.InfoContext(ctx, "Note is deleted.", "type", "service", "name", "noteService", "method", "Delete", "noteCategory", req.GetCategory(), "noteID", req.GetId())
+	return &notespb.DeleteNoteResponse{}, nil
+}
+
+func (g *grpcService) ListNotes(req *notespb.ListNotesRequest, stream grpc.ServerStreamingServer[notespb.Note]) error {
+	ctx := stream.Context()
+	notes, err := g.notes.GetNotesByCategory(ctx, req.GetCategory())
+	if err != nil {
+		g.log.ErrorContext(ctx, "Failed to list the notes.", logError(err, "grpc.ListNotes")...)
+		return grpcError(err)
+	}
+	for _, note := range notes {
+		if err := stream.Send(toNotePB(note)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *grpcService) ListCategories(req *notespb.ListCategoriesRequest, stream grpc.ServerStreamingServer[notespb.Category]) error {
+	ctx := stream.Context()
+	categories, err := g.notes.ListCategories(ctx)
+	if err != nil {
+		g.log.ErrorContext(ctx, "Failed to list the categories.", logError(err, "grpc.ListCategories")...)
+		return grpcError(err)
+	}
+	for _, category := range categories {
+		if err := stream.Send(&notespb.Category{Name: category}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toNotePB(note notes.Note) *notespb.Note {
+	return &notespb.Note{
+		Id:       note.ID,
+		Category: note.Category,
+		Note:     note.Note,
+	}
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/KatrinSalt/notes-service/api/notespb"
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_grpcService_unary(t *testing.T) {
+	note := notes.Note{ID: "1", Category: "work", Note: "note"}
+
+	tests := []struct {
+		name     string
+		service  *stubNotesService
+		call     func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error)
+		want     proto.Message
+		wantCode codes.Code
+		wantMsg  string
+	}{
+		{
+			name:    "CreateNote() - created",
+			service: &stubNotesService{note: note},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.CreateNote(ctx, &notespb.CreateNoteRequest{Category: "work", Note: "note"})
+			},
+			want:     &notespb.Note{Id: "1", Category: "work", Note: "note"},
+			wantCode: codes.OK,
+		},
+		{
+			name:    "CreateNote() - invalid input",
+			service: &stubNotesService{err: notes.ErrInvalidInput},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.CreateNote(ctx, &notespb.CreateNoteRequest{Category: "work"})
+			},
+			wantCode: codes.InvalidArgument,
+			wantMsg:  "invalid input",
+		},
+		{
+			name:    "CreateNote() - already exists",
+			service: &stubNotesService{err: notes.ErrAlreadyExists},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.CreateNote(ctx, &notespb.CreateNoteRequest{Category: "work", Note: "note"})
+			},
+			wantCode: codes.AlreadyExists,
+			wantMsg:  "already exists",
+		},
+		{
+			name:    "GetNote() - found",
+			service: &stubNotesService{note: note},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.GetNote(ctx, &notespb.GetNoteRequest{Category: "work", Id: "1"})
+			},
+			want:     &notespb.Note{Id: "1", Category: "work", Note: "note"},
+			wantCode: codes.OK,
+		},
+		{
+			name:    "GetNote() - not found",
+			service: &stubNotesService{err: fmt.Errorf("category work, id 1: %w", notes.ErrNotFound)},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.GetNote(ctx, &notespb.GetNoteRequest{Category: "work", Id: "1"})
+			},
+			wantCode: codes.NotFound,
+			wantMsg:  "category work, id 1: not found",
+		},
+		{
+			name:    "UpdateNote() - unavailable",
+			service: &stubNotesService{err: notes.ErrUnavailable},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.UpdateNote(ctx, &notespb.UpdateNoteRequest{Category: "work", Id: "1", Note: "note"})
+			},
+			wantCode: codes.Unavailable,
+			wantMsg:  "service is unavailable",
+		},
+		{
+			name:    "DeleteNote() - deleted",
+			service: &stubNotesService{},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.DeleteNote(ctx, &notespb.DeleteNoteRequest{Category: "work", Id: "1"})
+			},
+			want:     &notespb.DeleteNoteResponse{},
+			wantCode: codes.OK,
+		},
+		{
+			name:    "DeleteNote() - internal error is not exposed",
+			service: &stubNotesService{err: fmt.Errorf("%w: connection reset", notes.ErrService)},
+			call: func(ctx context.Context, client notespb.NotesServiceClient) (proto.Message, error) {
+				return client.DeleteNote(ctx, &notespb.DeleteNoteRequest{Category: "work", Id: "1"})
+			},
+			wantCode: codes.Internal,
+			wantMsg:  "internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestGRPCClient(t, tt.service)
+
+			got, err := tt.call(context.Background(), client)
+			require.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				require.Equal(t, tt.wantMsg, status.Convert(err).Message())
+				return
+			}
+			require.True(t, proto.Equal(tt.want, got), "got %v, want %v", got, tt.want)
+		})
+	}
+}
+
+func Test_grpcService_streams(t *testing.T) {
+	service := &stubNotesService{
+		notes: []notes.Note{
+			{ID: "1", Category: "work", Note: "first"},
+			{ID: "2", Category: "work", Note: "second"},
+		},
+		categories: []string{"home", "work"},
+	}
+	client := newTestGRPCClient(t, service)
+	ctx := context.Background()
+
+	notesStream, err := client.ListNotes(ctx, &notespb.ListNotesRequest{Category: "work"})
+	require.NoError(t, err)
+	var ids []string
+	for {
+		note, err := notesStream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, note.GetId())
+	}
+	require.Equal(t, []string{"1", "2"}, ids)
+
+	categoriesStream, err := client.ListCategories(ctx, &notespb.ListCategoriesRequest{})
+	require.NoError(t, err)
+	var categories []string
+	for {
+		category, err := categoriesStream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		categories = append(categories, category.GetName())
+	}
+	require.Equal(t, []string{"home", "work"}, categories)
+
+	service.err = notes.ErrNotFound
+	notesStream, err = client.ListNotes(ctx, &notespb.ListNotesRequest{Category: "none"})
+	require.NoError(t, err)
+	_, err = notesStream.Recv()
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func Test_grpcService_rateLimit(t *testing.T) {
+	service := &stubNotesService{note: notes.Note{ID: "1", Category: "work", Note: "note"}}
+	client := newTestGRPCClient(t, service,
+		WithRateLimit(RateLimit{Rate: 0.001, Burst: 1}, RateLimit{Rate: 0.001, Burst: 1}),
+		WithAPIKeys("key"),
+	)
+	ctx := context.Background()
+
+	_, err := client.CreateNote(ctx, &notespb.CreateNoteRequest{Category: "work", Note: "note"})
+	require.NoError(t, err)
+	var header metadata.MD
+	_, err = client.CreateNote(ctx, &notespb.CreateNoteRequest{Category: "work", Note: "note"}, grpc.Header(&header))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.NotEmpty(t, header.Get("retry-after"))
+
+	// Reads are counted against their own bucket, as are streams.
+	_, err = client.GetNote(ctx, &notespb.GetNoteRequest{Category: "work", Id: "1"})
+	require.NoError(t, err)
+	stream, err := client.ListNotes(ctx, &notespb.ListNotesRequest{Category: "work"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// A client with an API key of the server has its own bucket.
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", "key")
+	_, err = client.CreateNote(ctx, &notespb.CreateNoteRequest{Category: "work", Note: "note"})
+	require.NoError(t, err)
+}
+
+// newTestGRPCClient serves the gRPC API of a server with the notes service
+// over an in-memory connection and returns a client of it.
+func newTestGRPCClient(t *testing.T, service notes.Service, options ...Option) notespb.NotesServiceClient {
+	t.Helper()
+
+	s, err := New(service, append([]Option{WithLogger(&mockLogger{}), WithGRPC("bufconn")}, options...)...)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	go s.grpcServer.Serve(listener)
+	t.Cleanup(s.grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return notespb.NewNotesServiceClient(conn)
+}
+
+// stubNotesService returns the same note, notes, categories and error
+// for every call.
+type stubNotesService struct {
+	note       notes.Note
+	notes      []notes.Note
+	categories []string
+	err        error
+}
+
+func (s *stubNotesService) CreateNote(ctx context.Context, note notes.Note) (notes.Note, error) {
+	return s.note, s.err
+}
+
+func (s *stubNotesService) UpdateNote(ctx context.Context, note notes.Note) (notes.Note, error) {
+	return s.note, s.err
+}
+
+func (s *stubNotesService) DeleteNote(ctx context.Context, note notes.Note) error {
+	return s.err
+}
+
+func (s *stubNotesService) GetNotesByCategory(ctx context.Context, category string) ([]notes.Note, error) {
+	return s.notes, s.err
+}
+
+func (s *stubNotesService) GetNotesByCategories(ctx context.Context, categories []string) (map[string][]notes.Note, error) {
+	grouped := make(map[string][]notes.Note)
+	for _, note := range s.notes {
+		grouped[note.Category] = append(grouped[note.Category], note)
+	}
+	return grouped, s.err
+}
+
+func (s *stubNotesService) ListCategories(ctx context.Context) ([]string, error) {
+	return s.categories, s.err
+}
+
+func (s *stubNotesService) GetNoteByID(ctx context.Context, category, id string) (notes.Note, error) {
+	return s.note, s.err
+}
@@ -20,6 +20,9 @@ func encode[T any](w http.ResponseWriter, status int, v T) error {
 
 // decode reads the request body as JSON and decodes it into the given value.
 func decode[T any](r *http.Request) (T, error) {
+	_, span := tracer.Start(r.Context(), "server.decode")
+	defer span.End()
+
 	var v T
 	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
 		var syntaxError *json.SyntaxError
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/api"
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+const (
+	// pathAdmin is the path of the administration API.
+	pathAdmin = "/admin"
+	// contentTypeNDJSON is the content type of exports and imports, with
+	// a JSON object per line.
+	contentTypeNDJSON = "application/x-ndjson"
+	// maxImportLineSize is the maximum size of a line of an import.
+	maxImportLineSize = 1 << 20
+	// maxImportBodySize is the maximum size of the body of an import.
+	maxImportBodySize = 32 << 20
+)
+
+// noteArchive is the interface that wraps around the methods to export
+// and import notes.
+type noteArchive interface {
+	Export(ctx context.Context, fn func(note notes.ArchivedNote) error) error
+	ExportCategory(ctx context.Context, category string, fn func(note notes.ArchivedNote) error) error
+	Import(ctx context.Context, notes []notes.ArchivedNote, options notes.ImportOptions) (notes.ImportReport, error)
+}
+
+// exportNotes streams every note of all categories as NDJSON. Errors
+// that happen after the first note is written end the response early.
+func (s server) exportNotes() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The export is as long as there are notes, so the write timeout
+		// of the server does not apply.
+		rc := http.NewResponseController(w)
+		rc.SetWriteDeadline(time.Time{})
+
+		started := false
+		start := func() {
+			w.Header().Set("Content-Type", contentTypeNDJSON)
+			w.Header().Set("Content-Disposition", `attachment; filename="notes.ndjson"`)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+
+		count := 0
+		enc := json.NewEncoder(w)
+		err := s.archive.Export(r.Context(), func(note notes.ArchivedNote) error {
+			if !started {
+				start()
+			}
+			count++
+			return enc.Encode(toExportedNoteAPI(note))
+		})
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to export notes.", logError(err, "exportNotes")...)
+			if started {
+				return
+			}
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+		if !started {
+			start()
+		}
+		s.log.InfoContext(r.Context(), "Notes are exported.", "type", "service", "name", "archive", "method", "Export", "notes", count)
+	})
+}
+
+// importNotes imports the notes of an NDJSON body. The mode query
+// parameter is create (the default), upsert or skip-existing, and
+// dryRun=true reports what the import would do without writing notes.
+func (s server) importNotes() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		options := notes.ImportOptions{Mode: notes.ImportCreate}
+		if mode := r.URL.Query().Get("mode"); len(mode) > 0 {
+			options.Mode = notes.ImportMode(mode)
+		}
+		if dryRun := r.URL.Query().Get("dryRun"); len(dryRun) > 0 {
+			var err error
+			if options.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+				writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Errorf("%w: invalid dryRun", ErrInvalidRequest))
+				return
+			}
+		}
+
+		imported, lines, err := decodeNDJSON(w, r)
+		if err != nil {
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+
+		report, err := s.archive.Import(r.Context(), imported, options)
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to import notes.", logError(err, "importNotes")...)
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+
+		response := api.ImportResponse{
+			Message: "Notes are imported",
+			Report:  toImportReportAPI(report, lines, nil),
+		}
+		if report.DryRun {
+			response.Message = "Notes would be imported"
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to import notes.", logError(err, "importNotes")...)
+			writeServerError(w)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Notes are imported.", "type", "service", "name", "archive", "method", "Import", "mode", report.Mode, "dryRun", report.DryRun, "created", report.Created, "updated", report.Updated, "skipped", report.Skipped, "failed", report.Failed)
+	})
+}
+
+// decodeNDJSON reads the notes of an NDJSON body, and returns them with
+// their line numbers. Empty lines are skipped.
+func decodeNDJSON(w http.ResponseWriter, r *http.Request) ([]notes.ArchivedNote, []int, error) {
+	var imported []notes.ArchivedNote
+	var lines []int
+
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxImportBodySize))
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var note api.ExportedNote
+		if err := json.Unmarshal(data, &note); err != nil {
+			return nil, nil, fmt.Errorf("%w: line %d: %s", ErrMalformedRequestBody, line, err)
+		}
+		imported = append(imported, toArchivedNote(note))
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, nil, fmt.Errorf("%w: line is too long", ErrMalformedRequestBody)
+		}
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, nil, fmt.Errorf("%w: body is larger than %d bytes", ErrMalformedRequestBody, maxImportBodySize)
+		}
+		return nil, nil, err
+	}
+	if len(imported) == 0 {
+		return nil, nil, ErrEmptyRequestBody
+	}
+	return imported, lines, nil
+}
+
+func toExportedNoteAPI(note notes.ArchivedNote) api.ExportedNote {
+	return api.ExportedNote{
+		ID:        note.ID,
+		Category:  note.Category,
+		Note:      note.Note,
+		CreatedAt: note.CreatedAt,
+	}
+}
+
+func toArchivedNote(note api.ExportedNote) notes.ArchivedNote {
+	return notes.ArchivedNote{
+		ID:        note.ID,
+		Category:  note.Category,
+		Note:      note.Note,
+		CreatedAt: note.CreatedAt,
+	}
+}
+
+// toImportReportAPI converts the report, with the line numbers or the
+// file names of the imported notes.
+func toImportReportAPI(report notes.ImportReport, lines []int, files []string) api.ImportReport {
+	r := api.ImportReport{
+		Mode:    string(report.Mode),
+		DryRun:  report.DryRun,
+		Total:   report.Total,
+		Created: report.Created,
+		Updated: report.Updated,
+		Skipped: report.Skipped,
+		Failed:  report.Failed,
+		Errors:  []api.ImportError{},
+	}
+	for _, e := range report.Errors {
+		importErr := api.ImportError{
+			ID:      e.ID,
+			Message: e.Err.Error(),
+		}
+		if lines != nil {
+			importErr.Line = lines[e.Index]
+		}
+		if files != nil {
+			importErr.File = files[e.Index]
+		}
+		r.Errors = append(r.Errors, importErr)
+	}
+	return r
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_exportNotes(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		archive         *stubArchive
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name: "exportNotes() - streams the notes as NDJSON",
+			archive: &stubArchive{notes: []notes.ArchivedNote{
+				{ID: "1", Category: "home", Note: "note", CreatedAt: createdAt},
+				{ID: "2", Category: "work", Note: "other", CreatedAt: createdAt},
+			}},
+			wantStatus:      http.StatusOK,
+			wantContentType: contentTypeNDJSON,
+			wantBody: `{"id":"1","category":"home","note":"note","createdAt":"2024-01-02T03:04:05Z"}` + "\n" +
+				`{"id":"2","category":"work","note":"other","createdAt":"2024-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name:            "exportNotes() - no notes",
+			archive:         &stubArchive{},
+			wantStatus:      http.StatusOK,
+			wantContentType: contentTypeNDJSON,
+		},
+		{
+			name:            "exportNotes() - database is unavailable",
+			archive:         &stubArchive{err: notes.ErrUnavailable},
+			wantStatus:      http.StatusServiceUnavailable,
+			wantContentType: "application/json",
+			wantBody:        `{"statusCode":503,"code":"ServiceUnavailable","message":"service is unavailable"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithArchive(tt.archive), WithAdmin())
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+			require.Equal(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func Test_importNotes(t *testing.T) {
+	body := `{"id":"1","category":"work","note":"note","createdAt":"2024-01-02T03:04:05Z"}` + "\n\n" +
+		`{"category":"home","note":"new"}` + "\n"
+
+	tests := []struct {
+		name        string
+		target      string
+		body        string
+		wantStatus  int
+		wantBody    string
+		wantOptions notes.ImportOptions
+		wantNotes   []notes.ArchivedNote
+	}{
+		{
+			name:        "importNotes() - create only by default",
+			target:      "/admin/import",
+			body:        body,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"message":"Notes are imported","report":{"mode":"create","dryRun":false,"total":2,"created":1,"updated":0,"skipped":0,"failed":1,"errors":[{"line":1,"id":"1","message":"already exists"}]}}`,
+			wantOptions: notes.ImportOptions{Mode: notes.ImportCreate},
+			wantNotes: []notes.ArchivedNote{
+				{ID: "1", Category: "work", Note: "note", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				{Category: "home", Note: "new"},
+			},
+		},
+		{
+			name:        "importNotes() - dry run of an upsert",
+			target:      "/admin/import?mode=upsert&dryRun=true",
+			body:        body,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"message":"Notes would be imported","report":{"mode":"upsert","dryRun":true,"total":2,"created":1,"updated":0,"skipped":0,"failed":1,"errors":[{"line":1,"id":"1","message":"already exists"}]}}`,
+			wantOptions: notes.ImportOptions{Mode: notes.ImportUpsert, DryRun: true},
+			wantNotes: []notes.ArchivedNote{
+				{ID: "1", Category: "work", Note: "note", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				{Category: "home", Note: "new"},
+			},
+		},
+		{
+			name:       "importNotes() - malformed line",
+			target:     "/admin/import",
+			body:       `{"category":"work","note":"note"}` + "\n" + `{"category":`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"MalformedRequestBody","message":"malformed request body: line 2: unexpected end of JSON input"}`,
+		},
+		{
+			name:       "importNotes() - body too large",
+			target:     "/admin/import",
+			body:       strings.Repeat("\n", maxImportBodySize+1),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"MalformedRequestBody","message":"malformed request body: body is larger than 33554432 bytes"}`,
+		},
+		{
+			name:       "importNotes() - empty body",
+			target:     "/admin/import",
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"EmptyRequestBody","message":"empty request body"}`,
+		},
+		{
+			name:       "importNotes() - invalid dry run",
+			target:     "/admin/import?dryRun=maybe",
+			body:       body,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"InvalidRequest","message":"invalid request: invalid dryRun"}`,
+		},
+		{
+			name:        "importNotes() - unknown mode",
+			target:      "/admin/import?mode=merge",
+			body:        body,
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"statusCode":400,"code":"InvalidInput","message":"invalid input"}`,
+			wantOptions: notes.ImportOptions{Mode: "merge"},
+			wantNotes: []notes.ArchivedNote{
+				{ID: "1", Category: "work", Note: "note", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				{Category: "home", Note: "new"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := &stubArchive{}
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithArchive(archive), WithAdmin())
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.JSONEq(t, tt.wantBody, rr.Body.String())
+			require.Equal(t, tt.wantOptions, archive.options)
+			require.Equal(t, tt.wantNotes, archive.imported)
+		})
+	}
+}
+
+func Test_routes_archiveWithOptions(t *testing.T) {
+	archive := &stubArchive{notes: []notes.ArchivedNote{{ID: "1", Category: "work", Note: "note"}}}
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithOptions(Options{Archive: archive}))
+	require.NoError(t, err)
+	s.routes()
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/notes/categories/work/export.zip", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+// stubArchive exports its notes, and reports the notes with an ID as
+// existing notes when they are imported.
+type stubArchive struct {
+	notes    []notes.ArchivedNote
+	err      error
+	imported []notes.ArchivedNote
+	options  notes.ImportOptions
+}
+
+func (a *stubArchive) Export(ctx context.Context, fn func(note notes.ArchivedNote) error) error {
+	if a.err != nil {
+		return a.err
+	}
+	for _, note := range a.notes {
+		if err := fn(note); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *stubArchive) ExportCategory(ctx context.Context, category string, fn func(note notes.ArchivedNote) error) error {
+	if a.err != nil {
+		return a.err
+	}
+	found := false
+	for _, note := range a.notes {
+		if note.Category != category {
+			continue
+		}
+		found = true
+		if err := fn(note); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return notes.ErrNotFound
+	}
+	return nil
+}
+
+func (a *stubArchive) Import(ctx context.Context, imported []notes.ArchivedNote, options notes.ImportOptions) (notes.ImportReport, error) {
+	a.imported = imported
+	a.options = options
+	switch options.Mode {
+	case notes.ImportCreate, notes.ImportUpsert, notes.ImportSkipExisting:
+	default:
+		return notes.ImportReport{}, notes.ErrInvalidInput
+	}
+
+	report := notes.ImportReport{Mode: options.Mode, DryRun: options.DryRun, Total: len(imported)}
+	for i, note := range imported {
+		if len(note.ID) > 0 {
+			report.Failed++
+			report.Errors = append(report.Errors, notes.ImportError{Index: i, ID: note.ID, Err: notes.ErrAlreadyExists})
+			continue
+		}
+		report.Created++
+	}
+	return report, nil
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/KatrinSalt/notes-service/api"
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+// noteHistory is the interface that wraps around the methods to read the
+// history of a note and restore it.
+type noteHistory interface {
+	Revisions(ctx context.Context, category, id string) ([]notes.Revision, error)
+	Restore(ctx context.Context, category, id, commit string) (notes.Note, error)
+}
+
+// noteRevisions returns the changes of a note, newest first.
+func (s server) noteRevisions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		category := r.PathValue("category")
+		id := r.PathValue("id")
+
+		revisions, err := s.history.Revisions(r.Context(), category, id)
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to get the history of the note.", logError(err, "noteRevisions")...)
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+
+		response := api.HistoryResponse{
+			Revisions: toRevisionsAPI(revisions),
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to get the history of the note.", logError(err, "noteRevisions")...)
+			writeServerError(w)
+			return
+		}
+	})
+}
+
+// restoreNote writes a note as it was after a commit of its history.
+func (s server) restoreNote() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		category := r.PathValue("category")
+		id := r.PathValue("id")
+
+		req, err := decode[api.RestoreRequest](r)
+		if err != nil {
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+		if len(req.Commit) == 0 {
+			writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Errorf("%w: commit is required", ErrInvalidRequest))
+			return
+		}
+
+		data, err := s.history.Restore(r.Context(), category, id, req.Commit)
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to restore the note.", logError(err, "restoreNote")...)
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+
+		response := api.NoteResponse{
+			Message: "Note is restored",
+			Note:    toNoteAPI(data),
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to restore the note.", logError(err, "restoreNote")...)
+			writeServerError(w)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Note is restored.", "type", "service", "name", "noteService", "method", "Restore", "noteCategory", data.Category, "noteID", data.ID, "commit", req.Commit)
+	})
+}
+
+func toRevisionsAPI(revisions []notes.Revision) []api.Revision {
+	revisionsAPI := make([]api.Revision, len(revisions))
+	for i, revision := range revisions {
+		revisionsAPI[i] = api.Revision{
+			Commit:  revision.Commit,
+			Author:  api.Author{Name: revision.Author.Name, Email: revision.Author.Email},
+			Time:    revision.Time,
+			Message: revision.Message,
+			Deleted: revision.Deleted,
+		}
+		if !revision.Deleted {
+			note := toNoteAPI(revision.Note)
+			revisionsAPI[i].Note = &note
+		}
+	}
+	return revisionsAPI
+}
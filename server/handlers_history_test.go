@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_noteRevisions(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		history    *stubHistory
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "noteRevisions() - lists the changes",
+			history: &stubHistory{revisions: []notes.Revision{
+				{Commit: "b2", Author: notes.Author{Name: "Bob"}, Time: created.Add(time.Hour), Message: "Delete note work/1.md", Deleted: true},
+				{Commit: "a1", Author: notes.Author{Name: "Alice", Email: "alice@example.com"}, Time: created, Message: "Create note work/1.md", Note: notes.Note{ID: "1", Category: "work", Note: "note"}},
+			}},
+			wantStatus: http.StatusOK,
+			wantBody: `{"revisions":[` +
+				`{"commit":"b2","author":{"name":"Bob"},"time":"2024-01-02T04:04:05Z","message":"Delete note work/1.md","deleted":true},` +
+				`{"commit":"a1","author":{"name":"Alice","email":"alice@example.com"},"time":"2024-01-02T03:04:05Z","message":"Create note work/1.md","note":{"id":"1","category":"work","note":"note"}}]}`,
+		},
+		{
+			name:       "noteRevisions() - note without history",
+			history:    &stubHistory{err: notes.ErrNotFound},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"statusCode":404,"code":"NotFound","message":"not found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithHistory(tt.history))
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/categories/work/notes/1/history", nil))
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.JSONEq(t, tt.wantBody, rr.Body.String())
+			require.Equal(t, "work", tt.history.category)
+			require.Equal(t, "1", tt.history.id)
+		})
+	}
+}
+
+func Test_restoreNote(t *testing.T) {
+	tests := []struct {
+		name       string
+		history    *stubHistory
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCommit string
+	}{
+		{
+			name:       "restoreNote() - restores the note",
+			history:    &stubHistory{note: notes.Note{ID: "1", Category: "work", Note: "note"}},
+			body:       `{"commit":"a1b2c3"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"Note is restored","note":{"id":"1","category":"work","note":"note"}}`,
+			wantCommit: "a1b2c3",
+		},
+		{
+			name:       "restoreNote() - missing commit",
+			history:    &stubHistory{},
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"InvalidRequest","message":"invalid request: commit is required"}`,
+		},
+		{
+			name:       "restoreNote() - unknown commit",
+			history:    &stubHistory{err: notes.ErrNotFound},
+			body:       `{"commit":"ffff"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"statusCode":404,"code":"NotFound","message":"not found"}`,
+			wantCommit: "ffff",
+		},
+		{
+			name:       "restoreNote() - invalid commit",
+			history:    &stubHistory{err: notes.ErrInvalidInput},
+			body:       `{"commit":"HEAD"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"InvalidInput","message":"invalid input"}`,
+			wantCommit: "HEAD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithHistory(tt.history))
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/categories/work/notes/1/restore", strings.NewReader(tt.body))
+			s.router.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.JSONEq(t, tt.wantBody, rr.Body.String())
+			require.Equal(t, tt.wantCommit, tt.history.commit)
+		})
+	}
+}
+
+func Test_routes_historyRequiresWithHistory(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}))
+	require.NoError(t, err)
+	s.routes()
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/categories/work/notes/1/history", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func Test_routes_historyWithOptions(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithOptions(Options{History: &stubHistory{}}))
+	require.NoError(t, err)
+	s.routes()
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/categories/work/notes/1/history", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+type stubHistory struct {
+	revisions []notes.Revision
+	note      notes.Note
+	err       error
+	category  string
+	id        string
+	commit    string
+}
+
+func (h *stubHistory) Revisions(ctx context.Context, category, id string) ([]notes.Revision, error) {
+	h.category, h.id = category, id
+	if h.err != nil {
+		return nil, h.err
+	}
+	return h.revisions, nil
+}
+
+func (h *stubHistory) Restore(ctx context.Context, category, id, commit string) (notes.Note, error) {
+	h.category, h.id, h.commit = category, id, commit
+	if h.err != nil {
+		return notes.Note{}, h.err
+	}
+	return h.note, nil
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/api"
+	"github.com/KatrinSalt/notes-service/markdown"
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+const (
+	// contentTypeZip is the content type of the Markdown archives of a
+	// category.
+	contentTypeZip = "application/zip"
+	// maxImportArchiveSize is the maximum size of an imported archive.
+	maxImportArchiveSize = 32 << 20
+	// maxImportFileSize is the maximum decompressed size of a file of an
+	// imported archive.
+	maxImportFileSize = 1 << 20
+	// maxImportExtractedSize is the maximum decompressed size of the
+	// files of an imported archive.
+	maxImportExtractedSize = 64 << 20
+)
+
+// errFileTooLarge is returned when a file of an imported archive is
+// larger than its limit.
+var errFileTooLarge = errors.New("file is too large")
+
+// exportCategoryZip streams the notes of a category as a zip archive
+// with a Markdown file per note. Errors that happen after the first note
+// is written end the response early, and leave the archive incomplete.
+func (s server) exportCategoryZip() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		category := r.PathValue("category")
+
+		rc := http.NewResponseController(w)
+		rc.SetWriteDeadline(time.Time{})
+
+		var zw *zip.Writer
+		start := func() {
+			w.Header().Set("Content-Type", contentTypeZip)
+			w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(category+".zip"))
+			w.WriteHeader(http.StatusOK)
+			zw = zip.NewWriter(w)
+		}
+
+		count := 0
+		err := s.archive.ExportCategory(r.Context(), category, func(note notes.ArchivedNote) error {
+			if zw == nil {
+				start()
+			}
+			data, err := markdown.Marshal(toMarkdownNote(note))
+			if err != nil {
+				return err
+			}
+			f, err := zw.CreateHeader(&zip.FileHeader{
+				Name:     markdown.FileName(note.ID),
+				Method:   zip.Deflate,
+				Modified: note.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(data); err != nil {
+				return err
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to export notes.", logError(err, "exportCategoryZip")...)
+			if zw != nil {
+				return
+			}
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+		if zw == nil {
+			start()
+		}
+		if err := zw.Close(); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to export notes.", logError(err, "exportCategoryZip")...)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Notes are exported.", "type", "service", "name", "archive", "method", "ExportCategory", "noteCategory", category, "notes", count)
+	})
+}
+
+// importCategoryZip imports the Markdown files of a zip archive into a
+// category. The category of the path is used over the category of the
+// front-matter, and notes without an ID in their front-matter get the
+// name of their file. The mode and dryRun query parameters are the same
+// as for the import of all notes.
+func (s server) importCategoryZip() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		category := r.PathValue("category")
+
+		options := notes.ImportOptions{Mode: notes.ImportCreate}
+		if mode := r.URL.Query().Get("mode"); len(mode) > 0 {
+			options.Mode = notes.ImportMode(mode)
+		}
+		if dryRun := r.URL.Query().Get("dryRun"); len(dryRun) > 0 {
+			var err error
+			if options.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+				writeError(w, http.StatusBadRequest, "InvalidRequest", fmt.Errorf("%w: invalid dryRun", ErrInvalidRequest))
+				return
+			}
+		}
+
+		imported, files, err := decodeMarkdownZip(w, r, category)
+		if err != nil {
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			s.log.ErrorContext(r.Context(), "Failed to import notes.", logError(err, "importCategoryZip")...)
+			writeServerError(w)
+			return
+		}
+
+		report, err := s.archive.Import(r.Context(), imported, options)
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to import notes.", logError(err, "importCategoryZip")...)
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+
+		response := api.ImportResponse{
+			Message: "Notes are imported",
+			Report:  toImportReportAPI(report, nil, files),
+		}
+		if report.DryRun {
+			response.Message = "Notes would be imported"
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to import notes.", logError(err, "importCategoryZip")...)
+			writeServerError(w)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Notes are imported.", "type", "service", "name", "archive", "method", "Import", "noteCategory", category, "mode", report.Mode, "dryRun", report.DryRun, "created", report.Created, "updated", report.Updated, "skipped", report.Skipped, "failed", report.Failed)
+	})
+}
+
+// decodeMarkdownZip reads the Markdown files of a zip body, and returns
+// the notes with the names of their files. Directories in the archive
+// are ignored, as are files that are not Markdown files.
+func decodeMarkdownZip(w http.ResponseWriter, r *http.Request, category string) ([]notes.ArchivedNote, []string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportArchiveSize))
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, nil, fmt.Errorf("%w: archive is larger than %d bytes", ErrMalformedRequestBody, maxImportArchiveSize)
+		}
+		return nil, nil, err
+	}
+	if len(body) == 0 {
+		return nil, nil, ErrEmptyRequestBody
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrMalformedRequestBody, err)
+	}
+
+	var imported []notes.ArchivedNote
+	var files []string
+	var extracted int64
+	for _, f := range zr.File {
+		name := path.Base(f.Name)
+		if f.FileInfo().IsDir() || path.Ext(name) != markdown.Extension {
+			continue
+		}
+		data, err := readZipFile(f, maxImportFileSize)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %s", ErrMalformedRequestBody, f.Name, err)
+		}
+		extracted += int64(len(data))
+		if extracted > maxImportExtractedSize {
+			return nil, nil, fmt.Errorf("%w: archive is larger than %d bytes when extracted", ErrMalformedRequestBody, maxImportExtractedSize)
+		}
+		note, err := markdown.Unmarshal(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %s: %s", ErrMalformedRequestBody, f.Name, err)
+		}
+		if len(note.ID) == 0 {
+			note.ID = strings.TrimSuffix(name, markdown.Extension)
+		}
+		note.Category = category
+		imported = append(imported, toArchivedNoteFromMarkdown(note))
+		files = append(files, f.Name)
+	}
+	if len(imported) == 0 {
+		return nil, nil, fmt.Errorf("%w: archive has no Markdown files", ErrEmptyRequestBody)
+	}
+	return imported, files, nil
+}
+
+// readZipFile reads the content of a file of a zip archive, up to limit
+// bytes. The size in the header of the file is checked first, and the
+// content is read up to the limit regardless of the header, since the
+// header can understate the size.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%w: larger than %d bytes", errFileTooLarge, limit)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: larger than %d bytes", errFileTooLarge, limit)
+	}
+	return data, nil
+}
+
+func toMarkdownNote(note notes.ArchivedNote) markdown.Note {
+	return markdown.Note{
+		ID:       note.ID,
+		Category: note.Category,
+		Created:  note.CreatedAt,
+		Body:     note.Note,
+	}
+}
+
+func toArchivedNoteFromMarkdown(note markdown.Note) notes.ArchivedNote {
+	return notes.ArchivedNote{
+		ID:        note.ID,
+		Category:  note.Category,
+		Note:      note.Body,
+		CreatedAt: note.Created,
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_exportCategoryZip(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		archive         *stubArchive
+		wantStatus      int
+		wantContentType string
+		wantFiles       map[string]string
+		wantBody        string
+	}{
+		{
+			name: "exportCategoryZip() - writes a Markdown file per note",
+			archive: &stubArchive{notes: []notes.ArchivedNote{
+				{ID: "1", Category: "work", Note: "# Note\n\ntext", CreatedAt: createdAt},
+				{ID: "2", Category: "home", Note: "other", CreatedAt: createdAt},
+			}},
+			wantStatus:      http.StatusOK,
+			wantContentType: contentTypeZip,
+			wantFiles: map[string]string{
+				"1.md": "---\nid: \"1\"\ncategory: work\ncreated: 2024-01-02T03:04:05Z\n---\n\n# Note\n\ntext\n",
+			},
+		},
+		{
+			name:            "exportCategoryZip() - category not found",
+			archive:         &stubArchive{},
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/json",
+			wantBody:        `{"statusCode":404,"code":"NotFound","message":"not found"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithArchive(tt.archive))
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/notes/categories/work/export.zip", nil))
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+			if tt.wantFiles == nil {
+				require.Equal(t, tt.wantBody, rr.Body.String())
+				return
+			}
+			require.Equal(t, `attachment; filename="work.zip"`, rr.Header().Get("Content-Disposition"))
+			require.Equal(t, tt.wantFiles, readZip(t, rr.Body.Bytes()))
+		})
+	}
+}
+
+func Test_importCategoryZip(t *testing.T) {
+	archive := newZip(t, map[string]string{
+		"work/1.md":    "---\nid: \"1\"\ncategory: home\ncreated: 2024-01-02T03:04:05Z\n---\n\nnote\n",
+		"work/new.md":  "---\ncreated: 2024-01-02T03:04:05Z\n---\nnew",
+		"work/read.me": "not a note",
+	})
+
+	large := "---\ncreated: 2024-01-02T03:04:05Z\n---\n" + strings.Repeat("a", maxImportFileSize)
+	extracted := map[string]string{}
+	for i := range maxImportExtractedSize/maxImportFileSize + 1 {
+		extracted[strconv.Itoa(i)+".md"] = large[:maxImportFileSize]
+	}
+
+	tests := []struct {
+		name        string
+		target      string
+		body        []byte
+		wantStatus  int
+		wantBody    string
+		wantOptions notes.ImportOptions
+		wantNotes   []notes.ArchivedNote
+	}{
+		{
+			name:        "importCategoryZip() - imports into the category of the path",
+			target:      "/notes/categories/work/import.zip?mode=upsert",
+			body:        archive,
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"message":"Notes are imported","report":{"mode":"upsert","dryRun":false,"total":2,"created":0,"updated":0,"skipped":0,"failed":2,"errors":[{"file":"work/1.md","id":"1","message":"already exists"},{"file":"work/new.md","id":"new","message":"already exists"}]}}`,
+			wantOptions: notes.ImportOptions{Mode: notes.ImportUpsert},
+			wantNotes: []notes.ArchivedNote{
+				{ID: "1", Category: "work", Note: "note", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+				{ID: "new", Category: "work", Note: "new", CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+			},
+		},
+		{
+			name:       "importCategoryZip() - not a zip archive",
+			target:     "/notes/categories/work/import.zip",
+			body:       []byte("notes"),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"MalformedRequestBody","message":"malformed request body: zip: not a valid zip file"}`,
+		},
+		{
+			name:       "importCategoryZip() - invalid Markdown file",
+			target:     "/notes/categories/work/import.zip",
+			body:       newZip(t, map[string]string{"1.md": "# note"}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"MalformedRequestBody","message":"malformed request body: 1.md: invalid markdown note: front-matter is missing"}`,
+		},
+		{
+			name:       "importCategoryZip() - file too large",
+			target:     "/notes/categories/work/import.zip",
+			body:       newZip(t, map[string]string{"1.md": large}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"MalformedRequestBody","message":"malformed request body: 1.md: file is too large: larger than 1048576 bytes"}`,
+		},
+		{
+			name:       "importCategoryZip() - archive too large when extracted",
+			target:     "/notes/categories/work/import.zip",
+			body:       newZip(t, extracted),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"MalformedRequestBody","message":"malformed request body: archive is larger than 67108864 bytes when extracted"}`,
+		},
+		{
+			name:       "importCategoryZip() - no Markdown files",
+			target:     "/notes/categories/work/import.zip",
+			body:       newZip(t, map[string]string{"read.me": "not a note"}),
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"EmptyRequestBody","message":"empty request body: archive has no Markdown files"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := &stubArchive{}
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithArchive(archive))
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tt.target, bytes.NewReader(tt.body)))
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.JSONEq(t, tt.wantBody, rr.Body.String())
+			require.Equal(t, tt.wantOptions, archive.options)
+			require.Equal(t, tt.wantNotes, archive.imported)
+		})
+	}
+}
+
+func Test_routes_adminRequiresWithAdmin(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithArchive(&stubArchive{}))
+	require.NoError(t, err)
+	s.routes()
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func Test_routes_adminWithOptions(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithOptions(Options{Archive: &stubArchive{}, Admin: true}))
+	require.NoError(t, err)
+	s.routes()
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+// newZip returns a zip archive of the files, in the order of their names.
+func newZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// readZip returns the files of a zip archive by their names.
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
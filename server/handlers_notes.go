@@ -1,8 +1,8 @@
 package server
 
 import (
-	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/KatrinSalt/notes-service/api"
 	"github.com/KatrinSalt/notes-service/notes"
@@ -22,9 +22,9 @@ func (s server) createNote() http.Handler {
 			writeError(w, statusCode, code, err)
 			return
 		}
-		data, err := s.notes.CreateNote(toCreateNote(category, noteReq))
+		data, err := s.notes.CreateNote(r.Context(), toCreateNote(category, noteReq))
 		if err != nil {
-			s.log.Error("Failed to create a note.", logError(err, "createNote")...)
+			s.log.ErrorContext(r.Context(), "Failed to create a note.", logError(err, "createNote")...)
 			if statusCode, code := errorCodes(err); statusCode != 0 {
 				writeError(w, statusCode, code, err)
 				return
@@ -38,12 +38,13 @@ func (s server) createNote() http.Handler {
 			Note:    toNoteAPI(data),
 		}
 
+		w.Header().Set("Location", "/v1/categories/"+url.PathEscape(data.Category)+"/notes/"+url.PathEscape(data.ID))
 		if err := encode(w, http.StatusCreated, response); err != nil {
-			s.log.Error("Failed to create a note.", logError(err, "createNote")...)
+			s.log.ErrorContext(r.Context(), "Failed to create a note.", logError(err, "createNote")...)
 			writeServerError(w)
 			return
 		}
-		s.log.Info("Note is created.", "type", "service", "name", "noteService", "method", "Create", "noteCategory", data.Category, "noteID", data.ID)
+		s.log.InfoContext(r.Context(), "Note is created.", "type", "service", "name", "noteService", "method", "Create", "noteCategory", data.Category, "noteID", data.ID)
 	})
 }
 
@@ -63,9 +64,9 @@ func (s server) updateNote() http.Handler {
 
 		note := toUpdateNote(category, id, noteReq)
 
-		data, err := s.notes.UpdateNote(note)
+		data, err := s.notes.UpdateNote(r.Context(), note)
 		if err != nil {
-			s.log.Error("Failed to update the note.", logError(err, "updateNote")...)
+			s.log.ErrorContext(r.Context(), "Failed to update the note.", logError(err, "updateNote")...)
 			if statusCode, code := errorCodes(err); statusCode != 0 {
 				writeError(w, statusCode, code, err)
 				return
@@ -80,11 +81,11 @@ func (s server) updateNote() http.Handler {
 		}
 
 		if err := encode(w, http.StatusOK, response); err != nil {
-			s.log.Error("Failed to update a note.", logError(err, "updateNote")...)
+			s.log.ErrorContext(r.Context(), "Failed to update a note.", logError(err, "updateNote")...)
 			writeServerError(w)
 			return
 		}
-		s.log.Info("Note is updated.", "type", "service", "name", "noteService", "method", "Update", "noteCategory", data.Category, "noteID", data.ID)
+		s.log.InfoContext(r.Context(), "Note is updated.", "type", "service", "name", "noteService", "method", "Update", "noteCategory", data.Category, "noteID", data.ID)
 	})
 }
 
@@ -96,11 +97,10 @@ func (s server) deleteNote() http.Handler {
 		id := r.PathValue("id")
 
 		note := toDeleteNote(category, id)
-		fmt.Printf("handler: note to delete: %v\n", note)
 
-		err := s.notes.DeleteNote(note)
+		err := s.notes.DeleteNote(r.Context(), note)
 		if err != nil {
-			s.log.Error("Failed to delete a note with ID.", logError(err, "deleteNote")...)
+			s.log.ErrorContext(r.Context(), "Failed to delete a note with ID.", logError(err, "deleteNote")...)
 			if statusCode, code := errorCodes(err); statusCode != 0 {
 				writeError(w, statusCode, code, err)
 				return
@@ -114,12 +114,12 @@ func (s server) deleteNote() http.Handler {
 		}
 
 		if err := encode(w, http.StatusOK, response); err != nil {
-			s.log.Error("Failed to delete a note.", logError(err, "deleteNote")...)
+			s.log.ErrorContext(r.Context(), "Failed to delete a note.", logError(err, "deleteNote")...)
 			writeServerError(w)
 			return
 		}
 
-		s.log.Info("Note is deleted.", "type", "service", "name", "noteService", "method", "Delete", "noteCategory", note.Category, "noteID", note.ID)
+		s.log.InfoContext(r.Context(), "Note is deleted.", "type", "service", "name", "noteService", "method", "Delete", "noteCategory", note.Category, "noteID", note.ID)
 	})
 }
 
@@ -129,9 +129,9 @@ func (s server) getNotesByCategory() http.Handler {
 		// it is assumed that the category is provided in the path
 		category := r.PathValue("category")
 
-		data, err := s.notes.GetNotesByCategory(category)
+		data, err := s.notes.GetNotesByCategory(r.Context(), category)
 		if err != nil {
-			s.log.Error("Failed to list notes in the category.", logError(err, "getNotesByCategory")...)
+			s.log.ErrorContext(r.Context(), "Failed to list notes in the category.", logError(err, "getNotesByCategory")...)
 			if statusCode, code := errorCodes(err); statusCode != 0 {
 				writeError(w, statusCode, code, err)
 				return
@@ -146,11 +146,11 @@ func (s server) getNotesByCategory() http.Handler {
 		}
 
 		if err := encode(w, http.StatusOK, response); err != nil {
-			s.log.Error("Failed to list notes in the category.", logError(err, "getNotesByCategory")...)
+			s.log.ErrorContext(r.Context(), "Failed to list notes in the category.", logError(err, "getNotesByCategory")...)
 			writeServerError(w)
 			return
 		}
-		s.log.Info("Notes are listed.", "type", "service", "name", "noteService", "method", "getNotesByCategory", "notesCategory", category)
+		s.log.InfoContext(r.Context(), "Notes are listed.", "type", "service", "name", "noteService", "method", "getNotesByCategory", "notesCategory", category)
 	})
 }
 
@@ -160,9 +160,9 @@ func (s server) getNoteByID() http.Handler {
 		category := r.PathValue("category")
 		id := r.PathValue("id")
 
-		data, err := s.notes.GetNoteByID(category, id)
+		data, err := s.notes.GetNoteByID(r.Context(), category, id)
 		if err != nil {
-			s.log.Error("Failed to get a note.", logError(err, "getNoteByID")...)
+			s.log.ErrorContext(r.Context(), "Failed to get a note.", logError(err, "getNoteByID")...)
 			if statusCode, code := errorCodes(err); statusCode != 0 {
 				writeError(w, statusCode, code, err)
 				return
@@ -177,11 +177,38 @@ func (s server) getNoteByID() http.Handler {
 		}
 
 		if err := encode(w, http.StatusOK, response); err != nil {
-			s.log.Error("Failed to get a note.", logError(err, "getNoteByID")...)
+			s.log.ErrorContext(r.Context(), "Failed to get a note.", logError(err, "getNoteByID")...)
 			writeServerError(w)
 			return
 		}
-		s.log.Info("Note is found.", "type", "service", "name", "noteService", "method", "getNoteByID", "noteID", id)
+		s.log.InfoContext(r.Context(), "Note is found.", "type", "service", "name", "noteService", "method", "getNoteByID", "noteID", id)
+	})
+}
+
+func (s server) listCategories() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		categories, err := s.notes.ListCategories(r.Context())
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to list categories.", logError(err, "listCategories")...)
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+
+		response := api.CategoriesResponse{
+			Message:    "Categories",
+			Categories: categories,
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to list categories.", logError(err, "listCategories")...)
+			writeServerError(w)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Categories are listed.", "type", "service", "name", "noteService", "method", "listCategories")
 	})
 }
 
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_handlers_requestContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{
+			name:   "createNote() - request context is passed to the service",
+			method: http.MethodPost,
+			target: "/notes/create/category",
+			body:   `{"note":"note"}`,
+		},
+		{
+			name:   "updateNote() - request context is passed to the service",
+			method: http.MethodPut,
+			target: "/notes/update/category/1",
+			body:   `{"note":"note"}`,
+		},
+		{
+			name:   "deleteNote() - request context is passed to the service",
+			method: http.MethodDelete,
+			target: "/notes/delete/category/1",
+		},
+		{
+			name:   "getNoteByID() - request context is passed to the service",
+			method: http.MethodGet,
+			target: "/notes/categories/category/ids/1",
+		},
+		{
+			name:   "getNotesByCategory() - request context is passed to the service",
+			method: http.MethodGet,
+			target: "/notes/categories/category",
+		},
+		{
+			name:   "listCategories() - request context is passed to the service",
+			method: http.MethodGet,
+			target: "/notes/categories",
+		},
+		{
+			name:   "createNote() - v1 - request context is passed to the service",
+			method: http.MethodPost,
+			target: "/v1/categories/category/notes",
+			body:   `{"note":"note"}`,
+		},
+		{
+			name:   "updateNote() - v1 - request context is passed to the service",
+			method: http.MethodPut,
+			target: "/v1/categories/category/notes/1",
+			body:   `{"note":"note"}`,
+		},
+		{
+			name:   "deleteNote() - v1 - request context is passed to the service",
+			method: http.MethodDelete,
+			target: "/v1/categories/category/notes/1",
+		},
+		{
+			name:   "getNoteByID() - v1 - request context is passed to the service",
+			method: http.MethodGet,
+			target: "/v1/categories/category/notes/1",
+		},
+		{
+			name:   "getNotesByCategory() - v1 - request context is passed to the service",
+			method: http.MethodGet,
+			target: "/v1/categories/category/notes",
+		},
+		{
+			name:   "listCategories() - v1 - request context is passed to the service",
+			method: http.MethodGet,
+			target: "/v1/categories",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			svc := &mockNotesService{called: make(chan struct{})}
+			s, err := New(svc, WithLogger(&mockLogger{}))
+			require.NoError(t, err)
+			s.routes()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)).WithContext(ctx)
+			rec := httptest.NewRecorder()
+
+			go func() {
+				<-svc.called
+				cancel()
+			}()
+
+			// Act
+			done := make(chan struct{})
+			go func() {
+				s.router.ServeHTTP(rec, req)
+				close(done)
+			}()
+
+			// Assert
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("handler did not return after the request was canceled")
+			}
+			require.ErrorIs(t, svc.err, context.Canceled)
+			require.Equal(t, statusClientClosedRequest, rec.Code)
+		})
+	}
+}
+
+// mockNotesService is a notes service whose calls block until their
+// context is done.
+type mockNotesService struct {
+	called chan struct{}
+	err    error
+}
+
+func (m *mockNotesService) wait(ctx context.Context) error {
+	close(m.called)
+	<-ctx.Done()
+	m.err = ctx.Err()
+	return notes.ErrCanceled
+}
+
+func (m *mockNotesService) CreateNote(ctx context.Context, note notes.Note) (notes.Note, error) {
+	return notes.Note{}, m.wait(ctx)
+}
+
+func (m *mockNotesService) UpdateNote(ctx context.Context, note notes.Note) (notes.Note, error) {
+	return notes.Note{}, m.wait(ctx)
+}
+
+func (m *mockNotesService) DeleteNote(ctx context.Context, note notes.Note) error {
+	return m.wait(ctx)
+}
+
+func (m *mockNotesService) GetNotesByCategory(ctx context.Context, category string) ([]notes.Note, error) {
+	return nil, m.wait(ctx)
+}
+
+func (m *mockNotesService) GetNotesByCategories(ctx context.Context, categories []string) (map[string][]notes.Note, error) {
+	return nil, m.wait(ctx)
+}
+
+func (m *mockNotesService) ListCategories(ctx context.Context) ([]string, error) {
+	return nil, m.wait(ctx)
+}
+
+func (m *mockNotesService) GetNoteByID(ctx context.Context, category, id string) (notes.Note, error) {
+	return notes.Note{}, m.wait(ctx)
+}
+
+// mockLogger discards the logged messages.
+type mockLogger struct{}
+
+func (l *mockLogger) Info(msg string, args ...any)  {}
+func (l *mockLogger) Error(msg string, args ...any) {}
+
+func (l *mockLogger) InfoContext(ctx context.Context, msg string, args ...any)  {}
+func (l *mockLogger) ErrorContext(ctx context.Context, msg string, args ...any) {}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/KatrinSalt/notes-service/api"
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/KatrinSalt/notes-service/webhooks"
+)
+
+// pathWebhooks is the path of the webhooks.
+const pathWebhooks = "/v1/webhooks"
+
+// webhookRegistry is the interface that wraps around the methods to
+// register webhooks and list their deliveries.
+type webhookRegistry interface {
+	Register(ctx context.Context, webhook webhooks.Webhook) (webhooks.Webhook, error)
+	Webhooks() []webhooks.Webhook
+	Webhook(id string) (webhooks.Webhook, error)
+	Delete(id string) error
+	Deliveries(id string) ([]webhooks.Delivery, error)
+}
+
+func (s server) createWebhook() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := decode[api.WebhookRequest](r)
+		if err != nil {
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+
+		webhook, err := s.webhooks.Register(r.Context(), toWebhook(req))
+		if err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to create a webhook.", logError(err, "createWebhook")...)
+			if statusCode, code := errorCodes(err); statusCode != 0 {
+				writeError(w, statusCode, code, err)
+				return
+			}
+			writeServerError(w)
+			return
+		}
+
+		// The secret is only returned when the webhook is created.
+		created := toWebhookAPI(webhook)
+		created.Secret = webhook.Secret
+		response := api.WebhookResponse{
+			Message: "Webhook is created",
+			Webhook: &created,
+		}
+
+		w.Header().Set("Location", pathWebhooks+"/"+webhook.ID)
+		if err := encode(w, http.StatusCreated, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to create a webhook.", logError(err, "createWebhook")...)
+			writeServerError(w)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Webhook is created.", "type", "service", "name", "webhooks", "method", "Register", "webhookID", webhook.ID)
+	})
+}
+
+func (s server) listWebhooks() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registered := s.webhooks.Webhooks()
+		response := api.WebhookResponse{
+			Message:  "Webhooks",
+			Webhooks: make([]api.Webhook, len(registered)),
+		}
+		for i, webhook := range registered {
+			response.Webhooks[i] = toWebhookAPI(webhook)
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to list webhooks.", logError(err, "listWebhooks")...)
+			writeServerError(w)
+			return
+		}
+	})
+}
+
+func (s server) getWebhook() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhook, err := s.webhooks.Webhook(r.PathValue("id"))
+		if err != nil {
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+
+		found := toWebhookAPI(webhook)
+		response := api.WebhookResponse{
+			Message: "Webhook",
+			Webhook: &found,
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to get a webhook.", logError(err, "getWebhook")...)
+			writeServerError(w)
+			return
+		}
+	})
+}
+
+func (s server) deleteWebhook() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if err := s.webhooks.Delete(id); err != nil {
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+
+		response := api.WebhookResponse{
+			Message: "Webhook is deleted",
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to delete a webhook.", logError(err, "deleteWebhook")...)
+			writeServerError(w)
+			return
+		}
+		s.log.InfoContext(r.Context(), "Webhook is deleted.", "type", "service", "name", "webhooks", "method", "Delete", "webhookID", id)
+	})
+}
+
+// listDeliveries lists the recent deliveries of a webhook, newest first.
+// The deliveries are filtered by the status query parameter, for example
+// status=dead-lettered lists the dead-lettered deliveries.
+func (s server) listDeliveries() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries, err := s.webhooks.Deliveries(r.PathValue("id"))
+		if err != nil {
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+
+		status := webhooks.Status(r.URL.Query().Get("status"))
+		response := api.DeliveriesResponse{
+			Message:    "Deliveries",
+			Deliveries: []api.Delivery{},
+		}
+		for _, delivery := range deliveries {
+			if len(status) > 0 && delivery.Status != status {
+				continue
+			}
+			response.Deliveries = append(response.Deliveries, toDeliveryAPI(delivery))
+		}
+
+		if err := encode(w, http.StatusOK, response); err != nil {
+			s.log.ErrorContext(r.Context(), "Failed to list deliveries.", logError(err, "listDeliveries")...)
+			writeServerError(w)
+			return
+		}
+	})
+}
+
+func toWebhook(req api.WebhookRequest) webhooks.Webhook {
+	webhook := webhooks.Webhook{
+		URL:      req.URL,
+		Category: req.Category,
+		Secret:   req.Secret,
+	}
+	for _, event := range req.Events {
+		webhook.Events = append(webhook.Events, notes.EventType(event))
+	}
+	return webhook
+}
+
+// toWebhookAPI converts the webhook without its secret.
+func toWebhookAPI(webhook webhooks.Webhook) api.Webhook {
+	w := api.Webhook{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Category:  webhook.Category,
+		CreatedAt: webhook.CreatedAt,
+	}
+	for _, event := range webhook.Events {
+		w.Events = append(w.Events, string(event))
+	}
+	return w
+}
+
+func toDeliveryAPI(delivery webhooks.Delivery) api.Delivery {
+	return api.Delivery{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		Event:          string(delivery.Event),
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+	}
+}
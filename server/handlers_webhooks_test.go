@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/KatrinSalt/notes-service/webhooks"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_webhooks(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "createWebhook() - returns the secret",
+			method:     http.MethodPost,
+			target:     "/v1/webhooks",
+			body:       `{"url":"https://example.com/hook","category":"work","events":["created"]}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"message":"Webhook is created","webhook":{"id":"2","url":"https://example.com/hook","category":"work","events":["created"],"secret":"generated","createdAt":"2024-01-02T03:04:05Z"}}`,
+		},
+		{
+			name:       "createWebhook() - invalid webhook",
+			method:     http.MethodPost,
+			target:     "/v1/webhooks",
+			body:       `{"url":"/hook"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"statusCode":400,"code":"InvalidWebhook","message":"invalid webhook"}`,
+		},
+		{
+			name:       "listWebhooks() - does not return the secrets",
+			method:     http.MethodGet,
+			target:     "/v1/webhooks",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"Webhooks","webhooks":[{"id":"1","url":"https://example.com/hook","createdAt":"2024-01-02T03:04:05Z"}]}`,
+		},
+		{
+			name:       "getWebhook() - not found",
+			method:     http.MethodGet,
+			target:     "/v1/webhooks/2",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"statusCode":404,"code":"WebhookNotFound","message":"webhook not found"}`,
+		},
+		{
+			name:       "deleteWebhook()",
+			method:     http.MethodDelete,
+			target:     "/v1/webhooks/1",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"Webhook is deleted"}`,
+		},
+		{
+			name:       "listDeliveries() - filtered by status",
+			method:     http.MethodGet,
+			target:     "/v1/webhooks/1/deliveries?status=dead-lettered",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"message":"Deliveries","deliveries":[{"id":"d1","eventId":1,"event":"created","status":"dead-lettered","attempts":5,"responseStatus":503,"error":"unexpected status: 503 Service Unavailable","createdAt":"2024-01-02T03:04:05Z","updatedAt":"2024-01-02T03:04:05Z"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &stubWebhookRegistry{
+				webhooks: []webhooks.Webhook{
+					{ID: "1", URL: "https://example.com/hook", Secret: "secret", CreatedAt: createdAt},
+				},
+				deliveries: []webhooks.Delivery{
+					{ID: "d2", EventID: 2, Event: notes.EventUpdated, Status: webhooks.StatusSucceeded, Attempts: 1, ResponseStatus: 200, CreatedAt: createdAt, UpdatedAt: createdAt},
+					{ID: "d1", EventID: 1, Event: notes.EventCreated, Status: webhooks.StatusDeadLettered, Attempts: 5, ResponseStatus: 503, Error: "unexpected status: 503 Service Unavailable", CreatedAt: createdAt, UpdatedAt: createdAt},
+				},
+				createdAt: createdAt,
+			}
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithWebhooks(registry))
+			require.NoError(t, err)
+			s.routes()
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			s.router.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantStatus, rr.Code)
+			require.JSONEq(t, tt.wantBody, rr.Body.String())
+		})
+	}
+}
+
+func Test_routes_webhooksWithOptions(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithOptions(Options{Webhooks: &stubWebhookRegistry{}}))
+	require.NoError(t, err)
+	s.routes()
+
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+// stubWebhookRegistry keeps the webhooks in a slice and returns the same
+// deliveries for every webhook.
+type stubWebhookRegistry struct {
+	webhooks   []webhooks.Webhook
+	deliveries []webhooks.Delivery
+	createdAt  time.Time
+}
+
+func (r *stubWebhookRegistry) Register(ctx context.Context, webhook webhooks.Webhook) (webhooks.Webhook, error) {
+	if !strings.HasPrefix(webhook.URL, "https://") {
+		return webhooks.Webhook{}, webhooks.ErrInvalidWebhook
+	}
+	webhook.ID = "2"
+	webhook.Secret = "generated"
+	webhook.CreatedAt = r.createdAt
+	r.webhooks = append(r.webhooks, webhook)
+	return webhook, nil
+}
+
+func (r *stubWebhookRegistry) Webhooks() []webhooks.Webhook {
+	return r.webhooks
+}
+
+func (r *stubWebhookRegistry) Webhook(id string) (webhooks.Webhook, error) {
+	for _, webhook := range r.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return webhooks.Webhook{}, webhooks.ErrNotFound
+}
+
+func (r *stubWebhookRegistry) Delete(id string) error {
+	if _, err := r.Webhook(id); err != nil {
+		return err
+	}
+	r.webhooks = nil
+	return nil
+}
+
+func (r *stubWebhookRegistry) Deliveries(id string) ([]webhooks.Delivery, error) {
+	if _, err := r.Webhook(id); err != nil {
+		return nil, err
+	}
+	return r.deliveries, nil
+}
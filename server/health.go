@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// pathLiveness is the path of the liveness probe.
+	pathLiveness = "/healthz"
+	// pathReadiness is the path of the readiness probe.
+	pathReadiness = "/readyz"
+	// defaultReadinessTimeout is the default time the readiness checks
+	// have to complete.
+	defaultReadinessTimeout = 2 * time.Second
+)
+
+// ReadinessCheck is a named check of a dependency of the server.
+type ReadinessCheck struct {
+	// Name identifies the dependency in the readiness response.
+	Name string
+	// Check returns an error if the dependency is not available.
+	Check func(ctx context.Context) error
+	// Optional checks are reported but do not make the server not ready.
+	Optional bool
+}
+
+// readinessResponse is the response of the readiness probe.
+type readinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// checkResult is the result of a single readiness check.
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// liveness responds with 200 as long as the server is able to serve requests.
+func (s server) liveness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encode(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+}
+
+// readiness runs the readiness checks and responds with 200 if all
+// required checks pass, and with 503 if any of them fails or the server
+// is shutting down.
+func (s server) readiness() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.shuttingDown.Load() {
+			encode(w, http.StatusServiceUnavailable, readinessResponse{Status: "shutting down"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), defaultReadinessTimeout)
+		defer cancel()
+
+		results := make([]checkResult, len(s.readinessChecks))
+		var wg sync.WaitGroup
+		for i, check := range s.readinessChecks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i] = checkResult{Status: "up", Optional: check.Optional}
+				if err := check.Check(ctx); err != nil {
+					results[i].Status = "down"
+					results[i].Error = err.Error()
+				}
+			}()
+		}
+		wg.Wait()
+
+		resp := readinessResponse{Status: "ready", Checks: make(map[string]checkResult, len(results))}
+		statusCode := http.StatusOK
+		for i, result := range results {
+			resp.Checks[s.readinessChecks[i].Name] = result
+			if result.Status == "down" && !result.Optional {
+				resp.Status = "not ready"
+				statusCode = http.StatusServiceUnavailable
+			}
+		}
+		if statusCode != http.StatusOK {
+			s.log.ErrorContext(r.Context(), "Server is not ready.", "checks", resp.Checks)
+		}
+		encode(w, statusCode, resp)
+	})
+}
+
+// isProbe reports whether the path is the path of a liveness or readiness probe.
+func isProbe(path string) bool {
+	return path == pathLiveness || path == pathReadiness
+}
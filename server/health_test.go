@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readiness(t *testing.T) {
+	errUnreachable := errors.New("unreachable")
+
+	tests := []struct {
+		name         string
+		checks       []ReadinessCheck
+		shuttingDown bool
+		wantStatus   int
+		wantResponse readinessResponse
+	}{
+		{
+			name: "readiness() - all checks pass",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: func(ctx context.Context) error { return nil }},
+			},
+			wantStatus: http.StatusOK,
+			wantResponse: readinessResponse{
+				Status: "ready",
+				Checks: map[string]checkResult{"database": {Status: "up"}},
+			},
+		},
+		{
+			name: "readiness() - required check fails",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: func(ctx context.Context) error { return errUnreachable }},
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantResponse: readinessResponse{
+				Status: "not ready",
+				Checks: map[string]checkResult{"database": {Status: "down", Error: "unreachable"}},
+			},
+		},
+		{
+			name: "readiness() - optional check fails",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: func(ctx context.Context) error { return nil }},
+				{Name: "circuitBreaker", Check: func(ctx context.Context) error { return errUnreachable }, Optional: true},
+			},
+			wantStatus: http.StatusOK,
+			wantResponse: readinessResponse{
+				Status: "ready",
+				Checks: map[string]checkResult{
+					"database":       {Status: "up"},
+					"circuitBreaker": {Status: "down", Error: "unreachable", Optional: true},
+				},
+			},
+		},
+		{
+			name: "readiness() - shutting down",
+			checks: []ReadinessCheck{
+				{Name: "database", Check: func(ctx context.Context) error { return nil }},
+			},
+			shuttingDown: true,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantResponse: readinessResponse{Status: "shutting down"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []Option{WithLogger(&mockLogger{})}
+			for _, check := range tt.checks {
+				options = append(options, WithReadinessCheck(check))
+			}
+			s, err := New(&mockNotesService{}, options...)
+			require.NoError(t, err)
+			s.routes()
+			s.shuttingDown.Store(tt.shuttingDown)
+
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pathReadiness, nil))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			var got readinessResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			require.Equal(t, tt.wantResponse, got)
+		})
+	}
+}
+
+func Test_liveness(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}))
+	require.NoError(t, err)
+	s.routes()
+	s.shuttingDown.Store(true)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pathLiveness, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
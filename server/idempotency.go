@@ -0,0 +1,236 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// headerIdempotencyKey is the header a client sets to make a request idempotent.
+	headerIdempotencyKey = "Idempotency-Key"
+	// headerIdempotentReplayed is set on responses that are replayed from the store.
+	headerIdempotentReplayed = "Idempotent-Replayed"
+	// defaultIdempotencyTTL is the default window a response is stored for.
+	defaultIdempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength is the maximum length of an idempotency key.
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBodySize is the maximum size of the body of a request
+	// with an idempotency key, which is read to fingerprint the request.
+	maxIdempotentBodySize = 1 << 20
+)
+
+// idempotentResponse is a stored response for an idempotency key.
+type idempotentResponse struct {
+	// done is closed when the first request has completed.
+	done        chan struct{}
+	fingerprint [sha256.Size]byte
+	statusCode  int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// idempotencyStore stores responses by idempotency key.
+type idempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newIdempotencyStore returns a new idempotencyStore that keeps
+// responses for the given ttl.
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &idempotencyStore{
+		responses: make(map[string]*idempotentResponse),
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// reserve returns the stored response for the key. If there is none,
+// a new pending response is reserved and reserved is true, in which case
+// the caller must either complete or release it.
+func (st *idempotencyStore) reserve(key string, fingerprint [sha256.Size]byte) (resp *idempotentResponse, reserved bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := st.now()
+	st.sweep(now)
+
+	if resp, ok := st.responses[key]; ok {
+		return resp, false
+	}
+	resp = &idempotentResponse{
+		done:        make(chan struct{}),
+		fingerprint: fingerprint,
+	}
+	st.responses[key] = resp
+	return resp, true
+}
+
+// complete stores the response for the key and wakes up waiting retries.
+func (st *idempotencyStore) complete(resp *idempotentResponse, statusCode int, header http.Header, body []byte) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	resp.statusCode = statusCode
+	resp.header = header
+	resp.body = body
+	resp.expires = st.now().Add(st.ttl)
+	close(resp.done)
+}
+
+// release removes a pending response for the key so that the request can
+// be retried, and wakes up waiting retries.
+func (st *idempotencyStore) release(key string, resp *idempotentResponse) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.responses[key] == resp {
+		delete(st.responses, key)
+	}
+	close(resp.done)
+}
+
+// sweep removes expired responses.
+func (st *idempotencyStore) sweep(now time.Time) {
+	if now.Sub(st.lastSweep) < time.Minute {
+		return
+	}
+	for key, resp := range st.responses {
+		if !resp.expires.IsZero() && now.After(resp.expires) {
+			delete(st.responses, key)
+		}
+	}
+	st.lastSweep = now
+}
+
+// idempotent is a middleware that honors the Idempotency-Key header. The
+// first response for a key is stored and replayed for retries with the
+// same key. The request is served to the end even if the client goes
+// away, since the note may already be written, and its outcome is stored.
+// Only responses of requests that were refused before the write are not
+// stored, so that the request can be retried. Requests are fingerprinted
+// by their operation, category and body, so that a key can be retried on
+// any route of the same operation.
+func (s server) idempotent(operation string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(headerIdempotencyKey)
+		if len(key) == 0 || s.idempotency == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			statusCode, code := errorCodes(ErrInvalidIdempotencyKey)
+			writeError(w, statusCode, code, ErrInvalidIdempotencyKey)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+		if err != nil {
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				err = fmt.Errorf("%w: body is larger than %d bytes", ErrMalformedRequestBody, maxIdempotentBodySize)
+			} else {
+				err = ErrInvalidRequest
+			}
+			statusCode, code := errorCodes(err)
+			writeError(w, statusCode, code, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := sha256.Sum256(append([]byte(operation+" "+r.PathValue("category")+"\n"), body...))
+
+		storeKey := s.clientKey(r) + " " + key
+		for {
+			resp, reserved := s.idempotency.reserve(storeKey, fingerprint)
+			if reserved {
+				s.serveIdempotent(w, r, next, storeKey, resp)
+				return
+			}
+
+			select {
+			case <-resp.done:
+			case <-r.Context().Done():
+				return
+			}
+			if resp.statusCode == 0 {
+				// The first request failed and released the key, try again.
+				continue
+			}
+			if resp.fingerprint != fingerprint {
+				statusCode, code := errorCodes(ErrIdempotencyKeyReused)
+				writeError(w, statusCode, code, ErrIdempotencyKeyReused)
+				return
+			}
+
+			for k, v := range resp.header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(headerIdempotentReplayed, "true")
+			w.WriteHeader(resp.statusCode)
+			w.Write(resp.body)
+			return
+		}
+	})
+}
+
+// serveIdempotent serves the request and stores its response. The
+// request is not canceled when the client goes away, so that a retry
+// replays the outcome of the write instead of writing the note again.
+func (s server) serveIdempotent(w http.ResponseWriter, r *http.Request, next http.Handler, key string, resp *idempotentResponse) {
+	rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	defer func() {
+		if !finalResponse(rec.statusCode) {
+			s.idempotency.release(key, resp)
+			return
+		}
+		// The replayed response keeps the request ID of the replaying request.
+		header := w.Header().Clone()
+		header.Del(headerRequestID)
+		s.idempotency.complete(resp, rec.statusCode, header, rec.body.Bytes())
+	}()
+	next.ServeHTTP(rec, r.WithContext(context.WithoutCancel(r.Context())))
+}
+
+// finalResponse reports whether a response with the status code is the
+// outcome of the request, and is replayed for retries. Other server
+// errors may be returned after the note is written, and are replayed so
+// that a retry does not create the note again. Only a request that is
+// refused with 503 Service Unavailable, such as when the circuit is
+// open, is retried.
+func finalResponse(statusCode int) bool {
+	return statusCode != http.StatusServiceUnavailable
+}
+
+// responseRecorder is an http.ResponseWriter that records the status
+// code and body while writing them to the underlying ResponseWriter.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader records the status code and writes it.
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	rec.statusCode = statusCode
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body and writes it.
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_idempotent(t *testing.T) {
+	type request struct {
+		key      string
+		body     string
+		target   string
+		category string
+	}
+
+	tests := []struct {
+		name         string
+		requests     []request
+		handlerCodes []int
+		wantCodes    []int
+		wantReplayed []bool
+		wantCalls    int
+	}{
+		{
+			name:         "idempotent() - retry is replayed",
+			requests:     []request{{key: "a", body: `{"note":"note"}`}, {key: "a", body: `{"note":"note"}`}},
+			handlerCodes: []int{http.StatusCreated},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, true},
+			wantCalls:    1,
+		},
+		{
+			name:         "idempotent() - requests without key are not replayed",
+			requests:     []request{{body: `{"note":"note"}`}, {body: `{"note":"note"}`}},
+			handlerCodes: []int{http.StatusCreated, http.StatusCreated},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, false},
+			wantCalls:    2,
+		},
+		{
+			name:         "idempotent() - different keys are not replayed",
+			requests:     []request{{key: "a", body: `{"note":"note"}`}, {key: "b", body: `{"note":"note"}`}},
+			handlerCodes: []int{http.StatusCreated, http.StatusCreated},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, false},
+			wantCalls:    2,
+		},
+		{
+			name:         "idempotent() - key reused with different body",
+			requests:     []request{{key: "a", body: `{"note":"note"}`}, {key: "a", body: `{"note":"other"}`}},
+			handlerCodes: []int{http.StatusCreated},
+			wantCodes:    []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			wantReplayed: []bool{false, false},
+			wantCalls:    1,
+		},
+		{
+			name: "idempotent() - retry on another route of the operation is replayed",
+			requests: []request{
+				{key: "a", body: `{"note":"note"}`, target: "/notes/create/work", category: "work"},
+				{key: "a", body: `{"note":"note"}`, target: "/v1/categories/work/notes", category: "work"},
+			},
+			handlerCodes: []int{http.StatusCreated},
+			wantCodes:    []int{http.StatusCreated, http.StatusCreated},
+			wantReplayed: []bool{false, true},
+			wantCalls:    1,
+		},
+		{
+			name: "idempotent() - key reused in another category",
+			requests: []request{
+				{key: "a", body: `{"note":"note"}`, category: "work"},
+				{key: "a", body: `{"note":"note"}`, category: "home"},
+			},
+			handlerCodes: []int{http.StatusCreated},
+			wantCodes:    []int{http.StatusCreated, http.StatusUnprocessableEntity},
+			wantReplayed: []bool{false, false},
+			wantCalls:    1,
+		},
+		{
+			name:         "idempotent() - body is too large",
+			requests:     []request{{key: "a", body: strings.Repeat("a", maxIdempotentBodySize+1)}},
+			wantCodes:    []int{http.StatusBadRequest},
+			wantReplayed: []bool{false},
+		},
+		{
+			name:         "idempotent() - unavailable responses are not stored",
+			requests:     []request{{key: "a", body: `{"note":"note"}`}, {key: "a", body: `{"note":"note"}`}},
+			handlerCodes: []int{http.StatusServiceUnavailable, http.StatusCreated},
+			wantCodes:    []int{http.StatusServiceUnavailable, http.StatusCreated},
+			wantReplayed: []bool{false, false},
+			wantCalls:    2,
+		},
+		{
+			name:         "idempotent() - server errors are stored",
+			requests:     []request{{key: "a", body: `{"note":"note"}`}, {key: "a", body: `{"note":"note"}`}},
+			handlerCodes: []int{http.StatusInternalServerError},
+			wantCodes:    []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			wantReplayed: []bool{false, true},
+			wantCalls:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server{idempotency: newIdempotencyStore(defaultIdempotencyTTL)}
+			calls := 0
+			h := s.idempotent("createNote", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.handlerCodes[calls])
+				w.Write([]byte(`{"message":"Note is created"}`))
+				calls++
+			}))
+
+			for i, req := range tt.requests {
+				target := req.target
+				if len(target) == 0 {
+					target = "/notes/create/test"
+				}
+				r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(req.body))
+				r.SetPathValue("category", req.category)
+				if len(req.key) > 0 {
+					r.Header.Set(headerIdempotencyKey, req.key)
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, r)
+
+				require.Equal(t, tt.wantCodes[i], rec.Code)
+				require.Equal(t, tt.wantReplayed[i], rec.Header().Get(headerIdempotentReplayed) == "true")
+				if tt.wantReplayed[i] {
+					require.Equal(t, `{"message":"Note is created"}`, rec.Body.String())
+				}
+			}
+			require.Equal(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func Test_idempotent_canceledRequestIsReplayed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := &cancelingService{stubNotesService: stubNotesService{note: notes.Note{ID: "1", Category: "work", Note: "note"}}, cancel: cancel}
+	s, err := New(svc, WithLogger(&mockLogger{}))
+	require.NoError(t, err)
+	s.routes()
+
+	// The client cancels the first request after the note is written.
+	req := httptest.NewRequest(http.MethodPost, "/v1/categories/work/notes", strings.NewReader(`{"note":"note"}`)).WithContext(ctx)
+	req.Header.Set(headerIdempotencyKey, "a")
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	// The retry with the same key replays the created note.
+	req = httptest.NewRequest(http.MethodPost, "/v1/categories/work/notes", strings.NewReader(`{"note":"note"}`))
+	req.Header.Set(headerIdempotencyKey, "a")
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "true", rec.Header().Get(headerIdempotentReplayed))
+	require.Equal(t, 1, svc.calls)
+}
+
+// cancelingService is a notes service that cancels the request of the
+// client after the note is written, and fails with the error of its
+// context if it is canceled.
+type cancelingService struct {
+	stubNotesService
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (s *cancelingService) CreateNote(ctx context.Context, note notes.Note) (notes.Note, error) {
+	s.calls++
+	created, err := s.stubNotesService.CreateNote(ctx, note)
+	s.cancel()
+	if ctx.Err() != nil {
+		return notes.Note{}, notes.ErrCanceled
+	}
+	return created, err
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/KatrinSalt/notes-service/log"
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+const (
+	// headerRequestID is the header of the request ID.
+	headerRequestID = "X-Request-ID"
+	// maxRequestIDLength is the maximum length of a request ID set by
+	// the client.
+	maxRequestIDLength = 128
+	// headerAuthorName and headerAuthorEmail are the headers of the
+	// author of the changes made by the request.
+	headerAuthorName  = "X-Author-Name"
+	headerAuthorEmail = "X-Author-Email"
+	// maxAuthorLength is the maximum length of the name and email of an
+	// author.
+	maxAuthorLength = 256
+)
+
+// middleware wraps the handler with the middleware applied to every
+// request served by the server.
+func (s server) middleware(h http.Handler) http.Handler {
+	h = s.author(h)
+	h = s.rateLimit(h)
+	h = s.trace(h)
+	h = s.instrument(h)
+	h = s.cors(h)
+	h = s.accessLog(h)
+	h = s.requestID(h)
+	return h
+}
+
+// requestID is a middleware that propagates the X-Request-ID header of
+// the request, or assigns a new request ID if it is missing or invalid.
+// The request ID is set on the response and added to every message
+// logged with the request context.
+func (s server) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(headerRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(headerRequestID, id)
+		next.ServeHTTP(w, r.WithContext(log.WithRequestID(r.Context(), id)))
+	})
+}
+
+// accessLog is a middleware that logs one line for every request.
+func (s server) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		s.log.InfoContext(r.Context(), "Request served.",
+			"type", "access",
+			"method", r.Method,
+			"route", s.routePattern(r),
+			"status", sw.status(),
+			"bytes", sw.bytes,
+			"duration", time.Since(start).String(),
+			"client", clientIP(r),
+		)
+	})
+}
+
+// author is a middleware that adds the author of the X-Author-Name and
+// X-Author-Email headers to the request context, so that it is recorded
+// by the databases that keep a history of the notes. Invalid values are
+// ignored.
+func (s server) author(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, email := r.Header.Get(headerAuthorName), r.Header.Get(headerAuthorEmail)
+		if author, ok := toAuthor(name, email); ok {
+			r = r.WithContext(notes.WithAuthor(r.Context(), author))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// toAuthor returns the author of the name and email, and false if
+// neither is set and valid.
+func toAuthor(name, email string) (notes.Author, bool) {
+	var author notes.Author
+	if validAuthor(name) {
+		author.Name = name
+	}
+	if validAuthor(email) {
+		author.Email = email
+	}
+	return author, len(author.Name) > 0 || len(author.Email) > 0
+}
+
+// validAuthor reports whether the name or email of an author can be
+// used. It must be non-empty, not too long, valid UTF-8 and contain only
+// printable characters other than the angle brackets that delimit the
+// email of a git author.
+func validAuthor(s string) bool {
+	if len(s) == 0 || len(s) > maxAuthorLength || !utf8.ValidString(s) {
+		return false
+	}
+	return !strings.ContainsAny(s, "<>") && strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsPrint(r)
+	}) < 0
+}
+
+// validRequestID reports whether the request ID set by the client can be
+// used. It must be non-empty, not too long and contain only printable
+// ASCII characters, so that it is safe to log and echo.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a new random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// instrument is a middleware that records the method, route, status
+// and latency of every request.
+func (s server) instrument(next http.Handler) http.Handler {
+	if s.metrics == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		s.metrics.ObserveHTTPRequest(r.Method, s.routePattern(r), sw.status(), time.Since(start))
+	})
+}
+
+// routePattern returns the pattern of the route matching the request,
+// so that metrics and logs are not labeled with every requested path.
+func (s server) routePattern(r *http.Request) string {
+	if _, pattern := s.router.Handler(r); len(pattern) > 0 {
+		return pattern
+	}
+	return "unmatched"
+}
+
+// statusWriter is an http.ResponseWriter that records the status code
+// and the number of bytes written.
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int
+}
+
+// WriteHeader records the status code and writes it.
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	if sw.statusCode == 0 {
+		sw.statusCode = statusCode
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes and writes them.
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.statusCode == 0 {
+		sw.statusCode = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter, so that
+// http.ResponseController can reach it.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// status returns the recorded status code.
+func (sw *statusWriter) status() int {
+	if sw.statusCode == 0 {
+		return http.StatusOK
+	}
+	return sw.statusCode
+}
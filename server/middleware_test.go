@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KatrinSalt/notes-service/db"
+	"github.com/KatrinSalt/notes-service/log"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_requestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{
+			name:      "requestID() - request ID is propagated",
+			requestID: "3f1c2a9e-request",
+			wantSame:  true,
+		},
+		{
+			name: "requestID() - request ID is assigned",
+		},
+		{
+			name:      "requestID() - invalid request ID is replaced",
+			requestID: "bad id\n",
+		},
+		{
+			name:      "requestID() - too long request ID is replaced",
+			requestID: strings.Repeat("a", maxRequestIDLength+1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			logger := &recordingLogger{}
+			s, err := New(&mockNotesService{}, WithLogger(logger))
+			require.NoError(t, err)
+			s.router.Handle("GET /notes/categories/{category}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				s.log.InfoContext(r.Context(), "Notes are listed.")
+				w.Write([]byte("notes"))
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/notes/categories/category", nil)
+			if len(tt.requestID) > 0 {
+				req.Header.Set(headerRequestID, tt.requestID)
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			s.middleware(s.router).ServeHTTP(rec, req)
+
+			// Assert
+			id := rec.Header().Get(headerRequestID)
+			require.True(t, validRequestID(id))
+			if tt.wantSame {
+				require.Equal(t, tt.requestID, id)
+			} else {
+				require.NotEqual(t, tt.requestID, id)
+			}
+
+			require.Len(t, logger.entries, 2)
+			for _, entry := range logger.entries {
+				require.Equal(t, id, entry.requestID)
+			}
+			access := logger.entries[1]
+			require.Equal(t, "Request served.", access.msg)
+			require.Equal(t, []any{
+				"type", "access",
+				"method", http.MethodGet,
+				"route", "GET /notes/categories/{category}",
+				"status", http.StatusOK,
+				"bytes", len("notes"),
+				"duration", access.args[11],
+				"client", "192.0.2.1",
+			}, access.args)
+		})
+	}
+}
+
+// recordingLogger records the messages logged with a context.
+type recordingLogger struct {
+	mockLogger
+	entries []logEntry
+}
+
+// logEntry is a message logged by the recordingLogger.
+type logEntry struct {
+	msg       string
+	args      []any
+	requestID string
+}
+
+func (l *recordingLogger) InfoContext(ctx context.Context, msg string, args ...any) {
+	l.entries = append(l.entries, logEntry{msg: msg, args: args, requestID: log.RequestID(ctx)})
+}
+
+func (l *recordingLogger) ErrorContext(ctx context.Context, msg string, args ...any) {
+	l.entries = append(l.entries, logEntry{msg: msg, args: args, requestID: log.RequestID(ctx)})
+}
+
+func Test_author(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorName string
+		email      string
+		want       db.Author
+		wantOK     bool
+	}{
+		{
+			name:       "author() - author is added to the context",
+			authorName: "Alice Smith",
+			email:      "alice@example.com",
+			want:       db.Author{Name: "Alice Smith", Email: "alice@example.com"},
+			wantOK:     true,
+		},
+		{
+			name:       "author() - only the name is set",
+			authorName: "Zoë",
+			want:       db.Author{Name: "Zoë"},
+			wantOK:     true,
+		},
+		{
+			name: "author() - no author",
+		},
+		{
+			name:       "author() - invalid values are ignored",
+			authorName: "Alice <alice@example.com>",
+			email:      strings.Repeat("a", maxAuthorLength+1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}))
+			require.NoError(t, err)
+			var got db.Author
+			var ok bool
+			s.router.Handle("POST /v1/categories/{category}/notes", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, ok = db.AuthorFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/categories/work/notes", nil)
+			if len(tt.authorName) > 0 {
+				req.Header.Set(headerAuthorName, tt.authorName)
+			}
+			if len(tt.email) > 0 {
+				req.Header.Set(headerAuthorEmail, tt.email)
+			}
+			s.middleware(s.router).ServeHTTP(httptest.NewRecorder(), req)
+
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/KatrinSalt/notes-service/api"
+)
+
+// openAPI serves the OpenAPI document of the API.
+func (s server) openAPI() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(api.OpenAPI)
+	})
+}
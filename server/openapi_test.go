@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/api"
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is the part of the OpenAPI document the tests check.
+type openAPIDocument struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func Test_openAPI_routes(t *testing.T) {
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(api.OpenAPI, &doc))
+
+	// Every optional route is enabled, so that all routes are compared.
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}), WithMetrics(&mockMetrics{}), WithUI(http.NotFoundHandler()), WithEvents(notes.NewBroker()), WithWebhooks(&stubWebhookRegistry{}), WithArchive(&stubArchive{}), WithAdmin(), WithHistory(&stubHistory{}))
+	require.NoError(t, err)
+
+	var routes []string
+	for _, route := range s.routeTable() {
+		routes = append(routes, route.pattern)
+	}
+
+	var operations []string
+	for path, methods := range doc.Paths {
+		for method := range methods {
+			if method == "parameters" {
+				continue
+			}
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	slices.Sort(routes)
+	slices.Sort(operations)
+	require.Equal(t, routes, operations, "the routes of the server and the paths of the OpenAPI document differ")
+}
+
+func Test_openAPI_schemas(t *testing.T) {
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(api.OpenAPI, &doc))
+
+	tests := []struct {
+		schema string
+		value  any
+	}{
+		{schema: "NoteRequest", value: api.NoteRequest{}},
+		{schema: "Note", value: api.Note{}},
+		{schema: "NoteResponse", value: api.NoteResponse{}},
+		{schema: "CategoriesResponse", value: api.CategoriesResponse{}},
+		{schema: "GraphQLRequest", value: graphQLRequest{}},
+		{schema: "WebhookRequest", value: api.WebhookRequest{}},
+		{schema: "Webhook", value: api.Webhook{}},
+		{schema: "WebhookResponse", value: api.WebhookResponse{}},
+		{schema: "Delivery", value: api.Delivery{}},
+		{schema: "DeliveriesResponse", value: api.DeliveriesResponse{}},
+		{schema: "ExportedNote", value: api.ExportedNote{}},
+		{schema: "ImportReport", value: api.ImportReport{}},
+		{schema: "ImportError", value: api.ImportError{}},
+		{schema: "ImportResponse", value: api.ImportResponse{}},
+		{schema: "Author", value: api.Author{}},
+		{schema: "Revision", value: api.Revision{}},
+		{schema: "HistoryResponse", value: api.HistoryResponse{}},
+		{schema: "RestoreRequest", value: api.RestoreRequest{}},
+		{schema: "Error", value: responseError{}},
+		{schema: "ReadinessResponse", value: readinessResponse{}},
+		{schema: "CheckResult", value: checkResult{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			schema, ok := doc.Components.Schemas[tt.schema]
+			require.True(t, ok, "schema %s is missing", tt.schema)
+
+			var properties []string
+			for name := range schema.Properties {
+				properties = append(properties, name)
+			}
+			fields := jsonFields(reflect.TypeOf(tt.value))
+
+			slices.Sort(properties)
+			slices.Sort(fields)
+			require.Equal(t, fields, properties, "the fields of %T and the schema %s differ", tt.value, tt.schema)
+		})
+	}
+}
+
+func Test_openAPI_handler(t *testing.T) {
+	s, err := New(&mockNotesService{}, WithLogger(&mockLogger{}))
+	require.NoError(t, err)
+	s.routes()
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pathOpenAPI, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.JSONEq(t, string(api.OpenAPI), rec.Body.String())
+}
+
+// jsonFields returns the JSON names of the fields of the struct type.
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = typ.Field(i).Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// mockMetrics is a metricsRecorder that discards the metrics.
+type mockMetrics struct{}
+
+func (m *mockMetrics) Handler() http.Handler {
+	return http.NotFoundHandler()
+}
+
+func (m *mockMetrics) ObserveHTTPRequest(method, route string, statusCode int, duration time.Duration) {
+}
@@ -1,5 +1,11 @@
 package server
 
+import (
+	"crypto/sha256"
+	"net/http"
+	"time"
+)
+
 // WithAddress sets the address for the server.
 func WithAddress(address string) Option {
 	return func(s *server) {
@@ -13,3 +19,136 @@ func WithLogger(log logger) Option {
 		s.log = log
 	}
 }
+
+// WithRateLimit enables per-client rate limiting with separate limits
+// for read and write routes. A limit with a Rate of zero is not applied.
+func WithRateLimit(read, write RateLimit) Option {
+	return func(s *server) {
+		if read.Rate > 0 {
+			s.readLimiter = newRateLimiter(read)
+		}
+		if write.Rate > 0 {
+			s.writeLimiter = newRateLimiter(write)
+		}
+	}
+}
+
+// WithAPIKeys sets the API keys that identify a client by the X-API-Key
+// header for rate limiting. Requests with other keys are identified by
+// their IP address.
+func WithAPIKeys(keys ...string) Option {
+	return func(s *server) {
+		if s.apiKeys == nil {
+			s.apiKeys = make(map[[sha256.Size]byte]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			if len(key) > 0 {
+				s.apiKeys[hashAPIKey(key)] = struct{}{}
+			}
+		}
+	}
+}
+
+// WithIdempotencyTTL sets how long responses to requests with an
+// Idempotency-Key header are stored and replayed.
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(s *server) {
+		s.idempotency = newIdempotencyStore(ttl)
+	}
+}
+
+// WithMetrics enables the /metrics endpoint and records metrics of
+// every request.
+func WithMetrics(metrics metricsRecorder) Option {
+	return func(s *server) {
+		s.metrics = metrics
+	}
+}
+
+// WithReadinessCheck adds a check of a dependency to the readiness probe.
+func WithReadinessCheck(check ReadinessCheck) Option {
+	return func(s *server) {
+		s.readinessChecks = append(s.readinessChecks, check)
+	}
+}
+
+// WithShutdownDelay sets how long the server keeps serving requests
+// after the readiness probe starts failing on shutdown, so that load
+// balancers stop sending new requests before the server stops.
+func WithShutdownDelay(delay time.Duration) Option {
+	return func(s *server) {
+		s.shutdownDelay = delay
+	}
+}
+
+// WithTLS enables HTTPS with the certificate and key files, and mutual
+// TLS if a client CA file is set. The certificates are reloaded when the
+// process receives SIGHUP.
+func WithTLS(options TLSOptions) Option {
+	return func(s *server) {
+		s.tlsOptions = &options
+	}
+}
+
+// WithCORS enables cross-origin requests from the allowed origins.
+func WithCORS(options CORSOptions) Option {
+	return func(s *server) {
+		s.corsOptions = &options
+	}
+}
+
+// WithUI serves the web front-end under /ui. The handler is called with
+// the /ui prefix stripped from the path.
+func WithUI(ui http.Handler) Option {
+	return func(s *server) {
+		s.ui = ui
+	}
+}
+
+// WithGRPC serves the gRPC API on the address, alongside the HTTP API.
+// The gRPC server uses the TLS configuration of the HTTP server.
+func WithGRPC(address string) Option {
+	return func(s *server) {
+		s.grpcAddress = address
+	}
+}
+
+// WithEvents enables the stream of the create, update and delete events
+// of notes at /notes/events.
+func WithEvents(events eventBroker) Option {
+	return func(s *server) {
+		s.events = events
+	}
+}
+
+// WithWebhooks enables the API to register webhooks and list their
+// deliveries under /v1/webhooks.
+func WithWebhooks(webhooks webhookRegistry) Option {
+	return func(s *server) {
+		s.webhooks = webhooks
+	}
+}
+
+// WithArchive enables the export and import of the notes of a category
+// as a zip archive of Markdown files.
+func WithArchive(archive noteArchive) Option {
+	return func(s *server) {
+		s.archive = archive
+	}
+}
+
+// WithAdmin enables the export and import of all notes under /admin. It
+// requires WithArchive.
+func WithAdmin() Option {
+	return func(s *server) {
+		s.admin = true
+	}
+}
+
+// WithHistory enables the history of the changes of a note and the
+// restore of a note as it was after a change.
+func WithHistory(history noteHistory) Option {
+	return func(s *server) {
+		s.history = history
+	}
+}
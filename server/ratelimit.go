@@ -0,0 +1,178 @@
+package server
+
+import (
+	"crypto/sha256"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// headerAPIKey is the header used to identify a client by its API key.
+	// Only the keys set with WithAPIKeys identify a client.
+	headerAPIKey = "X-API-Key"
+	// defaultLimiterTTL is how long an idle client bucket is kept in memory.
+	defaultLimiterTTL = 10 * time.Minute
+)
+
+// RateLimit holds the token bucket configuration for a group of routes.
+// Rate is the number of requests per second that are refilled into
+// the bucket and Burst is the size of the bucket.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// bucket is a token bucket for a single client.
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter keeps a token bucket per client key.
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	buckets   map[string]*bucket
+	ttl       time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// newRateLimiter returns a new rateLimiter for the given configuration.
+func newRateLimiter(cfg RateLimit) *rateLimiter {
+	return &rateLimiter{
+		limit:   rate.Limit(cfg.Rate),
+		burst:   cfg.Burst,
+		buckets: make(map[string]*bucket),
+		ttl:     defaultLimiterTTL,
+		now:     time.Now,
+	}
+}
+
+// allow reports whether a request from the client with the given key
+// may proceed. If not, it returns how long the client should wait
+// before retrying.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	r := b.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return false, time.Second
+	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep removes buckets of clients that have not been seen within the ttl.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.ttl {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.ttl {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// rateLimit is a middleware that limits requests per client. Read
+// requests (GET, HEAD and OPTIONS) and write requests are counted
+// against separate buckets.
+func (s server) rateLimit(next http.Handler) http.Handler {
+	if s.readLimiter == nil && s.writeLimiter == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isProbe(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		limiter := s.writeLimiter
+		if isReadMethod(r.Method) {
+			limiter = s.readLimiter
+		}
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if ok, retryAfter := limiter.allow(s.clientKey(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			statusCode, code := errorCodes(ErrRateLimited)
+			writeError(w, statusCode, code, ErrRateLimited)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isReadMethod reports whether the method is a read-only HTTP method.
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// clientKey returns the key a client is identified by. The API key is
+// used if it is one of the keys of the server, otherwise the IP address
+// of the client, so that a client cannot get a new bucket by sending a
+// new key.
+func (s server) clientKey(r *http.Request) string {
+	return s.clientKeyOf(r.Header.Get(headerAPIKey), clientIP(r))
+}
+
+// clientKeyOf returns the key of a client with the API key and IP address.
+func (s server) clientKeyOf(apiKey, ip string) string {
+	if len(apiKey) > 0 {
+		if _, ok := s.apiKeys[hashAPIKey(apiKey)]; ok {
+			return "key:" + apiKey
+		}
+	}
+	return "ip:" + ip
+}
+
+// hashAPIKey returns the hash of an API key. The keys of the server are
+// looked up by their hash, so that the lookup time does not depend on
+// how much of a key matches.
+func hashAPIKey(key string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(key))
+}
+
+// clientIP returns the IP address of the client of the request.
+func clientIP(r *http.Request) string {
+	return hostOf(r.RemoteAddr)
+}
+
+// hostOf returns the host of the address, or the address if it has no
+// port.
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
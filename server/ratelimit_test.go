@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_rateLimiter_allow(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{Rate: 1, Burst: 2})
+	limiter.now = func() time.Time { return now }
+
+	ok, _ := limiter.allow("a")
+	require.True(t, ok)
+	ok, _ = limiter.allow("a")
+	require.True(t, ok)
+
+	ok, retryAfter := limiter.allow("a")
+	require.False(t, ok)
+	require.InDelta(t, time.Second, retryAfter, float64(time.Millisecond))
+
+	// Other clients have their own bucket.
+	ok, _ = limiter.allow("b")
+	require.True(t, ok)
+
+	// The bucket is refilled over time.
+	now = now.Add(time.Second)
+	ok, _ = limiter.allow("a")
+	require.True(t, ok)
+}
+
+func Test_rateLimiter_sweep(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(RateLimit{Rate: 1, Burst: 1})
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("a")
+	now = now.Add(limiter.ttl + time.Second)
+	limiter.allow("b")
+
+	require.Len(t, limiter.buckets, 1)
+	require.Contains(t, limiter.buckets, "b")
+}
+
+func Test_rateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		apiKey     string
+		apiKeys    []string
+		remoteAddr []string
+		wantStatus []int
+	}{
+		{
+			name:       "rateLimit() - write limit exceeded",
+			method:     http.MethodPost,
+			remoteAddr: []string{"10.0.0.1:1000", "10.0.0.1:1001"},
+			wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:       "rateLimit() - read limit is separate",
+			method:     http.MethodGet,
+			remoteAddr: []string{"10.0.0.1:1000", "10.0.0.1:1001", "10.0.0.1:1002"},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:       "rateLimit() - clients are limited by ip",
+			method:     http.MethodPost,
+			remoteAddr: []string{"10.0.0.1:1000", "10.0.0.2:1000"},
+			wantStatus: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:       "rateLimit() - clients are limited by api key",
+			method:     http.MethodPost,
+			apiKey:     "key",
+			remoteAddr: []string{"10.0.0.1:1000", "10.0.0.2:1000"},
+			wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:       "rateLimit() - unknown api keys are limited by ip",
+			method:     http.MethodPost,
+			apiKeys:    []string{"other", "other-2"},
+			remoteAddr: []string{"10.0.0.1:1000", "10.0.0.1:1001"},
+			wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := server{}
+			WithRateLimit(RateLimit{Rate: 1, Burst: 2}, RateLimit{Rate: 1, Burst: 1})(&s)
+			WithAPIKeys("key")(&s)
+			h := s.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for i, addr := range tt.remoteAddr {
+				req := httptest.NewRequest(tt.method, "/notes/categories/test", nil)
+				req.RemoteAddr = addr
+				if len(tt.apiKey) > 0 {
+					req.Header.Set(headerAPIKey, tt.apiKey)
+				}
+				if len(tt.apiKeys) > 0 {
+					req.Header.Set(headerAPIKey, tt.apiKeys[i])
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+
+				require.Equal(t, tt.wantStatus[i], rec.Code)
+				if rec.Code == http.StatusTooManyRequests {
+					require.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+					var respErr responseError
+					require.NoError(t, json.NewDecoder(rec.Body).Decode(&respErr))
+					require.Equal(t, "RateLimited", respErr.Code)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,95 @@
 package server
 
+import "net/http"
+
+const (
+	// pathUI is the path the web front-end is served under.
+	pathUI = "/ui"
+	// pathOpenAPI is the path of the OpenAPI document.
+	pathOpenAPI = "/openapi.json"
+)
+
+// route is a route of the server.
+type route struct {
+	pattern string
+	handler http.Handler
+}
+
+// routeTable returns the routes of the server. Every route must be
+// described in the OpenAPI document.
+func (s server) routeTable() []route {
+	routes := []route{
+		{"POST /v1/categories/{category}/notes", s.idempotent("createNote", s.createNote())},
+		{"GET /v1/categories/{category}/notes", s.getNotesByCategory()},
+		{"GET /v1/categories/{category}/notes/{id}", s.getNoteByID()},
+		{"PUT /v1/categories/{category}/notes/{id}", s.updateNote()},
+		{"DELETE /v1/categories/{category}/notes/{id}", s.deleteNote()},
+		{"GET /v1/categories", s.listCategories()},
+		{"POST " + pathGraphQL, s.graphQL()},
+
+		// Legacy routes, deprecated in favor of the /v1 routes.
+		{"POST /notes/create/{category}", deprecated(s.idempotent("createNote", s.createNote()), "/v1/categories/{category}/notes")},
+		{"PUT /notes/update/{category}/{id}", deprecated(s.updateNote(), "/v1/categories/{category}/notes/{id}")},
+		{"DELETE /notes/delete/{category}/{id}", deprecated(s.deleteNote(), "/v1/categories/{category}/notes/{id}")},
+		{"GET /notes/categories/{category}/ids/{id}", deprecated(s.getNoteByID(), "/v1/categories/{category}/notes/{id}")},
+		{"GET /notes/categories/{category}", deprecated(s.getNotesByCategory(), "/v1/categories/{category}/notes")},
+		{"GET /notes/categories", deprecated(s.listCategories(), "/v1/categories")},
+
+		{"GET " + pathLiveness, s.liveness()},
+		{"GET " + pathReadiness, s.readiness()},
+		{"GET " + pathOpenAPI, s.openAPI()},
+	}
+
+	if s.ui != nil {
+		routes = append(routes,
+			route{"GET " + pathUI, http.RedirectHandler(pathUI+"/", http.StatusMovedPermanently)},
+			route{"GET " + pathUI + "/", http.StripPrefix(pathUI, s.ui)},
+		)
+	}
+
+	if s.events != nil {
+		routes = append(routes, route{"GET " + pathEvents, s.noteEvents()})
+	}
+
+	if s.webhooks != nil {
+		routes = append(routes,
+			route{"POST " + pathWebhooks, s.createWebhook()},
+			route{"GET " + pathWebhooks, s.listWebhooks()},
+			route{"GET " + pathWebhooks + "/{id}", s.getWebhook()},
+			route{"DELETE " + pathWebhooks + "/{id}", s.deleteWebhook()},
+			route{"GET " + pathWebhooks + "/{id}/deliveries", s.listDeliveries()},
+		)
+	}
+
+	if s.archive != nil {
+		routes = append(routes,
+			route{"GET /notes/categories/{category}/export.zip", s.exportCategoryZip()},
+			route{"POST /notes/categories/{category}/import.zip", s.importCategoryZip()},
+		)
+	}
+
+	if s.archive != nil && s.admin {
+		routes = append(routes,
+			route{"GET " + pathAdmin + "/export", s.exportNotes()},
+			route{"POST " + pathAdmin + "/import", s.importNotes()},
+		)
+	}
+
+	if s.history != nil {
+		routes = append(routes,
+			route{"GET /v1/categories/{category}/notes/{id}/history", s.noteRevisions()},
+			route{"POST /v1/categories/{category}/notes/{id}/restore", s.restoreNote()},
+		)
+	}
+
+	if s.metrics != nil {
+		routes = append(routes, route{"GET /metrics", s.metrics.Handler()})
+	}
+	return routes
+}
+
 func (s server) routes() {
-	s.router.Handle("POST /notes/create/{category}", s.createNote())
-	s.router.Handle("PUT /notes/update/{category}/{id}", s.updateNote())
-	s.router.Handle("DELETE /notes/delete/{category}/{id}", s.deleteNote())
-	s.router.Handle("GET /notes/categories/{category}/ids/{id}", s.getNoteByID())
-	s.router.Handle("GET /notes/categories/{category}", s.getNotesByCategory())
+	for _, route := range s.routeTable() {
+		s.router.Handle(route.pattern, route.handler)
+	}
 }
@@ -2,16 +2,20 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/KatrinSalt/notes-service/log"
 	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/graphql-go/graphql"
+	"google.golang.org/grpc"
 )
 
 // Defaults for server configuration.
@@ -23,10 +27,20 @@ const (
 	defaultIdleTimeout  = 30 * time.Second
 )
 
-// logger is the interface that wraps around methods Info and Error.
+// logger is the interface that wraps around methods Info and Error,
+// and their variants that log the request ID of the context.
 type logger interface {
 	Info(msg string, args ...any)
 	Error(msg string, args ...any)
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+// metricsRecorder is the interface that wraps around the methods to
+// record and serve metrics.
+type metricsRecorder interface {
+	Handler() http.Handler
+	ObserveHTTPRequest(method, route string, statusCode int, duration time.Duration)
 }
 
 // server holds an http.Server, a router and it's configured options.
@@ -35,9 +49,48 @@ type server struct {
 	router     *http.ServeMux
 	log        logger
 	notes      notes.Service
-	stopCh     chan os.Signal
-	errCh      chan error
-	started    bool
+	// readLimiter and writeLimiter are nil when rate limiting is disabled.
+	readLimiter  *rateLimiter
+	writeLimiter *rateLimiter
+	// apiKeys are the hashes of the API keys that identify a client.
+	apiKeys     map[[sha256.Size]byte]struct{}
+	idempotency *idempotencyStore
+	metrics     metricsRecorder
+	// readinessChecks are run by the readiness probe.
+	readinessChecks []ReadinessCheck
+	// shuttingDown is set when the server starts to shut down, so that
+	// the readiness probe fails while the requests are drained.
+	shuttingDown  *atomic.Bool
+	shutdownDelay time.Duration
+	// ui serves the web front-end, it is nil when the front-end is disabled.
+	ui http.Handler
+	// corsOptions and corsConfig are nil when CORS is disabled.
+	corsOptions *CORSOptions
+	corsConfig  *cors
+	// tlsOptions and tls are nil when TLS is disabled.
+	tlsOptions *TLSOptions
+	tls        *certReloader
+	// grpcAddress and grpcServer are empty when the gRPC API is disabled.
+	grpcAddress string
+	grpcServer  *grpc.Server
+	// graphQLSchema is the schema served at /graphql.
+	graphQLSchema *graphql.Schema
+	// events is nil when the stream of note events is disabled.
+	events eventBroker
+	// streams is canceled when the server shuts down, to end the
+	// long-lived event streams.
+	streams context.Context
+	// webhooks is nil when the webhooks API is disabled.
+	webhooks webhookRegistry
+	// archive is nil when the export and import of notes is disabled.
+	archive noteArchive
+	// admin enables the export and import of all notes under /admin.
+	admin bool
+	// history is nil when the history of the notes is disabled.
+	history noteHistory
+	stopCh  chan os.Signal
+	errCh   chan error
+	started bool
 }
 
 // Options holds the configuration for the server.
@@ -49,6 +102,42 @@ type Options struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// ReadRateLimit and WriteRateLimit enable per-client rate limiting
+	// of read and write routes when their Rate is greater than zero.
+	ReadRateLimit  RateLimit
+	WriteRateLimit RateLimit
+	// APIKeys are the API keys that identify a client for rate limiting.
+	APIKeys []string
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key header are stored.
+	IdempotencyTTL time.Duration
+	// Metrics enables the /metrics endpoint and request metrics.
+	Metrics metricsRecorder
+	// ReadinessChecks are run by the readiness probe.
+	ReadinessChecks []ReadinessCheck
+	// ShutdownDelay is how long the server keeps serving requests after
+	// the readiness probe starts failing on shutdown.
+	ShutdownDelay time.Duration
+	// TLS enables HTTPS when its CertFile is set.
+	TLS TLSOptions
+	// CORS enables cross-origin requests when its AllowedOrigins are set.
+	CORS CORSOptions
+	// UI serves the web front-end under /ui when it is set.
+	UI http.Handler
+	// GRPCAddress enables the gRPC API on the address when it is set.
+	GRPCAddress string
+	// Events enables the stream of note events when it is set.
+	Events eventBroker
+	// Webhooks enables the webhooks API when it is set.
+	Webhooks webhookRegistry
+	// Archive enables the export and import of the notes of a category
+	// as Markdown when it is set.
+	Archive noteArchive
+	// Admin enables the export and import of all notes under /admin
+	// when Archive is set.
+	Admin bool
+	// History enables the history and restore of notes when it is set.
+	History noteHistory
 }
 
 // Option is a function that configures the server.
@@ -66,15 +155,27 @@ func New(notes notes.Service, options ...Option) (*server, error) {
 			WriteTimeout: defaultWriteTimeout,
 			IdleTimeout:  defaultIdleTimeout,
 		},
-		notes:  notes,
-		stopCh: make(chan os.Signal),
-		errCh:  make(chan error),
+		notes:        notes,
+		idempotency:  newIdempotencyStore(defaultIdempotencyTTL),
+		shuttingDown: &atomic.Bool{},
+		stopCh:       make(chan os.Signal),
+		errCh:        make(chan error),
 	}
 
 	for _, option := range options {
 		option(s)
 	}
 
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		return nil, err
+	}
+	s.graphQLSchema = &schema
+
+	streams, cancelStreams := context.WithCancel(context.Background())
+	s.streams = streams
+	s.httpServer.RegisterOnShutdown(cancelStreams)
+
 	if s.router == nil {
 		s.router = http.NewServeMux()
 		s.httpServer.Handler = s.router
@@ -85,6 +186,24 @@ func New(notes notes.Service, options ...Option) (*server, error) {
 	if len(s.httpServer.Addr) == 0 {
 		s.httpServer.Addr = defaultHost + ":" + defaultPort
 	}
+	if s.corsOptions != nil {
+		corsConfig, err := newCORS(*s.corsOptions)
+		if err != nil {
+			return nil, err
+		}
+		s.corsConfig = corsConfig
+	}
+	if s.tlsOptions != nil {
+		reloader, err := newCertReloader(*s.tlsOptions)
+		if err != nil {
+			return nil, err
+		}
+		s.tls = reloader
+		s.httpServer.TLSConfig = reloader.tlsConfig()
+	}
+	if len(s.grpcAddress) > 0 {
+		s.grpcServer = s.newGRPCServer()
+	}
 
 	return s, nil
 }
@@ -96,19 +215,38 @@ func (s *server) Start() error {
 	}()
 
 	s.routes()
+	s.httpServer.Handler = s.middleware(s.router)
 
 	go func() {
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.listenAndServe(); err != nil && err != http.ErrServerClosed {
 			s.errCh <- err
 		}
 	}()
 
+	if s.grpcServer != nil {
+		go func() {
+			if err := s.serveGRPC(); err != nil {
+				s.errCh <- err
+			}
+		}()
+	}
+
+	if s.tls != nil {
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		defer func() {
+			signal.Stop(reload)
+			close(reload)
+		}()
+		go s.reloadCertificates(reload)
+	}
+
 	go func() {
 		s.stop()
 	}()
 
 	s.started = true
-	s.log.Info("Server started.", "address", s.httpServer.Addr)
+	s.log.Info("Server started.", "address", s.httpServer.Addr, "grpcAddress", s.grpcAddress, "tls", s.tls != nil)
 	for {
 		select {
 		case err := <-s.errCh:
@@ -122,12 +260,39 @@ func (s *server) Start() error {
 	}
 }
 
+// listenAndServe listens on the address of the server and serves
+// requests, with TLS if it is enabled.
+func (s server) listenAndServe() error {
+	if s.tls != nil {
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
+	return s.httpServer.ListenAndServe()
+}
+
+// reloadCertificates reloads the TLS certificates on every signal
+// received on the channel.
+func (s server) reloadCertificates(reload <-chan os.Signal) {
+	for range reload {
+		if err := s.tls.reload(); err != nil {
+			s.log.Error("Failed to reload TLS certificates.", "error", err)
+			continue
+		}
+		s.log.Info("TLS certificates reloaded.")
+	}
+}
+
 // stop the server.
 func (s server) stop() {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-stop
 
+	s.shuttingDown.Store(true)
+	if s.shutdownDelay > 0 {
+		s.log.Info("Server is shutting down.", "delay", s.shutdownDelay.String())
+		time.Sleep(s.shutdownDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
@@ -135,6 +300,9 @@ func (s server) stop() {
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		s.errCh <- err
 	}
+	if s.grpcServer != nil {
+		s.stopGRPC(ctx)
+	}
 
 	s.stopCh <- sig
 }
@@ -161,5 +329,50 @@ func WithOptions(options Options) Option {
 		if options.IdleTimeout > 0 {
 			s.httpServer.IdleTimeout = options.IdleTimeout
 		}
+		if options.ReadRateLimit.Rate > 0 || options.WriteRateLimit.Rate > 0 {
+			WithRateLimit(options.ReadRateLimit, options.WriteRateLimit)(s)
+		}
+		if len(options.APIKeys) > 0 {
+			WithAPIKeys(options.APIKeys...)(s)
+		}
+		if options.IdempotencyTTL > 0 {
+			WithIdempotencyTTL(options.IdempotencyTTL)(s)
+		}
+		if options.Metrics != nil {
+			s.metrics = options.Metrics
+		}
+		for _, check := range options.ReadinessChecks {
+			WithReadinessCheck(check)(s)
+		}
+		if options.ShutdownDelay > 0 {
+			s.shutdownDelay = options.ShutdownDelay
+		}
+		if len(options.TLS.CertFile) > 0 {
+			WithTLS(options.TLS)(s)
+		}
+		if len(options.CORS.AllowedOrigins) > 0 {
+			WithCORS(options.CORS)(s)
+		}
+		if options.UI != nil {
+			s.ui = options.UI
+		}
+		if len(options.GRPCAddress) > 0 {
+			s.grpcAddress = options.GRPCAddress
+		}
+		if options.Events != nil {
+			s.events = options.Events
+		}
+		if options.Webhooks != nil {
+			s.webhooks = options.Webhooks
+		}
+		if options.Archive != nil {
+			s.archive = options.Archive
+		}
+		if options.Admin {
+			s.admin = true
+		}
+		if options.History != nil {
+			s.history = options.History
+		}
 	}
 }
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var (
+	// ErrInvalidTLSVersion is returned when the minimum TLS version is not supported.
+	ErrInvalidTLSVersion = errors.New("invalid TLS version, must be 1.2 or 1.3")
+	// ErrInvalidClientCA is returned when the client CA file contains no certificates.
+	ErrInvalidClientCA = errors.New("client CA file contains no certificates")
+)
+
+// TLSOptions holds the TLS configuration for the server.
+type TLSOptions struct {
+	// CertFile and KeyFile are the PEM encoded certificate and key files
+	// of the server.
+	CertFile string
+	KeyFile  string
+	// MinVersion is the minimum TLS version, 1.2 (default) or 1.3.
+	MinVersion string
+	// ClientCAFile is the PEM encoded CA certificate file used to verify
+	// client certificates. If it is set, clients must present a valid
+	// certificate (mutual TLS).
+	ClientCAFile string
+}
+
+// tlsVersions maps the supported minimum TLS versions to their values.
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// certReloader holds the TLS configuration of the server and reloads the
+// certificates from their files, so that they can be rotated without a
+// restart.
+type certReloader struct {
+	options    TLSOptions
+	minVersion uint16
+	config     atomic.Pointer[tls.Config]
+}
+
+// newCertReloader returns a certReloader with the certificates loaded.
+func newCertReloader(options TLSOptions) (*certReloader, error) {
+	minVersion, ok := tlsVersions[options.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTLSVersion, options.MinVersion)
+	}
+
+	r := &certReloader{options: options, minVersion: minVersion}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the certificates from their files. The current
+// certificates are kept if loading fails.
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   r.minVersion,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+
+	if len(r.options.ClientCAFile) > 0 {
+		pem, err := os.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("load client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return ErrInvalidClientCA
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r.config.Store(config)
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the http.Server. Every
+// connection gets the most recently loaded configuration.
+func (r *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config.Load(), nil
+		},
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_certReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "ca", nil)
+	writeTestCert(t, dir, "ca", ca)
+	writeTestCert(t, dir, "server", newTestCert(t, "server-1", ca))
+	writeTestCert(t, dir, "client", newTestCert(t, "client", ca))
+
+	reloader, err := newCertReloader(TLSOptions{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	})
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = reloader.tlsConfig()
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key"))
+	require.NoError(t, err)
+
+	get := func(certs ...tls.Certificate) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs},
+		}}
+		return client.Get(srv.URL)
+	}
+
+	t.Run("client without certificate is rejected", func(t *testing.T) {
+		_, err := get()
+		require.Error(t, err)
+	})
+
+	t.Run("client with certificate is accepted", func(t *testing.T) {
+		resp, err := get(clientCert)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, "server-1", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	t.Run("reloaded certificate is served", func(t *testing.T) {
+		writeTestCert(t, dir, "server", newTestCert(t, "server-2", ca))
+		require.NoError(t, reloader.reload())
+
+		resp, err := get(clientCert)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, "server-2", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+
+	t.Run("failed reload keeps the certificate", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "server.crt"), []byte("invalid"), 0o600))
+		require.Error(t, reloader.reload())
+
+		resp, err := get(clientCert)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, "server-2", resp.TLS.PeerCertificates[0].Subject.CommonName)
+	})
+}
+
+func Test_newCertReloader_invalidVersion(t *testing.T) {
+	_, err := newCertReloader(TLSOptions{MinVersion: "1.0"})
+	require.ErrorIs(t, err, ErrInvalidTLSVersion)
+}
+
+// newTestCert returns a certificate for localhost signed by the parent,
+// or a self-signed CA certificate if the parent is nil.
+func newTestCert(t *testing.T, commonName string, parent *tls.Certificate) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	signer, signerKey := template, any(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeTestCert writes the certificate and its key as PEM files name.crt
+// and name.key to the directory.
+func writeTestCert(t *testing.T, dir, name string, cert *tls.Certificate) {
+	t.Helper()
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), certPEM, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), keyPEM, 0o600))
+}
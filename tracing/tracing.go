@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// ExporterNone disables tracing.
+	ExporterNone = "none"
+	// ExporterOTLP exports spans with OTLP over HTTP. The endpoint is
+	// configured with the standard OTEL_EXPORTER_OTLP_* environment variables.
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to stdout, for local debugging.
+	ExporterStdout = "stdout"
+)
+
+const (
+	// defaultServiceName is the default service name reported in spans.
+	defaultServiceName = "notes-service"
+)
+
+// ErrUnknownExporter is returned when the exporter is not supported.
+var ErrUnknownExporter = errors.New("unknown trace exporter")
+
+// Options contains options for the tracing setup.
+type Options struct {
+	// ServiceName is the service name reported in spans.
+	ServiceName string
+	// SampleRatio is the ratio of traces that are sampled, from 0 to 1.
+	// The sampling decision of the parent span is respected.
+	SampleRatio float64
+	// Writer is the writer of the stdout exporter.
+	Writer io.Writer
+}
+
+// Option is a function that sets options for the tracing setup.
+type Option func(o *Options)
+
+// Setup sets up the global tracer provider and propagator with the
+// given exporter. It returns a function that flushes and shuts down the
+// tracer provider.
+func Setup(ctx context.Context, exporter string, options ...Option) (func(context.Context) error, error) {
+	opts := Options{
+		ServiceName: defaultServiceName,
+		SampleRatio: 1,
+		Writer:      os.Stdout,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		spanExporter, err = otlptracehttp.New(ctx)
+	case ExporterStdout:
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(opts.Writer), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExporter, exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func Test_Setup(t *testing.T) {
+	tests := []struct {
+		name        string
+		exporter    string
+		wantOutput  bool
+		expectError error
+	}{
+		{
+			name:     "Setup() - tracing disabled",
+			exporter: ExporterNone,
+		},
+		{
+			name:       "Setup() - stdout exporter",
+			exporter:   ExporterStdout,
+			wantOutput: true,
+		},
+		{
+			name:        "Setup() - unknown exporter",
+			exporter:    "unknown",
+			expectError: ErrUnknownExporter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			shutdown, err := Setup(context.Background(), tt.exporter, func(o *Options) {
+				o.Writer = &buf
+			})
+			if tt.expectError != nil {
+				require.ErrorIs(t, err, tt.expectError)
+				return
+			}
+			require.NoError(t, err)
+
+			_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+			span.End()
+			require.NoError(t, shutdown(context.Background()))
+
+			if tt.wantOutput {
+				require.Contains(t, buf.String(), `"Name": "test-span"`)
+				require.Contains(t, buf.String(), `"Value": "notes-service"`)
+			} else {
+				require.Empty(t, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+"use strict";
+
+// The front-end is served by the API server, so the API is called with
+// paths relative to the origin.
+const api = {
+    async request(method, path, body, headers = {}) {
+        const options = { method, headers: { ...headers } };
+        if (body !== undefined) {
+            options.headers["Content-Type"] = "application/json";
+            options.body = JSON.stringify(body);
+        }
+        const resp = await fetch(path, options);
+        const data = await resp.json().catch(() => ({}));
+        if (!resp.ok) {
+            throw new Error(data.message || resp.statusText);
+        }
+        return data;
+    },
+    listCategories() {
+        return this.request("GET", "/v1/categories");
+    },
+    listNotes(category) {
+        return this.request("GET", `/v1/categories/${encodeURIComponent(category)}/notes`);
+    },
+    createNote(category, note) {
+        // The idempotency key makes it safe to retry the request.
+        return this.request("POST", `/v1/categories/${encodeURIComponent(category)}/notes`, { note }, {
+            "Idempotency-Key": crypto.randomUUID(),
+        });
+    },
+    updateNote(category, id, note) {
+        return this.request("PUT", `/v1/categories/${encodeURIComponent(category)}/notes/${encodeURIComponent(id)}`, { note });
+    },
+    deleteNote(category, id) {
+        return this.request("DELETE", `/v1/categories/${encodeURIComponent(category)}/notes/${encodeURIComponent(id)}`);
+    },
+};
+
+const state = {
+    categories: [],
+    category: null,
+    notes: [],
+    query: "",
+};
+
+const el = {
+    status: document.getElementById("status"),
+    categories: document.getElementById("categories"),
+    categoryForm: document.getElementById("category-form"),
+    categoryInput: document.getElementById("category-input"),
+    notesTitle: document.getElementById("notes-title"),
+    search: document.getElementById("search"),
+    createForm: document.getElementById("create-form"),
+    createInput: document.getElementById("create-input"),
+    notes: document.getElementById("notes"),
+    noteTemplate: document.getElementById("note-template"),
+};
+
+function setStatus(message, isError = false) {
+    el.status.textContent = message;
+    el.status.classList.toggle("error", isError);
+}
+
+// run calls fn and reports its error in the status line.
+async function run(fn) {
+    try {
+        await fn();
+    } catch (err) {
+        setStatus(err.message, true);
+    }
+}
+
+async function loadCategories() {
+    const data = await api.listCategories();
+    state.categories = data.categories || [];
+    renderCategories();
+}
+
+async function openCategory(category) {
+    const data = await api.listNotes(category);
+    state.category = category;
+    state.notes = data.notes || [];
+    state.query = "";
+    el.search.value = "";
+    el.search.disabled = false;
+    el.createForm.hidden = false;
+    setStatus("");
+    renderCategories();
+    renderNotes();
+}
+
+function renderCategories() {
+    const categories = [...state.categories];
+    if (state.category && !categories.includes(state.category)) {
+        categories.push(state.category);
+        categories.sort();
+    }
+    el.categories.replaceChildren(...categories.map((category) => {
+        const button = document.createElement("button");
+        button.type = "button";
+        button.textContent = category;
+        button.setAttribute("aria-current", String(category === state.category));
+        button.addEventListener("click", () => run(() => openCategory(category)));
+        const li = document.createElement("li");
+        li.append(button);
+        return li;
+    }));
+}
+
+function renderNotes() {
+    el.notesTitle.textContent = state.category;
+    const query = state.query.toLowerCase();
+    const notes = state.notes.filter((note) => note.note.toLowerCase().includes(query));
+    if (notes.length === 0) {
+        const li = document.createElement("li");
+        li.textContent = state.notes.length === 0 ? "No notes in this category." : "No notes match the search.";
+        el.notes.replaceChildren(li);
+        return;
+    }
+    el.notes.replaceChildren(...notes.map(renderNote));
+}
+
+function renderNote(note) {
+    const li = el.noteTemplate.content.firstElementChild.cloneNode(true);
+    const text = li.querySelector(".note-text");
+    const edit = li.querySelector(".note-edit");
+    const buttons = {
+        edit: li.querySelector(".edit"),
+        save: li.querySelector(".save"),
+        cancel: li.querySelector(".cancel"),
+        delete: li.querySelector(".delete"),
+    };
+    text.textContent = note.note;
+
+    const setEditing = (editing) => {
+        text.hidden = editing;
+        edit.hidden = !editing;
+        buttons.edit.hidden = editing;
+        buttons.save.hidden = !editing;
+        buttons.cancel.hidden = !editing;
+    };
+
+    buttons.edit.addEventListener("click", () => {
+        edit.value = note.note;
+        setEditing(true);
+        edit.focus();
+    });
+    buttons.cancel.addEventListener("click", () => setEditing(false));
+    buttons.save.addEventListener("click", () => run(async () => {
+        const data = await api.updateNote(note.category, note.id, edit.value);
+        Object.assign(note, data.note);
+        setStatus("Note is updated.");
+        renderNotes();
+    }));
+    buttons.delete.addEventListener("click", () => run(async () => {
+        if (!confirm("Delete this note?")) {
+            return;
+        }
+        await api.deleteNote(note.category, note.id);
+        state.notes = state.notes.filter((n) => n.id !== note.id);
+        setStatus("Note is deleted.");
+        renderNotes();
+        if (state.notes.length === 0) {
+            await loadCategories();
+        }
+    }));
+    return li;
+}
+
+el.categoryForm.addEventListener("submit", (event) => {
+    event.preventDefault();
+    const category = el.categoryInput.value.trim();
+    if (category) {
+        el.categoryInput.value = "";
+        run(() => openCategory(category));
+    }
+});
+
+el.search.addEventListener("input", () => {
+    state.query = el.search.value;
+    renderNotes();
+});
+
+el.createForm.addEventListener("submit", (event) => {
+    event.preventDefault();
+    run(async () => {
+        const data = await api.createNote(state.category, el.createInput.value);
+        el.createInput.value = "";
+        state.notes.push(data.note);
+        setStatus("Note is created.");
+        renderNotes();
+        if (!state.categories.includes(state.category)) {
+            await loadCategories();
+        }
+    });
+});
+
+run(loadCategories);
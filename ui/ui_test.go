@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Handler(t *testing.T) {
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "Handler() - index",
+			path:            "/",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        `<script src="app.js"></script>`,
+		},
+		{
+			name:            "Handler() - script",
+			path:            "/app.js",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/javascript; charset=utf-8",
+			wantBody:        `"/v1/categories"`,
+		},
+		{
+			name:            "Handler() - stylesheet",
+			path:            "/style.css",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/css; charset=utf-8",
+		},
+		{
+			name:       "Handler() - missing file",
+			path:       "/missing.js",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.wantStatus, rec.Code)
+			require.Equal(t, contentSecurityPolicy, rec.Header().Get("Content-Security-Policy"))
+			if len(tt.wantContentType) > 0 {
+				require.Equal(t, tt.wantContentType, rec.Header().Get("Content-Type"))
+			}
+			require.Contains(t, rec.Body.String(), tt.wantBody)
+		})
+	}
+}
@@ -0,0 +1,567 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultTimeout is the default timeout of a delivery attempt.
+	defaultTimeout = 10 * time.Second
+	// defaultMaxAttempts is the default number of attempts of a delivery.
+	defaultMaxAttempts = 5
+	// defaultBaseDelay is the default delay before the second attempt of
+	// a delivery. The delay doubles with every attempt.
+	defaultBaseDelay = time.Second
+	// defaultMaxDelay is the default maximum delay between attempts.
+	defaultMaxDelay = time.Minute
+	// defaultWorkers is the default number of concurrent deliveries.
+	defaultWorkers = 4
+	// defaultQueueSize is the default number of deliveries waiting for a
+	// worker.
+	defaultQueueSize = 1000
+	// defaultDeliveriesSize is the default number of recent deliveries
+	// kept per webhook.
+	defaultDeliveriesSize = 100
+	// secretSize is the size in bytes of generated secrets.
+	secretSize = 32
+	// lookupTimeout is the timeout of resolving the host of a webhook
+	// when it is registered.
+	lookupTimeout = 5 * time.Second
+)
+
+// logger is the interface that wraps around methods Debug, Info and Error.
+type logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// eventSource is the interface that wraps around the methods to subscribe
+// to the events of the notes service.
+type eventSource interface {
+	Subscribe(category string, lastEventID uint64) *notes.Subscription
+	Unsubscribe(sub *notes.Subscription)
+}
+
+// payload is the body of a delivery.
+type payload struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	EventID   uint64    `json:"eventId"`
+	Timestamp time.Time `json:"timestamp"`
+	Note      note      `json:"note"`
+}
+
+// note is the note of a delivery.
+type note struct {
+	ID       string `json:"id"`
+	Category string `json:"category"`
+	Note     string `json:"note,omitempty"`
+}
+
+// job is a delivery waiting for a worker.
+type job struct {
+	webhook  Webhook
+	delivery *Delivery
+	body     []byte
+	// attempt is the number of the next attempt.
+	attempt int
+}
+
+// Dispatcher keeps the registered webhooks and delivers the events of the
+// notes service to them. Deliveries are signed with the secret of the
+// webhook and retried with exponential backoff. Deliveries that fail on
+// every attempt are dead-lettered, and kept with the recent deliveries of
+// the webhook. The deliveries are kept in memory, the webhooks are only
+// kept when the server restarts if the dispatcher has a store.
+type Dispatcher struct {
+	events         eventSource
+	log            logger
+	client         *http.Client
+	store          store
+	lookup         lookupFunc
+	allowPrivate   bool
+	maxAttempts    int
+	baseDelay      time.Duration
+	maxDelay       time.Duration
+	workers        int
+	deliveriesSize int
+	queue          chan job
+
+	mu         sync.RWMutex
+	webhooks   map[string]Webhook
+	deliveries map[string][]*Delivery
+	// retries are the timers of the deliveries waiting for their next
+	// attempt.
+	retries map[*Delivery]*time.Timer
+}
+
+// DispatcherOptions contains options for the Dispatcher.
+type DispatcherOptions struct {
+	// Client sends the deliveries. Its timeout is the timeout of a
+	// delivery attempt. If it is nil, a client with the timeout Timeout is
+	// used, which refuses to connect to addresses that are not public
+	// unless AllowPrivateTargets is set.
+	Client *http.Client
+	// Timeout is the timeout of a delivery attempt of the default client.
+	Timeout time.Duration
+	// AllowPrivateTargets allows webhooks with addresses that are not
+	// public, such as private, loopback and link-local addresses, for
+	// example to deliver to services on the same network. Otherwise they are rejected when they are registered
+	// and when the deliveries connect to them.
+	AllowPrivateTargets bool
+	// Store persists the registered webhooks. They are kept in memory
+	// only if it is nil.
+	Store store
+	// MaxAttempts is the number of attempts of a delivery before it is
+	// dead-lettered.
+	MaxAttempts int
+	// BaseDelay is the delay before the second attempt of a delivery. The
+	// delay doubles with every attempt.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between attempts.
+	MaxDelay time.Duration
+	// Workers is the number of concurrent deliveries.
+	Workers int
+	// QueueSize is the number of deliveries waiting for a worker.
+	// Deliveries are dead-lettered when the queue is full.
+	QueueSize int
+	// DeliveriesSize is the number of recent deliveries kept per webhook.
+	DeliveriesSize int
+}
+
+// DispatcherOption is a function that sets options on the Dispatcher.
+type DispatcherOption func(o *DispatcherOptions)
+
+// NewDispatcher returns a new Dispatcher of the events of the source. If
+// the source is nil, the events are passed to Deliver instead, for example
+// by the notes.OutboxRelay.
+func NewDispatcher(events eventSource, logger logger, options ...DispatcherOption) (*Dispatcher, error) {
+	if logger == nil {
+		return nil, ErrLoggerRequired
+	}
+
+	opts := DispatcherOptions{
+		Timeout:        defaultTimeout,
+		MaxAttempts:    defaultMaxAttempts,
+		BaseDelay:      defaultBaseDelay,
+		MaxDelay:       defaultMaxDelay,
+		Workers:        defaultWorkers,
+		QueueSize:      defaultQueueSize,
+		DeliveriesSize: defaultDeliveriesSize,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.Client == nil {
+		opts.Client = newClient(opts.Timeout, opts.AllowPrivateTargets)
+	}
+
+	webhooks := make(map[string]Webhook)
+	if opts.Store != nil {
+		stored, err := opts.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for _, webhook := range stored {
+			webhooks[webhook.ID] = webhook
+		}
+	}
+
+	return &Dispatcher{
+		events:         events,
+		log:            logger,
+		client:         opts.Client,
+		store:          opts.Store,
+		lookup:         net.DefaultResolver.LookupIPAddr,
+		allowPrivate:   opts.AllowPrivateTargets,
+		maxAttempts:    max(opts.MaxAttempts, 1),
+		baseDelay:      opts.BaseDelay,
+		maxDelay:       opts.MaxDelay,
+		workers:        max(opts.Workers, 1),
+		deliveriesSize: max(opts.DeliveriesSize, 1),
+		queue:          make(chan job, opts.QueueSize),
+		webhooks:       webhooks,
+		deliveries:     make(map[string][]*Delivery),
+		retries:        make(map[*Delivery]*time.Timer),
+	}, nil
+}
+
+// Register validates and registers the webhook. A secret is generated if
+// the webhook does not have one.
+func (d *Dispatcher) Register(ctx context.Context, webhook Webhook) (Webhook, error) {
+	if err := webhook.validate(); err != nil {
+		return Webhook{}, err
+	}
+	if !d.allowPrivate {
+		ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+		defer cancel()
+		if err := checkTarget(ctx, d.lookup, webhook.URL); err != nil {
+			return Webhook{}, err
+		}
+	}
+	if len(webhook.Secret) == 0 {
+		secret := make([]byte, secretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return Webhook{}, fmt.Errorf("generating secret: %w", err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+	webhook.ID = uuid.NewString()
+	webhook.Events = slices.Clone(webhook.Events)
+	webhook.CreatedAt = time.Now().UTC()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.webhooks[webhook.ID] = webhook
+	if err := d.save(); err != nil {
+		delete(d.webhooks, webhook.ID)
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// Webhooks returns the registered webhooks, oldest first.
+func (d *Dispatcher) Webhooks() []Webhook {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.sortedWebhooks()
+}
+
+// sortedWebhooks returns the registered webhooks, oldest first. It must be
+// called with the lock held.
+func (d *Dispatcher) sortedWebhooks() []Webhook {
+	webhooks := make([]Webhook, 0, len(d.webhooks))
+	for _, webhook := range d.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	slices.SortFunc(webhooks, func(a, b Webhook) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return webhooks
+}
+
+// Webhook returns the webhook with the ID.
+func (d *Dispatcher) Webhook(id string) (Webhook, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	webhook, ok := d.webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	return webhook, nil
+}
+
+// Delete removes the webhook with the ID and its deliveries. Deliveries
+// in progress are still attempted.
+func (d *Dispatcher) Delete(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	webhook, ok := d.webhooks[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(d.webhooks, id)
+	if err := d.save(); err != nil {
+		d.webhooks[id] = webhook
+		return err
+	}
+	delete(d.deliveries, id)
+	return nil
+}
+
+// save stores the registered webhooks if the dispatcher has a store. It
+// must be called with the lock held.
+func (d *Dispatcher) save() error {
+	if d.store == nil {
+		return nil
+	}
+	return d.store.Save(d.sortedWebhooks())
+}
+
+// Deliveries returns the recent deliveries of the webhook with the ID,
+// newest first.
+func (d *Dispatcher) Deliveries(id string) ([]Delivery, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if _, ok := d.webhooks[id]; !ok {
+		return nil, ErrNotFound
+	}
+	records := d.deliveries[id]
+	deliveries := make([]Delivery, len(records))
+	for i, delivery := range records {
+		deliveries[len(records)-1-i] = *delivery
+	}
+	return deliveries, nil
+}
+
+// Run delivers the events of the source, or the events passed to Deliver,
+// to the webhooks until the context is canceled. Deliveries in progress,
+// and deliveries waiting for their next attempt, are dead-lettered when
+// the context is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	// The retries are stopped after the workers, which schedule them.
+	defer d.stopRetries(ctx)
+
+	var wg sync.WaitGroup
+	for range d.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work(ctx)
+		}()
+	}
+	defer wg.Wait()
+
+	if d.events == nil {
+		<-ctx.Done()
+		return
+	}
+
+	var lastEventID uint64
+	sub := d.events.Subscribe("", 0)
+	for {
+		select {
+		case <-ctx.Done():
+			d.events.Unsubscribe(sub)
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				// The dispatcher fell behind, it resumes after the last
+				// event it received.
+				sub = d.events.Subscribe("", lastEventID)
+				if sub.Missed {
+					d.log.Error("Webhook events were missed.", "lastEventId", lastEventID)
+				}
+				for _, event := range sub.Replay {
+					lastEventID = event.ID
+					d.dispatch(event)
+				}
+				continue
+			}
+			lastEventID = event.ID
+			d.dispatch(event)
+		}
+	}
+}
+
+// Deliver queues the deliveries of the event to the webhooks it matches.
+// It returns an error if a delivery could not be queued, in which case the
+// event can be delivered again. The webhooks that already received the
+// event receive it again with the same event ID.
+func (d *Dispatcher) Deliver(ctx context.Context, event notes.Event) error {
+	return d.dispatch(event)
+}
+
+// dispatch queues the deliveries of the event to the webhooks it matches.
+// The deliveries that could not be queued are dead-lettered, and the last
+// of their errors is returned.
+func (d *Dispatcher) dispatch(event notes.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var dispatchErr error
+	for _, webhook := range d.webhooks {
+		if !webhook.matches(event) {
+			continue
+		}
+
+		now := time.Now().UTC()
+		delivery := &Delivery{
+			ID:        uuid.NewString(),
+			WebhookID: webhook.ID,
+			EventID:   event.ID,
+			Event:     event.Type,
+			Status:    StatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		d.record(delivery)
+
+		body, err := json.Marshal(payload{
+			ID:        delivery.ID,
+			Event:     string(event.Type),
+			EventID:   event.ID,
+			Timestamp: now,
+			Note:      note{ID: event.Note.ID, Category: event.Note.Category, Note: event.Note.Note},
+		})
+		if err != nil {
+			d.deadLetter(delivery, 0, err)
+			dispatchErr = err
+			continue
+		}
+
+		select {
+		case d.queue <- job{webhook: webhook, delivery: delivery, body: body, attempt: 1}:
+		default:
+			dispatchErr = errors.New("delivery queue is full")
+			d.deadLetter(delivery, 0, dispatchErr)
+		}
+	}
+	return dispatchErr
+}
+
+// record adds the delivery to the recent deliveries of its webhook. It
+// must be called with the lock held.
+func (d *Dispatcher) record(delivery *Delivery) {
+	deliveries := append(d.deliveries[delivery.WebhookID], delivery)
+	if len(deliveries) > d.deliveriesSize {
+		deliveries = deliveries[len(deliveries)-d.deliveriesSize:]
+	}
+	d.deliveries[delivery.WebhookID] = deliveries
+}
+
+// deadLetter marks the delivery as dead-lettered. It must be called with
+// the lock held.
+func (d *Dispatcher) deadLetter(delivery *Delivery, status int, err error) {
+	delivery.Status = StatusDeadLettered
+	delivery.ResponseStatus = status
+	delivery.Error = err.Error()
+	delivery.UpdatedAt = time.Now().UTC()
+	d.log.Error("Webhook delivery dead-lettered.", "webhookId", delivery.WebhookID, "deliveryId", delivery.ID, "eventId", delivery.EventID, "attempts", delivery.Attempts, "error", err)
+}
+
+// work delivers the queued deliveries until the context is canceled.
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-d.queue:
+			d.deliver(ctx, j)
+		}
+	}
+}
+
+// deliver makes an attempt of the delivery. If it fails with an error
+// that is retried, the next attempt is scheduled after the backoff, so
+// that the worker is free for other deliveries in the meantime.
+func (d *Dispatcher) deliver(ctx context.Context, j job) {
+	status, err := d.send(ctx, j)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j.delivery.Attempts = j.attempt
+	j.delivery.ResponseStatus = status
+	j.delivery.UpdatedAt = time.Now().UTC()
+	if err == nil {
+		j.delivery.Status = StatusSucceeded
+		j.delivery.Error = ""
+		d.log.Debug("Webhook delivered.", "webhookId", j.webhook.ID, "deliveryId", j.delivery.ID, "attempts", j.attempt)
+		return
+	}
+	if j.attempt >= d.maxAttempts || !retryable(status) || errors.Is(err, errForbiddenAddress) || ctx.Err() != nil {
+		d.deadLetter(j.delivery, status, err)
+		return
+	}
+	j.delivery.Error = err.Error()
+
+	delay := d.backoff(j.attempt)
+	j.attempt++
+	d.retries[j.delivery] = time.AfterFunc(delay, func() {
+		d.retry(ctx, j)
+	})
+}
+
+// retry queues the next attempt of the delivery. The delivery is
+// dead-lettered if the queue is full.
+func (d *Dispatcher) retry(ctx context.Context, j job) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.retries[j.delivery]; !ok {
+		// The retries were stopped.
+		return
+	}
+	delete(d.retries, j.delivery)
+	if ctx.Err() != nil {
+		d.deadLetter(j.delivery, j.delivery.ResponseStatus, ctx.Err())
+		return
+	}
+
+	select {
+	case d.queue <- j:
+	default:
+		d.deadLetter(j.delivery, j.delivery.ResponseStatus, errors.New("delivery queue is full"))
+	}
+}
+
+// stopRetries stops the scheduled attempts and dead-letters their
+// deliveries.
+func (d *Dispatcher) stopRetries(ctx context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for delivery, timer := range d.retries {
+		timer.Stop()
+		delete(d.retries, delivery)
+		d.deadLetter(delivery, delivery.ResponseStatus, ctx.Err())
+	}
+}
+
+// send sends the delivery to the webhook and returns the status code of
+// the response. Responses with a status code other than 2xx are errors.
+func (d *Dispatcher) send(ctx context.Context, j job) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.webhook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "notes-service-webhooks")
+	req.Header.Set(HeaderID, j.webhook.ID)
+	req.Header.Set(HeaderDelivery, j.delivery.ID)
+	req.Header.Set(HeaderEvent, string(j.delivery.Event))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Signature(j.webhook.Secret, timestamp, j.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay after the attempt.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempt && delay < d.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, d.maxDelay)
+}
+
+// retryable reports whether a delivery that failed with the status code
+// is attempted again. Requests without a response, timeouts, rate limits
+// and server errors are retried.
+func retryable(status int) bool {
+	return status == 0 ||
+		status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status >= 500
+}
@@ -0,0 +1,440 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Dispatcher_Register(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        Webhook
+		allowPrivate bool
+		wantErr      error
+	}{
+		{
+			name:  "Register() - all events of all categories",
+			input: Webhook{URL: "https://example.com/hook"},
+		},
+		{
+			name:  "Register() - filtered events",
+			input: Webhook{URL: "http://example.com:8080/hook", Category: "work", Events: []notes.EventType{notes.EventCreated}},
+		},
+		{
+			name:    "Register() - loopback address",
+			input:   Webhook{URL: "http://127.0.0.1:8080/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - host resolving to a loopback address",
+			input:   Webhook{URL: "http://localhost:8080/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - host resolving to a private address",
+			input:   Webhook{URL: "https://internal.example.com/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - link-local address",
+			input:   Webhook{URL: "http://169.254.169.254/latest/meta-data"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - ipv6 loopback address",
+			input:   Webhook{URL: "http://[::1]/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - unresolvable host",
+			input:   Webhook{URL: "https://unknown.example.com/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:         "Register() - private addresses are allowed",
+			input:        Webhook{URL: "http://localhost:8080/hook"},
+			allowPrivate: true,
+		},
+		{
+			name:    "Register() - relative url",
+			input:   Webhook{URL: "/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - unsupported scheme",
+			input:   Webhook{URL: "ftp://example.com/hook"},
+			wantErr: ErrInvalidWebhook,
+		},
+		{
+			name:    "Register() - unknown event",
+			input:   Webhook{URL: "https://example.com/hook", Events: []notes.EventType{"archived"}},
+			wantErr: ErrInvalidWebhook,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDispatcher(notes.NewBroker(), &mockLogger{}, func(o *DispatcherOptions) {
+				o.AllowPrivateTargets = tt.allowPrivate
+			})
+			require.NoError(t, err)
+			d.lookup = fakeLookup
+
+			got, err := d.Register(context.Background(), tt.input)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Empty(t, d.Webhooks())
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, got.ID)
+			require.Len(t, got.Secret, 2*secretSize)
+			require.Equal(t, tt.input.URL, got.URL)
+
+			webhook, err := d.Webhook(got.ID)
+			require.NoError(t, err)
+			require.Equal(t, got, webhook)
+		})
+	}
+}
+
+func Test_Dispatcher_deliveries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		webhook    Webhook
+		wantCalls  int
+		wantStatus Status
+		wantCode   int
+	}{
+		{
+			name:       "delivers a signed event",
+			statuses:   []int{http.StatusNoContent},
+			webhook:    Webhook{Secret: "secret", Category: "work"},
+			wantCalls:  1,
+			wantStatus: StatusSucceeded,
+			wantCode:   http.StatusNoContent,
+		},
+		{
+			name:       "retries server errors",
+			statuses:   []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			webhook:    Webhook{Secret: "secret", Category: "work"},
+			wantCalls:  3,
+			wantStatus: StatusSucceeded,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "dead-letters after the last attempt",
+			statuses:   []int{http.StatusBadGateway},
+			webhook:    Webhook{Secret: "secret", Events: []notes.EventType{notes.EventCreated}},
+			wantCalls:  3,
+			wantStatus: StatusDeadLettered,
+			wantCode:   http.StatusBadGateway,
+		},
+		{
+			name:       "dead-letters client errors without retries",
+			statuses:   []int{http.StatusGone},
+			webhook:    Webhook{Category: "work"},
+			wantCalls:  1,
+			wantStatus: StatusDeadLettered,
+			wantCode:   http.StatusGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			var calls atomic.Int32
+			received := make(chan *http.Request, 10)
+			bodies := make(chan []byte, 10)
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1))
+				body, _ := io.ReadAll(r.Body)
+				received <- r
+				bodies <- body
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses))-1])
+			}))
+			defer receiver.Close()
+
+			broker := notes.NewBroker()
+			events := &subscribedBroker{Broker: broker, subscribed: make(chan struct{}, 1)}
+			d, err := NewDispatcher(events, &mockLogger{}, func(o *DispatcherOptions) {
+				o.MaxAttempts = 3
+				o.BaseDelay = time.Millisecond
+				o.AllowPrivateTargets = true
+			})
+			require.NoError(t, err)
+
+			webhook := tt.webhook
+			webhook.URL = receiver.URL
+			webhook, err = d.Register(context.Background(), webhook)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				d.Run(ctx)
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+
+			// Act
+			// Events that do not match the webhook are not delivered.
+			<-events.subscribed
+			broker.Publish(notes.EventUpdated, notes.Note{ID: "2", Category: "home"})
+			broker.Publish(notes.EventCreated, notes.Note{ID: "1", Category: "work", Note: "note"})
+
+			// Assert
+			var deliveries []Delivery
+			require.Eventually(t, func() bool {
+				deliveries, err = d.Deliveries(webhook.ID)
+				require.NoError(t, err)
+				return len(deliveries) == 1 && deliveries[0].Status != StatusPending
+			}, 5*time.Second, time.Millisecond)
+
+			require.Equal(t, tt.wantCalls, int(calls.Load()))
+			delivery := deliveries[0]
+			require.Equal(t, tt.wantStatus, delivery.Status)
+			require.Equal(t, tt.wantCode, delivery.ResponseStatus)
+			require.Equal(t, tt.wantCalls, delivery.Attempts)
+			require.Equal(t, notes.EventCreated, delivery.Event)
+			if tt.wantStatus == StatusDeadLettered {
+				require.NotEmpty(t, delivery.Error)
+			} else {
+				require.Empty(t, delivery.Error)
+			}
+
+			r, body := <-received, <-bodies
+			require.Equal(t, webhook.ID, r.Header.Get(HeaderID))
+			require.Equal(t, delivery.ID, r.Header.Get(HeaderDelivery))
+			require.Equal(t, string(notes.EventCreated), r.Header.Get(HeaderEvent))
+			require.Equal(t, Signature(webhook.Secret, r.Header.Get(HeaderTimestamp), body), r.Header.Get(HeaderSignature))
+
+			var got payload
+			require.NoError(t, json.Unmarshal(body, &got))
+			require.Equal(t, delivery.ID, got.ID)
+			require.Equal(t, "created", got.Event)
+			require.Equal(t, uint64(2), got.EventID)
+			require.Equal(t, note{ID: "1", Category: "work", Note: "note"}, got.Note)
+		})
+	}
+}
+
+func Test_Dispatcher_Deliver(t *testing.T) {
+	// Without an event source and workers, the deliveries stay queued.
+	d, err := NewDispatcher(nil, &mockLogger{}, func(o *DispatcherOptions) {
+		o.QueueSize = 1
+	})
+	require.NoError(t, err)
+	d.lookup = fakeLookup
+	webhook, err := d.Register(context.Background(), Webhook{URL: "https://example.com/hook", Category: "work"})
+	require.NoError(t, err)
+
+	event := notes.Event{ID: 1, Type: notes.EventCreated, Note: notes.Note{ID: "1", Category: "work"}}
+	require.NoError(t, d.Deliver(context.Background(), event))
+	require.NoError(t, d.Deliver(context.Background(), notes.Event{ID: 2, Type: notes.EventCreated, Note: notes.Note{ID: "2", Category: "home"}}))
+	require.Error(t, d.Deliver(context.Background(), event))
+
+	deliveries, err := d.Deliveries(webhook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	require.Equal(t, StatusDeadLettered, deliveries[0].Status)
+	require.Equal(t, StatusPending, deliveries[1].Status)
+}
+
+func Test_Dispatcher_Delete(t *testing.T) {
+	d, err := NewDispatcher(notes.NewBroker(), &mockLogger{})
+	require.NoError(t, err)
+	d.lookup = fakeLookup
+	webhook, err := d.Register(context.Background(), Webhook{URL: "https://example.com/hook"})
+	require.NoError(t, err)
+
+	require.NoError(t, d.Delete(webhook.ID))
+	require.ErrorIs(t, d.Delete(webhook.ID), ErrNotFound)
+	_, err = d.Webhook(webhook.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = d.Deliveries(webhook.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_Dispatcher_retriesDoNotBlockWorkers(t *testing.T) {
+	// Arrange
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	succeeding := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer succeeding.Close()
+
+	d, err := NewDispatcher(nil, &mockLogger{}, func(o *DispatcherOptions) {
+		o.Workers = 1
+		o.BaseDelay = time.Hour
+		o.AllowPrivateTargets = true
+	})
+	require.NoError(t, err)
+	first, err := d.Register(context.Background(), Webhook{URL: failing.URL, Category: "work"})
+	require.NoError(t, err)
+	second, err := d.Register(context.Background(), Webhook{URL: succeeding.URL, Category: "home"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+
+	// Act
+	// The second delivery is made while the first waits for its retry.
+	require.NoError(t, d.Deliver(ctx, notes.Event{ID: 1, Type: notes.EventCreated, Note: notes.Note{ID: "1", Category: "work"}}))
+	require.Eventually(t, func() bool {
+		deliveries, err := d.Deliveries(first.ID)
+		require.NoError(t, err)
+		return deliveries[0].Attempts == 1
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, d.Deliver(ctx, notes.Event{ID: 2, Type: notes.EventCreated, Note: notes.Note{ID: "2", Category: "home"}}))
+
+	// Assert
+	require.Eventually(t, func() bool {
+		deliveries, err := d.Deliveries(second.ID)
+		require.NoError(t, err)
+		return deliveries[0].Status == StatusSucceeded
+	}, 5*time.Second, time.Millisecond)
+
+	// The delivery waiting for its retry is dead-lettered when the
+	// dispatcher stops.
+	cancel()
+	<-done
+	deliveries, err := d.Deliveries(first.ID)
+	require.NoError(t, err)
+	require.Equal(t, StatusDeadLettered, deliveries[0].Status)
+	require.Equal(t, 1, deliveries[0].Attempts)
+}
+
+func Test_Dispatcher_refusesPrivateAddresses(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer receiver.Close()
+
+	d, err := NewDispatcher(nil, &mockLogger{}, func(o *DispatcherOptions) {
+		o.BaseDelay = time.Millisecond
+	})
+	require.NoError(t, err)
+	// The webhook is added without the checks of Register, like a host
+	// that resolves to another address after it is registered.
+	d.webhooks["1"] = Webhook{ID: "1", URL: receiver.URL}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Act
+	require.NoError(t, d.Deliver(ctx, notes.Event{ID: 1, Type: notes.EventCreated, Note: notes.Note{ID: "1", Category: "work"}}))
+
+	// Assert
+	var deliveries []Delivery
+	require.Eventually(t, func() bool {
+		deliveries, err = d.Deliveries("1")
+		require.NoError(t, err)
+		return deliveries[0].Status != StatusPending
+	}, 5*time.Second, time.Millisecond)
+	require.Equal(t, StatusDeadLettered, deliveries[0].Status)
+	require.Equal(t, 1, deliveries[0].Attempts)
+	require.Contains(t, deliveries[0].Error, errForbiddenAddress.Error())
+	require.Zero(t, calls.Load())
+}
+
+func Test_Dispatcher_Store(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "webhooks.json"))
+
+	d, err := NewDispatcher(nil, &mockLogger{}, func(o *DispatcherOptions) {
+		o.Store = store
+	})
+	require.NoError(t, err)
+	d.lookup = fakeLookup
+	first, err := d.Register(context.Background(), Webhook{URL: "https://example.com/first"})
+	require.NoError(t, err)
+	second, err := d.Register(context.Background(), Webhook{URL: "https://example.com/second", Category: "work", Events: []notes.EventType{notes.EventDeleted}})
+	require.NoError(t, err)
+	require.NoError(t, d.Delete(first.ID))
+
+	// The webhooks are loaded by a new dispatcher, like after a restart.
+	restarted, err := NewDispatcher(nil, &mockLogger{}, func(o *DispatcherOptions) {
+		o.Store = store
+	})
+	require.NoError(t, err)
+	webhooks := restarted.Webhooks()
+	require.Len(t, webhooks, 1)
+	require.Equal(t, second.ID, webhooks[0].ID)
+	require.Equal(t, second.URL, webhooks[0].URL)
+	require.Equal(t, second.Category, webhooks[0].Category)
+	require.Equal(t, second.Events, webhooks[0].Events)
+	require.Equal(t, second.Secret, webhooks[0].Secret)
+	require.True(t, second.CreatedAt.Equal(webhooks[0].CreatedAt))
+}
+
+func Test_Signature(t *testing.T) {
+	// printf '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	require.Equal(t, want, Signature("secret", "1700000000", []byte("{}")))
+}
+
+// subscribedBroker signals when the dispatcher subscribes to the broker.
+type subscribedBroker struct {
+	*notes.Broker
+	subscribed chan struct{}
+}
+
+func (b *subscribedBroker) Subscribe(category string, lastEventID uint64) *notes.Subscription {
+	sub := b.Broker.Subscribe(category, lastEventID)
+	select {
+	case b.subscribed <- struct{}{}:
+	default:
+	}
+	return sub
+}
+
+// fakeLookup resolves the hosts of the tests without DNS.
+func fakeLookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	switch host {
+	case "example.com":
+		return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}}, nil
+	case "localhost":
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}, {IP: net.ParseIP("::1")}}, nil
+	case "internal.example.com":
+		return []net.IPAddr{{IP: net.ParseIP("93.184.215.14")}, {IP: net.ParseIP("10.0.0.1")}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+type mockLogger struct{}
+
+func (l *mockLogger) Debug(msg string, args ...any) {}
+func (l *mockLogger) Info(msg string, args ...any)  {}
+func (l *mockLogger) Error(msg string, args ...any) {}
@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+// store is the interface that wraps around the methods to persist the
+// registered webhooks.
+type store interface {
+	// Load returns the stored webhooks.
+	Load() ([]Webhook, error)
+	// Save replaces the stored webhooks.
+	Save(webhooks []Webhook) error
+}
+
+// storedWebhook is a webhook in the file of a FileStore.
+type storedWebhook struct {
+	ID        string            `json:"id"`
+	URL       string            `json:"url"`
+	Category  string            `json:"category,omitempty"`
+	Events    []notes.EventType `json:"events,omitempty"`
+	Secret    string            `json:"secret"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// FileStore stores the registered webhooks in a JSON file, so that they
+// are kept when the server restarts. The file contains the secrets of the
+// webhooks, and is only readable by its owner.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a new FileStore with the file at the path. The file
+// is created on the first registration.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load returns the webhooks in the file. There are no webhooks if the file
+// does not exist.
+func (s *FileStore) Load() ([]Webhook, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading webhooks: %w", err)
+	}
+
+	var stored []storedWebhook
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("reading webhooks: %w", err)
+	}
+	webhooks := make([]Webhook, len(stored))
+	for i, webhook := range stored {
+		webhooks[i] = Webhook(webhook)
+	}
+	return webhooks, nil
+}
+
+// Save replaces the webhooks in the file. The file is replaced atomically,
+// so that it is not corrupted if the server stops while writing it.
+func (s *FileStore) Save(webhooks []Webhook) error {
+	stored := make([]storedWebhook, len(webhooks))
+	for i, webhook := range webhooks {
+		stored[i] = storedWebhook(webhook)
+	}
+	b, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("writing webhooks: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing webhooks: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing webhooks: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing webhooks: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("writing webhooks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	// dialTimeout is the timeout of connecting to a webhook.
+	dialTimeout = 30 * time.Second
+)
+
+// errForbiddenAddress is returned when a delivery would connect to an
+// address that is not public.
+var errForbiddenAddress = errors.New("address is not public")
+
+// lookupFunc resolves the IP addresses of a host.
+type lookupFunc func(ctx context.Context, host string) ([]net.IPAddr, error)
+
+// nonPublicPrefixes are the address ranges that are not globally
+// reachable, from the IANA special-purpose address registries, and the
+// ranges of the translation mechanisms that embed IPv4 addresses in IPv6
+// addresses.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This network"
+	netip.MustParsePrefix("10.0.0.0/8"),      // Private
+	netip.MustParsePrefix("100.64.0.0/10"),   // Shared address space (CGNAT)
+	netip.MustParsePrefix("127.0.0.0/8"),     // Loopback
+	netip.MustParsePrefix("169.254.0.0/16"),  // Link-local
+	netip.MustParsePrefix("172.16.0.0/12"),   // Private
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // Documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("192.168.0.0/16"),  // Private
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // Documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // Documentation
+	netip.MustParsePrefix("224.0.0.0/4"),     // Multicast
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved and broadcast
+	netip.MustParsePrefix("::/128"),          // Unspecified
+	netip.MustParsePrefix("::1/128"),         // Loopback
+	netip.MustParsePrefix("::ffff:0:0/96"),   // IPv4-mapped
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // Discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4
+	netip.MustParsePrefix("fc00::/7"),        // Unique local
+	netip.MustParsePrefix("fe80::/10"),       // Link-local
+	netip.MustParsePrefix("ff00::/8"),        // Multicast
+}
+
+// publicAddr reports whether the address is public, i.e. it is not in
+// one of the ranges that are not globally reachable. IPv4-mapped IPv6
+// addresses are checked as IPv4 addresses.
+func publicAddr(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTarget checks that the host of the URL resolves to public
+// addresses only, so that webhooks cannot be used to reach the network of
+// the server.
+func checkTarget(ctx context.Context, lookup lookupFunc, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidWebhook)
+	}
+	host := u.Hostname()
+
+	var ips []netip.Addr
+	if ip, err := netip.ParseAddr(host); err == nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := lookup(ctx, host)
+		if err != nil || len(addrs) == 0 {
+			return fmt.Errorf("%w: host %s cannot be resolved", ErrInvalidWebhook, host)
+		}
+		for _, addr := range addrs {
+			ip, _ := netip.AddrFromSlice(addr.IP)
+			ips = append(ips, ip)
+		}
+	}
+	for _, ip := range ips {
+		if !publicAddr(ip) {
+			return fmt.Errorf("%w: url must not target a private, loopback, link-local or other non-public address", ErrInvalidWebhook)
+		}
+	}
+	return nil
+}
+
+// dialControl refuses connections to addresses that are not public. It
+// checks the address that is dialed, after the host is resolved, so that
+// hosts that resolve to other addresses after the registration of the
+// webhook are refused as well.
+func dialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip, err := netip.ParseAddr(host); err != nil || !publicAddr(ip) {
+		return fmt.Errorf("connecting to %s: %w", address, errForbiddenAddress)
+	}
+	return nil
+}
+
+// newClient returns the client of the deliveries. Unless private targets
+// are allowed, it refuses to connect to addresses that are not public. It
+// does not use a proxy, since the proxy would be dialed instead of the
+// webhook.
+func newClient(timeout time.Duration, allowPrivateTargets bool) *http.Client {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	if !allowPrivateTargets {
+		dialer.Control = dialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
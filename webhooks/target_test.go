@@ -0,0 +1,38 @@
+package webhooks
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_publicAddr(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		want bool
+	}{
+		{name: "publicAddr() - public IPv4", addr: "93.184.216.34", want: true},
+		{name: "publicAddr() - public IPv6", addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{name: "publicAddr() - private", addr: "10.1.2.3"},
+		{name: "publicAddr() - loopback", addr: "127.0.0.1"},
+		{name: "publicAddr() - link-local metadata", addr: "169.254.169.254"},
+		{name: "publicAddr() - shared address space", addr: "100.100.100.200"},
+		{name: "publicAddr() - this network", addr: "0.1.2.3"},
+		{name: "publicAddr() - broadcast", addr: "255.255.255.255"},
+		{name: "publicAddr() - IPv6 loopback", addr: "::1"},
+		{name: "publicAddr() - IPv4-mapped private", addr: "::ffff:10.1.2.3"},
+		{name: "publicAddr() - IPv4-mapped public", addr: "::ffff:93.184.216.34", want: true},
+		{name: "publicAddr() - NAT64", addr: "64:ff9b::a9fe:a9fe"},
+		{name: "publicAddr() - 6to4", addr: "2002:a9fe:a9fe::1"},
+		{name: "publicAddr() - unique local", addr: "fd00::1"},
+		{name: "publicAddr() - link-local with zone", addr: "fe80::1%eth0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, publicAddr(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/KatrinSalt/notes-service/notes"
+)
+
+var (
+	// ErrLoggerRequired is returned when the logger is not provided.
+	ErrLoggerRequired = errors.New("logger is not provided")
+)
+
+var (
+	// ErrInvalidWebhook is returned when a webhook is invalid.
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrNotFound is returned when a webhook is not found.
+	ErrNotFound = errors.New("webhook not found")
+)
+
+// Headers of the deliveries.
+const (
+	// HeaderID is the header with the ID of the webhook.
+	HeaderID = "X-Webhook-ID"
+	// HeaderDelivery is the header with the ID of the delivery.
+	HeaderDelivery = "X-Webhook-Delivery"
+	// HeaderEvent is the header with the type of the event.
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp is the header with the Unix time the delivery
+	// attempt was signed at.
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature is the header with the signature of the delivery.
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Webhook is a URL that is notified of the events of notes.
+type Webhook struct {
+	ID  string
+	URL string
+	// Category filters the events by the category of the note. Events
+	// of all categories are delivered if it is empty.
+	Category string
+	// Events filters the events by their type. Events of all types are
+	// delivered if it is empty.
+	Events []notes.EventType
+	// Secret is the key the deliveries are signed with.
+	Secret    string
+	CreatedAt time.Time
+}
+
+// matches reports whether the event is delivered to the webhook.
+func (w Webhook) matches(event notes.Event) bool {
+	if len(w.Category) > 0 && w.Category != event.Note.Category {
+		return false
+	}
+	return len(w.Events) == 0 || slices.Contains(w.Events, event.Type)
+}
+
+// validate checks that the URL of the webhook is an absolute HTTP(S) URL
+// and that its events are known.
+func (w Webhook) validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("%w: url must be an absolute http or https url", ErrInvalidWebhook)
+	}
+	for _, event := range w.Events {
+		switch event {
+		case notes.EventCreated, notes.EventUpdated, notes.EventDeleted:
+		default:
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	return nil
+}
+
+// Status is the status of a delivery.
+type Status string
+
+const (
+	// StatusPending is the status of a delivery that is not delivered yet.
+	StatusPending Status = "pending"
+	// StatusSucceeded is the status of a delivery the receiver accepted.
+	StatusSucceeded Status = "succeeded"
+	// StatusDeadLettered is the status of a delivery that failed on every
+	// attempt, or could not be attempted.
+	StatusDeadLettered Status = "dead-lettered"
+)
+
+// Delivery is the record of the delivery of an event to a webhook.
+type Delivery struct {
+	ID        string
+	WebhookID string
+	EventID   uint64
+	Event     notes.EventType
+	Status    Status
+	Attempts  int
+	// ResponseStatus is the HTTP status code of the last attempt, zero if
+	// no response was received.
+	ResponseStatus int
+	// Error is the error of the last failed attempt.
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Signature returns the signature of a delivery, sent in the
+// X-Webhook-Signature header: the hex encoded HMAC-SHA256 of the
+// timestamp, a dot and the body, keyed with the secret of the webhook and
+// prefixed with "sha256=". Receivers verify deliveries by computing it
+// from the X-Webhook-Timestamp header and the body.
+func Signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}